@@ -9,20 +9,125 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func InitApi(router *gin.RouterGroup, controller controller.Controller, jwt *jwt.JWT, rc *middleware.RoleCheck) {
+func InitApi(router *gin.RouterGroup, controller controller.Controller, jwt *jwt.JWT, rc *middleware.RoleCheck, ak *middleware.APIKeyAuth) {
 	router.GET("/hello", controller.Hello().Hello)
 
 	user := router.Group("/user")
 	{
 		user.POST("/login-by-email", controller.User().LoginByEmail)
+		user.POST("/login-by-mobile/code", controller.User().SendLoginByMobileCode)
+		user.POST("/login-by-mobile", controller.User().LoginByMobile)
+		user.POST("/login/mfa", controller.MFA().LoginByMFA)
+		user.POST("/register", controller.User().Register)
+		user.POST("/register/verify", controller.User().RegisterVerify)
+		user.POST("/register/resend-code", controller.User().RegisterResendCode)
+		user.POST("/token/refresh", controller.User().RefreshToken)
+		user.POST("/password/forgot", controller.User().ForgotPassword)
+		user.POST("/password/reset", controller.User().ResetPassword)
+		user.POST("/email/revert", controller.Profile().RevertEmailChange)
+		user.POST("/account/restore", controller.Account().Restore)
+		// signed links, the signature authorizes the request
+		user.GET("/data-exports/signed/:uniqueId", controller.Account().DownloadDataExport)
+		user.HEAD("/data-exports/signed/:uniqueId", controller.Account().DownloadDataExport)
+		user.GET("/oauth/providers", controller.OAuth().ListProviders)
+		user.GET("/oauth/:provider/authorize", controller.OAuth().Authorize)
+		user.POST("/oauth/:provider/callback", controller.OAuth().Callback)
 	}
 
-	user.Use(jwt.JWT(), rc.RoleCheckAny(
-		model.UserRoleCodeSuperAdmin,
-		model.UserRoleCodeAdmin,
-		model.UserRoleCodeUser,
-	))
+	// read-only endpoints machine clients may call with an API key
+	userRead := router.Group("/user", ak.APIKeyOr(jwt.JWT(), "user:read"), rc.RequirePermission(model.PermissionUserRead))
 	{
-		user.GET("/info", controller.User().Info)
+		userRead.GET("/info", controller.User().Info)
+	}
+
+	user.Use(jwt.JWT(), rc.RequirePermission(model.PermissionUserRead))
+	{
+		user.POST("/logout", controller.User().Logout)
+		user.GET("/sessions", controller.Session().List)
+		user.GET("/data-exports", controller.Account().ListDataExports)
+		user.GET("/data-exports/:uniqueId", controller.Account().GetDataExport)
+	}
+
+	userWrite := user.Group("", rc.RequirePermission(model.PermissionUserWrite))
+	{
+		userWrite.PATCH("/profile", controller.Profile().Update)
+		userWrite.POST("/profile/avatar", controller.Profile().UpdateAvatar)
+	}
+
+	// acting for the user rather than seeing what they see, impersonation
+	// tokens are refused
+	userSensitive := userWrite.Group("", middleware.DenyImpersonation())
+	{
+		userSensitive.POST("/logout/all", controller.User().LogoutAll)
+		userSensitive.DELETE("/sessions/:id", controller.Session().Revoke)
+		userSensitive.POST("/profile/mobile/code", controller.Profile().SendMobileCode)
+		userSensitive.POST("/reauth/code", controller.Profile().SendReauthCode)
+		userSensitive.POST("/password/change", controller.Profile().ChangePassword)
+		userSensitive.POST("/email/change", controller.Profile().RequestEmailChange)
+		userSensitive.POST("/email/change/confirm", controller.Profile().ConfirmEmailChange)
+		userSensitive.POST("/data-exports", controller.Account().RequestDataExport)
+		userSensitive.POST("/account/delete", controller.Account().Delete)
+		userSensitive.POST("/mfa/totp/enroll", controller.MFA().EnrollTOTP)
+		userSensitive.POST("/mfa/totp/confirm", controller.MFA().ConfirmTOTP)
+		userSensitive.POST("/mfa/totp/disable", controller.MFA().DisableTOTP)
+		userSensitive.GET("/oauth/:provider/link", controller.OAuth().AuthorizeLink)
+		userSensitive.POST("/oauth/:provider/link", controller.OAuth().Link)
+	}
+
+	files := router.Group("/files", jwt.JWT(), rc.RequirePermission(model.PermissionFileRead))
+	{
+		files.POST("", rc.RequirePermission(model.PermissionFileWrite), controller.File().Upload)
+		files.GET("", controller.File().List)
+		files.GET("/:uniqueId", controller.File().Get)
+		files.GET("/:uniqueId/content", controller.File().Download)
+		files.HEAD("/:uniqueId/content", controller.File().Download)
+		files.GET("/:uniqueId/url", controller.File().SignedURL)
+		files.DELETE("/:uniqueId", rc.RequirePermission(model.PermissionFileWrite), controller.File().Delete)
+	}
+
+	// signed links, the signature authorizes the request
+	router.GET("/files/signed/:uniqueId", controller.File().ServeSignedFile)
+	router.HEAD("/files/signed/:uniqueId", controller.File().ServeSignedFile)
+	router.GET("/storage/*key", controller.File().ServeSigned)
+	router.HEAD("/storage/*key", controller.File().ServeSigned)
+
+	admin := router.Group("/admin", jwt.JWT())
+	{
+		admin.GET("/api-keys", rc.RequirePermission(model.PermissionAdminAPIKeyRead), controller.APIKey().List)
+		admin.POST("/api-keys", rc.RequirePermission(model.PermissionAdminAPIKeyWrite), middleware.DenyImpersonation(), controller.APIKey().Create)
+		admin.DELETE("/api-keys/:id", rc.RequirePermission(model.PermissionAdminAPIKeyWrite), middleware.DenyImpersonation(), controller.APIKey().Revoke)
+	}
+
+	adminUsers := admin.Group("/users", rc.RequirePermission(model.PermissionAdminUserRead))
+	{
+		adminUsers.GET("", controller.AdminUser().List)
+		adminUsers.GET("/:uniqueId", controller.AdminUser().Get)
+	}
+
+	adminUsersWrite := adminUsers.Group("", rc.RequirePermission(model.PermissionAdminUserWrite))
+	{
+		adminUsersWrite.POST("", controller.AdminUser().Create)
+		adminUsersWrite.PATCH("/:uniqueId", controller.AdminUser().Update)
+		adminUsersWrite.DELETE("/:uniqueId", controller.AdminUser().Delete)
+		adminUsersWrite.POST("/:uniqueId/restore", controller.AdminUser().Restore)
+		adminUsersWrite.PUT("/:uniqueId/status", controller.AdminUser().SetStatus)
+		adminUsersWrite.POST("/:uniqueId/roles", controller.AdminUser().AssignRole)
+		adminUsersWrite.DELETE("/:uniqueId/roles/:role", controller.AdminUser().RemoveRole)
+	}
+
+	adminUsers.POST("/:uniqueId/impersonate", rc.RequirePermission(model.PermissionAdminUserImpersonate), middleware.DenyImpersonation(), controller.AdminUser().Impersonate)
+
+	adminRoles := admin.Group("", rc.RequirePermission(model.PermissionAdminRoleRead))
+	{
+		adminRoles.GET("/roles", controller.AdminRole().List)
+		adminRoles.GET("/roles/:code", controller.AdminRole().Get)
+		adminRoles.GET("/permissions", controller.AdminRole().ListPermissions)
+	}
+
+	adminRolesWrite := adminRoles.Group("/roles", rc.RequirePermission(model.PermissionAdminRoleWrite), middleware.DenyImpersonation())
+	{
+		adminRolesWrite.POST("", controller.AdminRole().Create)
+		adminRolesWrite.PATCH("/:code", controller.AdminRole().Update)
+		adminRolesWrite.DELETE("/:code", controller.AdminRole().Delete)
 	}
 }
@@ -6,6 +6,7 @@ import (
 	v1 "super-web-server/internal/api/v1"
 	"super-web-server/internal/config"
 	"super-web-server/internal/controller"
+	"super-web-server/internal/cron"
 	"super-web-server/internal/middleware"
 	"super-web-server/internal/repo"
 	"super-web-server/internal/service"
@@ -13,7 +14,14 @@ import (
 	"super-web-server/pkg/database"
 	"super-web-server/pkg/jwt"
 	"super-web-server/pkg/logger"
+	"super-web-server/pkg/mailer"
+	"super-web-server/pkg/oidc"
+	"super-web-server/pkg/password"
+	"super-web-server/pkg/policy"
+	"super-web-server/pkg/signurl"
+	"super-web-server/pkg/sms"
 	"super-web-server/pkg/snowflake"
+	"super-web-server/pkg/storage"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -32,11 +40,22 @@ type App struct {
 	snowflake  *snowflake.Snowflake
 	jwt        *jwt.JWT
 	roleCheck  *middleware.RoleCheck
+	apiKeyAuth *middleware.APIKeyAuth
+	mailer     mailer.Mailer
+	hasher     *password.Hasher
+	sms        sms.Provider
+	oidc       []*oidc.Provider
+	storage    storage.Storage
+	signer     *signurl.Signer
+	cron       *cron.Cron
+	policy     *policy.Engine
 }
 
 func NewApp(config *config.Config) (*App, error) {
 	app := &App{config: config}
-	app.InitEngineAndServer()
+	if err := app.InitEngineAndServer(); err != nil {
+		return nil, err
+	}
 
 	validator.Init()
 
@@ -44,6 +63,12 @@ func NewApp(config *config.Config) (*App, error) {
 		return nil, err
 	}
 
+	app.InitPasswordHasher()
+
+	if err := app.InitPasswordPolicy(); err != nil {
+		return nil, err
+	}
+
 	if err := app.InitDatabase(); err != nil {
 		return nil, err
 	}
@@ -56,29 +81,63 @@ func NewApp(config *config.Config) (*App, error) {
 		return nil, err
 	}
 
-	jwtConfig := app.config.JWT
+	if err := app.InitMailer(); err != nil {
+		return nil, err
+	}
+
+	if err := app.InitSMS(); err != nil {
+		return nil, err
+	}
+
+	app.InitOIDC()
+
+	if err := app.InitAvatar(); err != nil {
+		return nil, err
+	}
+
+	if err := app.InitSignedURL(); err != nil {
+		return nil, err
+	}
 
-	app.jwt = jwt.NewJWT(jwt.Config{
-		Secret: jwtConfig.Secret,
-		Expire: jwtConfig.Expire,
-		Issuer: jwtConfig.Issuer,
-	})
+	if err := app.InitStorage(); err != nil {
+		return nil, err
+	}
+
+	if err := app.InitJWT(); err != nil {
+		return nil, err
+	}
+
+	if err := app.InitPolicy(); err != nil {
+		return nil, err
+	}
 
 	app.repo = repo.NewRepo(app.db.DB, logger.GetModuleLogger("repo"))
-	app.service = service.NewService(app.repo, logger.GetModuleLogger("service"), app.redis, app.jwt)
+	app.service = service.NewService(app.repo, logger.GetModuleLogger("service"), app.redis, app.jwt, app.snowflake, app.mailer, app.sms, app.oidc, app.hasher, app.storage, app.signer, app.policy, app.config)
+	app.jwt.Use(app.service.Token().CheckAccessToken, app.service.User().CheckUserStatus, app.service.Session().TouchSession, app.service.AdminUser().CheckImpersonation)
 	app.roleCheck = middleware.NewRoleCheck(app.service)
+	app.apiKeyAuth = middleware.NewAPIKeyAuth(app.service)
+	app.InitCron()
 	app.controller = controller.NewController(app.service, logger.GetModuleLogger("controller"), app.jwt)
 
-	v1.InitApi(app.engine.Group("api/v1"), app.controller, app.jwt, app.roleCheck)
+	app.engine.GET("/.well-known/jwks.json", app.jwt.JWKS())
+	v1.InitApi(app.engine.Group("api/v1"), app.controller, app.jwt, app.roleCheck, app.apiKeyAuth)
 
 	return app, nil
 }
 
 func (a *App) Run() error {
+	a.service.Start()
+	a.cron.Start()
 	logger.InfoF("Starting server on http://localhost:%d", a.config.Server.Port)
 	return a.server.ListenAndServe()
 }
 
+// Shutdown stops taking requests first, so no background work starts while
+// the services stop.
 func (a *App) Shutdown(ctx context.Context) error {
-	return a.server.Shutdown(ctx)
+	err := a.server.Shutdown(ctx)
+	a.jwt.Stop()
+	a.cron.Stop()
+	a.service.Stop()
+	return err
 }
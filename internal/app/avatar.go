@@ -0,0 +1,19 @@
+package app
+
+import (
+	"os"
+	"super-web-server/pkg/logger"
+)
+
+// InitAvatar creates the avatar directory and serves it under the configured
+// URL. The files are always re-encoded JPEGs written by the profile service.
+func (a *App) InitAvatar() error {
+	avatarConfig := a.config.Avatar
+	if err := os.MkdirAll(avatarConfig.Dir, 0o755); err != nil {
+		return err
+	}
+	a.engine.Static(avatarConfig.URL, avatarConfig.Dir)
+
+	logger.Info("avatar initialized successfully")
+	return nil
+}
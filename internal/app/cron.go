@@ -0,0 +1,16 @@
+package app
+
+import (
+	"super-web-server/internal/cron"
+	"super-web-server/pkg/logger"
+)
+
+// InitCron registers the periodic jobs, they start with the server.
+func (a *App) InitCron() {
+	accountConfig := a.config.Account
+
+	a.cron = cron.NewCron(a.redis, logger.GetModuleLogger("cron"))
+	a.cron.Add("account:purge", accountConfig.CronInterval, a.service.Account().PurgeDeletedAccounts)
+	a.cron.Add("account:export-cleanup", accountConfig.CronInterval, a.service.Account().CleanupDataExports)
+	a.cron.Add("user:purge-unverified", accountConfig.CronInterval, a.service.User().PurgeUnverifiedUsers)
+}
@@ -8,6 +8,7 @@ import (
 	"super-web-server/pkg/logger"
 
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 func (a *App) InitDatabase() error {
@@ -37,6 +38,10 @@ func (a *App) InitDatabase() error {
 		Timezone:        dbConfig.Timezone,
 		Charset:         dbConfig.Charset,
 		ParseTime:       dbConfig.ParseTime,
+		GormConfig: &gorm.Config{
+			// translate driver errors (e.g. duplicate key) into gorm errors
+			TranslateError: true,
+		},
 	}
 
 	db, err := database.NewDB(config, gormLogger)
@@ -49,9 +54,34 @@ func (a *App) InitDatabase() error {
 
 	logger.Info("database initialized successfully")
 
+	if err := migrateLegacyPasswordSalt(db); err != nil {
+		logger.Error("database migrate password salt failed", zap.Error(err))
+		return err
+	}
+
+	if err := migrateUserMobile(db); err != nil {
+		logger.Error("database migrate user mobile failed", zap.Error(err))
+		return err
+	}
+
+	// users created before email verification existed are treated as verified
+	backfillEmailVerified := !db.Migrator().HasColumn(&model.User{}, "email_verified_at")
+	// unverified users were active until the account status knew about pending
+	backfillStatus := !db.Migrator().HasColumn(&model.User{}, "status_reason")
+	// roles created before the hierarchy existed get the default one
+	backfillRoleParents := db.Migrator().HasTable(&model.UserRole{}) && !db.Migrator().HasTable("user_role_parent_ref")
+
 	err = db.AutoMigrate(
 		&model.User{},
+		&model.Permission{},
 		&model.UserRole{},
+		&model.UserRecoveryCode{},
+		&model.UserIdentity{},
+		&model.APIKey{},
+		&model.UserSession{},
+		&model.UserPasswordHistory{},
+		&model.File{},
+		&model.UserDataExport{},
 	)
 
 	if err != nil {
@@ -59,9 +89,30 @@ func (a *App) InitDatabase() error {
 		return err
 	}
 
+	if backfillEmailVerified {
+		if err := db.Model(&model.User{}).Where("email_verified_at IS NULL").Update("email_verified_at", gorm.Expr("created_at")).Error; err != nil {
+			logger.Error("database backfill email_verified_at failed", zap.Error(err))
+			return err
+		}
+	}
+
+	if backfillStatus {
+		if err := migrateUserStatus(db); err != nil {
+			logger.Error("database backfill user status failed", zap.Error(err))
+			return err
+		}
+	}
+
+	if backfillRoleParents {
+		if err := migrateUserRoleHierarchy(db); err != nil {
+			logger.Error("database backfill user role hierarchy failed", zap.Error(err))
+			return err
+		}
+	}
+
 	logger.Info("database migrate successfully")
 
-	if err := seed.Seed(db, a.snowflake); err != nil {
+	if err := seed.Seed(db, a.snowflake, a.hasher, a.config.Seed.AdminPassword); err != nil {
 		logger.Error("database seed failed", zap.Error(err))
 		return err
 	}
@@ -70,3 +121,60 @@ func (a *App) InitDatabase() error {
 
 	return nil
 }
+
+// migrateLegacyPasswordSalt folds the salt column into the password hash
+// ("$bcrypt-salted$<salt>$<bcrypt hash>") and drops it. The hashes are
+// upgraded to argon2id on the next login of each user.
+func migrateLegacyPasswordSalt(db *database.DB) error {
+	if !db.Migrator().HasColumn(&model.User{}, "salt") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(
+			"UPDATE users SET password = CONCAT(?, salt, '$', password) WHERE password LIKE ?",
+			"$bcrypt-salted$", "$2%",
+		).Error; err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&model.User{}, "salt")
+	})
+}
+
+// migrateUserMobile stores users without a mobile as NULL before the unique
+// index on mobile replaces the plain one, they would collide on "" otherwise.
+func migrateUserMobile(db *database.DB) error {
+	if !db.Migrator().HasTable(&model.User{}) || db.Migrator().HasIndex(&model.User{}, "uk_users_mobile") {
+		return nil
+	}
+	if err := db.Model(&model.User{}).Unscoped().Where("mobile = ?", "").Update("mobile", nil).Error; err != nil {
+		return err
+	}
+	if db.Migrator().HasIndex(&model.User{}, "idx_users_mobile") {
+		return db.Migrator().DropIndex(&model.User{}, "idx_users_mobile")
+	}
+	return nil
+}
+
+// migrateUserStatus marks the users whose email is unverified as pending.
+func migrateUserStatus(db *database.DB) error {
+	return db.Model(&model.User{}).Unscoped().
+		Where("email_verified_at IS NULL").
+		Update("status", model.UserStatusPending).Error
+}
+
+// migrateUserRoleHierarchy marks the built-in roles and links them as the
+// default hierarchy does.
+func migrateUserRoleHierarchy(db *database.DB) error {
+	codes := make([]model.UserRoleEnum, 0, len(model.UserRoleSet))
+	for _, role := range model.UserRoleSet {
+		codes = append(codes, role.Code)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.UserRole{}).Where("code IN ?", codes).Update("system", true).Error; err != nil {
+			return err
+		}
+		return seed.LinkUserRoleParents(tx, codes)
+	})
+}
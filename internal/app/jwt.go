@@ -0,0 +1,46 @@
+package app
+
+import (
+	"fmt"
+	"super-web-server/internal/types"
+	"super-web-server/pkg/jwt"
+	"super-web-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+func (a *App) InitJWT() error {
+	jwtConfig := a.config.JWT
+
+	j, err := jwt.NewJWT(jwt.Config{
+		Secret:              jwtConfig.Secret,
+		Expire:              jwtConfig.Expire,
+		Issuer:              jwtConfig.Issuer,
+		RefreshExpire:       jwtConfig.RefreshExpire,
+		Algorithm:           jwtConfig.Algorithm,
+		KeyDir:              jwtConfig.KeyDir,
+		RotateInterval:      jwtConfig.RotateInterval,
+		KeyRetention:        jwtConfig.KeyRetention,
+		ImpersonationExpire: jwtConfig.ImpersonationExpire,
+	})
+	if err != nil {
+		return fmt.Errorf("init jwt failed: %w", err)
+	}
+
+	if a.config.Mode == types.ServerModeProd {
+		if jwtConfig.Algorithm == jwt.AlgorithmHS256 && len(jwtConfig.Secret) < 32 {
+			logger.Warn("jwt secret is shorter than 32 bytes, use a longer secret or an asymmetric algorithm")
+		}
+		if jwtConfig.Algorithm != jwt.AlgorithmHS256 && jwtConfig.KeyDir == "" {
+			logger.Warn("jwt keyDir is empty, access tokens are invalidated on restart")
+		}
+	}
+
+	j.Start(func(err error) {
+		logger.Error("jwt key rotation failed", zap.Error(err))
+	})
+	a.jwt = j
+
+	logger.Info("jwt initialized successfully", zap.String("algorithm", jwtConfig.Algorithm))
+	return nil
+}
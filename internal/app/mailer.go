@@ -0,0 +1,40 @@
+package app
+
+import (
+	"fmt"
+	"super-web-server/internal/types"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/mailer"
+)
+
+func (a *App) InitMailer() error {
+	var mailConfig = a.config.Mail
+
+	switch mailConfig.Driver {
+	case "smtp":
+		a.mailer = mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     mailConfig.Host,
+			Port:     mailConfig.Port,
+			Username: mailConfig.Username,
+			Password: mailConfig.Password,
+			From:     mailConfig.From,
+			TLS:      mailConfig.TLS,
+			Timeout:  mailConfig.Timeout,
+		})
+	case "file":
+		fileMailer, err := mailer.NewFileMailer(mailConfig.Directory, mailConfig.From)
+		if err != nil {
+			return fmt.Errorf("init file mailer failed %w", err)
+		}
+		a.mailer = fileMailer
+	default:
+		a.mailer = mailer.NewConsoleMailer(logger.GetModuleLogger("mailer"), mailConfig.From)
+	}
+
+	if a.config.Mode == types.ServerModeProd && mailConfig.Driver != "smtp" {
+		logger.WarnF("mailer driver %s is used in prod mode, no mail will be delivered", mailConfig.Driver)
+	}
+
+	logger.Info("mailer initialized successfully")
+	return nil
+}
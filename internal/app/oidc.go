@@ -0,0 +1,26 @@
+package app
+
+import (
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/oidc"
+
+	"go.uber.org/zap"
+)
+
+// InitOIDC creates the configured identity providers. Discovery happens on
+// first use, an unreachable provider does not block the startup.
+func (a *App) InitOIDC() {
+	for _, provider := range a.config.OIDC.Providers {
+		a.oidc = append(a.oidc, oidc.NewProvider(oidc.Config{
+			Name:         provider.Name,
+			Issuer:       provider.Issuer,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  provider.RedirectURL,
+			Scopes:       provider.Scopes,
+		}, nil))
+		logger.Info("oidc provider registered", zap.String("name", provider.Name), zap.String("issuer", provider.Issuer))
+	}
+
+	logger.Info("oidc initialized successfully")
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"fmt"
+	"super-web-server/internal/validator"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/password"
+
+	"go.uber.org/zap"
+)
+
+func (a *App) InitPasswordHasher() {
+	hashConfig := a.config.PasswordHash
+
+	a.hasher = password.NewHasher(password.Params{
+		Memory:      hashConfig.Memory,
+		Iterations:  hashConfig.Iterations,
+		Parallelism: hashConfig.Parallelism,
+		SaltLength:  hashConfig.SaltLength,
+		KeyLength:   hashConfig.KeyLength,
+	})
+
+	logger.Info("password hasher initialized successfully")
+}
+
+func (a *App) InitPasswordPolicy() error {
+	policyConfig := a.config.PasswordPolicy
+
+	policy := &password.Policy{
+		MinLength:     policyConfig.MinLength,
+		MaxLength:     policyConfig.MaxLength,
+		RequireLower:  policyConfig.RequireLower,
+		RequireUpper:  policyConfig.RequireUpper,
+		RequireDigit:  policyConfig.RequireDigit,
+		RequireSymbol: policyConfig.RequireSymbol,
+		MinClasses:    policyConfig.MinClasses,
+	}
+
+	if policyConfig.BreachedFile != "" {
+		breached, err := password.LoadBreachedList(policyConfig.BreachedFile)
+		if err != nil {
+			return fmt.Errorf("load breached password list failed %w", err)
+		}
+		policy.Breached = breached
+		logger.Info("breached password list loaded", zap.String("file", policyConfig.BreachedFile), zap.Int("count", breached.Len()))
+	}
+
+	validator.RegisterPasswordPolicy(policy)
+
+	logger.Info("password policy initialized successfully")
+	return nil
+}
@@ -0,0 +1,20 @@
+package app
+
+import (
+	"fmt"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/policy"
+
+	"go.uber.org/zap"
+)
+
+func (a *App) InitPolicy() error {
+	engine, err := policy.LoadFile(a.config.Policy.File)
+	if err != nil {
+		return fmt.Errorf("load policy file failed %w", err)
+	}
+	a.policy = engine
+
+	logger.Info("policy initialized successfully", zap.String("file", a.config.Policy.File))
+	return nil
+}
@@ -9,7 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func (a *App) InitEngineAndServer() {
+func (a *App) InitEngineAndServer() error {
 	var serverConfig = a.config.Server
 	if a.config.Mode != types.ServerModeDev {
 		gin.SetMode(gin.ReleaseMode)
@@ -18,6 +18,11 @@ func (a *App) InitEngineAndServer() {
 	}
 
 	a.engine = gin.New()
+	// gin trusts X-Forwarded-For from anyone by default, the client IP binds
+	// signed links and keys the login limit, so only listed proxies may set it
+	if err := a.engine.SetTrustedProxies(serverConfig.TrustedProxies); err != nil {
+		return fmt.Errorf("set trusted proxies failed: %w", err)
+	}
 	a.engine.Use(middleware.Recovery())
 	a.engine.Use(middleware.Logger())
 	a.engine.Use(middleware.CORS())
@@ -31,4 +36,5 @@ func (a *App) InitEngineAndServer() {
 		MaxHeaderBytes: serverConfig.MaxHeaderBytes,
 	}
 	a.server = server
+	return nil
 }
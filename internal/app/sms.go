@@ -0,0 +1,21 @@
+package app
+
+import (
+	"super-web-server/internal/types"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/sms"
+)
+
+func (a *App) InitSMS() error {
+	switch a.config.SMS.Driver {
+	default:
+		a.sms = sms.NewLogProvider(logger.GetModuleLogger("sms"))
+	}
+
+	if a.config.Mode == types.ServerModeProd && a.config.SMS.Driver == "log" {
+		logger.Warn("sms driver log is used in prod mode, no sms will be delivered")
+	}
+
+	logger.Info("sms initialized successfully")
+	return nil
+}
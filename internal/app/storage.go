@@ -0,0 +1,72 @@
+package app
+
+import (
+	"crypto/rand"
+	"fmt"
+	"super-web-server/internal/types"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/signurl"
+	"super-web-server/pkg/storage"
+
+	"go.uber.org/zap"
+)
+
+// InitSignedURL creates the signer of signed links. Without configured keys a
+// random key is used, links then only work until the next restart and only
+// on this instance.
+func (a *App) InitSignedURL() error {
+	signedURLConfig := a.config.SignedURL
+
+	var keys []signurl.Key
+	for _, key := range signedURLConfig.Keys {
+		keys = append(keys, signurl.Key{ID: key.ID, Secret: []byte(key.Secret)})
+	}
+	if len(keys) == 0 {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return fmt.Errorf("generate signed url key failed: %w", err)
+		}
+		keys = append(keys, signurl.Key{ID: "ephemeral", Secret: secret})
+		if a.config.Mode == types.ServerModeProd {
+			logger.Warn("signedUrl keys are empty, signed urls are invalidated on restart")
+		}
+	}
+
+	signer, err := signurl.NewSigner(keys...)
+	if err != nil {
+		return fmt.Errorf("init signed url failed: %w", err)
+	}
+	a.signer = signer
+
+	logger.Info("signed url initialized successfully", zap.String("keyId", keys[0].ID))
+	return nil
+}
+
+func (a *App) InitStorage() error {
+	storageConfig := a.config.Storage
+
+	switch storageConfig.Driver {
+	case "s3":
+		s3, err := storage.NewS3(storage.S3Config{
+			Endpoint:        storageConfig.S3Endpoint,
+			Region:          storageConfig.S3Region,
+			Bucket:          storageConfig.S3Bucket,
+			AccessKeyID:     storageConfig.S3AccessKeyID,
+			SecretAccessKey: storageConfig.S3SecretAccessKey,
+			PathStyle:       storageConfig.S3PathStyle,
+		})
+		if err != nil {
+			return fmt.Errorf("init s3 storage failed: %w", err)
+		}
+		a.storage = s3
+	default:
+		local, err := storage.NewLocal(storageConfig.Root, storage.NewURLSigner(storageConfig.URL, a.signer))
+		if err != nil {
+			return fmt.Errorf("init local storage failed: %w", err)
+		}
+		a.storage = local
+	}
+
+	logger.Info("storage initialized successfully", zap.String("driver", storageConfig.Driver))
+	return nil
+}
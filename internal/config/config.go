@@ -18,6 +18,26 @@ type Config struct {
 	Redis  RedisConfig  `mapstructure:"redis"`
 	JWT    JWTConfig    `mapstructure:"jwt"`
 	Log    LogConfig    `mapstructure:"log"`
+
+	Register       RegisterConfig       `mapstructure:"register"`
+	VerifyCode     VerifyCodeConfig     `mapstructure:"verifyCode"`
+	Mail           MailConfig           `mapstructure:"mail"`
+	PasswordReset  PasswordResetConfig  `mapstructure:"passwordReset"`
+	SMS            SMSConfig            `mapstructure:"sms"`
+	LoginLimit     LoginLimitConfig     `mapstructure:"loginLimit"`
+	MFA            MFAConfig            `mapstructure:"mfa"`
+	OIDC           OIDCConfig           `mapstructure:"oidc"`
+	PasswordHash   PasswordHashConfig   `mapstructure:"passwordHash"`
+	PasswordPolicy PasswordPolicyConfig `mapstructure:"passwordPolicy"`
+	Avatar         AvatarConfig         `mapstructure:"avatar"`
+	Storage        StorageConfig        `mapstructure:"storage"`
+	SignedURL      SignedURLConfig      `mapstructure:"signedUrl"`
+	EmailChange    EmailChangeConfig    `mapstructure:"emailChange"`
+	Account        AccountConfig        `mapstructure:"account"`
+	RoleCache      RoleCacheConfig      `mapstructure:"roleCache"`
+	Policy         PolicyConfig         `mapstructure:"policy"`
+	Reauth         ReauthConfig         `mapstructure:"reauth"`
+	Seed           SeedConfig           `mapstructure:"seed"`
 }
 
 var defaultConfig = &Config{
@@ -60,9 +80,112 @@ var defaultConfig = &Config{
 		DB:       0,
 	},
 	JWT: JWTConfig{
-		Secret: "123456",
-		Expire: 1 * time.Hour,
-		Issuer: "super-web-server",
+		Expire:              1 * time.Hour,
+		Issuer:              "super-web-server",
+		RefreshExpire:       7 * 24 * time.Hour,
+		KeyDir:              "./keys",
+		RotateInterval:      30 * 24 * time.Hour,
+		KeyRetention:        24 * time.Hour,
+		ImpersonationExpire: 15 * time.Minute,
+	},
+	Register: RegisterConfig{
+		UnverifiedExpire: 24 * time.Hour,
+	},
+	VerifyCode: VerifyCodeConfig{
+		Length:         6,
+		Expire:         10 * time.Minute,
+		MaxAttempts:    5,
+		ResendInterval: 1 * time.Minute,
+	},
+	Mail: MailConfig{
+		Driver:    "console",
+		From:      "super-web-server <noreply@example.com>",
+		Port:      587,
+		Timeout:   10 * time.Second,
+		Directory: "./mails",
+	},
+	PasswordReset: PasswordResetConfig{
+		Expire: 30 * time.Minute,
+		URL:    "http://localhost:3000/reset-password?token=%s",
+	},
+	SMS: SMSConfig{
+		Driver: "log",
+	},
+	LoginLimit: LoginLimitConfig{
+		Window:              15 * time.Minute,
+		DelayAfter:          3,
+		BaseDelay:           1 * time.Second,
+		MaxDelay:            30 * time.Second,
+		MaxFailuresPerEmail: 10,
+		MaxFailuresPerIP:    50,
+		Lockout:             15 * time.Minute,
+	},
+	MFA: MFAConfig{
+		Issuer:          "super-web-server",
+		EnrollExpire:    10 * time.Minute,
+		ChallengeExpire: 5 * time.Minute,
+		MaxAttempts:     5,
+		Skew:            1,
+		RecoveryCodes:   10,
+	},
+	OIDC: OIDCConfig{
+		StateExpire: 10 * time.Minute,
+	},
+	PasswordHash: PasswordHashConfig{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+	},
+	PasswordPolicy: PasswordPolicyConfig{
+		MinLength:    8,
+		MaxLength:    64,
+		MinClasses:   2,
+		History:      5,
+		BreachedFile: "./configs/breached-passwords.txt",
+	},
+	Avatar: AvatarConfig{
+		Dir:       "./static/avatars",
+		URL:       "/static/avatars",
+		MaxSize:   5 << 20,
+		MaxPixels: 8_000_000,
+		Size:      256,
+	},
+	Storage: StorageConfig{
+		Driver:  "local",
+		Root:    "./static/files",
+		URL:     "/api/v1/storage",
+		MaxSize: 100 << 20,
+	},
+	SignedURL: SignedURLConfig{
+		BaseURL:   "/api/v1/files/signed",
+		Expire:    15 * time.Minute,
+		MaxExpire: 7 * 24 * time.Hour,
+	},
+	EmailChange: EmailChangeConfig{
+		RevertExpire: 7 * 24 * time.Hour,
+		RevertURL:    "http://localhost:3000/email/revert?token=%s",
+	},
+	Account: AccountConfig{
+		DeletionGracePeriod: 30 * 24 * time.Hour,
+		RestoreURL:          "http://localhost:3000/account/restore?token=%s",
+		ExportURL:           "/api/v1/user/data-exports/signed",
+		ExportExpire:        72 * time.Hour,
+		ExportTimeout:       time.Hour,
+		ExportInterval:      24 * time.Hour,
+		CronInterval:        10 * time.Minute,
+	},
+	RoleCache: RoleCacheConfig{
+		TTL:      5 * time.Minute,
+		LocalTTL: 30 * time.Second,
+		Channel:  "user:roles:invalidate",
+	},
+	Policy: PolicyConfig{
+		File: "./configs/policy.yml",
+	},
+	Reauth: ReauthConfig{
+		MaxAge: 10 * time.Minute,
 	},
 }
 
@@ -111,6 +234,25 @@ func setDefaults(v *viper.Viper) {
 	setDefaultsFromStruct(v, "redis", defaultConfig.Redis)
 	setDefaultsFromStruct(v, "jwt", defaultConfig.JWT)
 	setDefaultsFromStruct(v, "log", defaultConfig.Log)
+	setDefaultsFromStruct(v, "register", defaultConfig.Register)
+	setDefaultsFromStruct(v, "verifyCode", defaultConfig.VerifyCode)
+	setDefaultsFromStruct(v, "mail", defaultConfig.Mail)
+	setDefaultsFromStruct(v, "passwordReset", defaultConfig.PasswordReset)
+	setDefaultsFromStruct(v, "sms", defaultConfig.SMS)
+	setDefaultsFromStruct(v, "loginLimit", defaultConfig.LoginLimit)
+	setDefaultsFromStruct(v, "mfa", defaultConfig.MFA)
+	setDefaultsFromStruct(v, "oidc", defaultConfig.OIDC)
+	setDefaultsFromStruct(v, "passwordHash", defaultConfig.PasswordHash)
+	setDefaultsFromStruct(v, "passwordPolicy", defaultConfig.PasswordPolicy)
+	setDefaultsFromStruct(v, "avatar", defaultConfig.Avatar)
+	setDefaultsFromStruct(v, "storage", defaultConfig.Storage)
+	setDefaultsFromStruct(v, "signedUrl", defaultConfig.SignedURL)
+	setDefaultsFromStruct(v, "emailChange", defaultConfig.EmailChange)
+	setDefaultsFromStruct(v, "account", defaultConfig.Account)
+	setDefaultsFromStruct(v, "roleCache", defaultConfig.RoleCache)
+	setDefaultsFromStruct(v, "policy", defaultConfig.Policy)
+	setDefaultsFromStruct(v, "reauth", defaultConfig.Reauth)
+	setDefaultsFromStruct(v, "seed", defaultConfig.Seed)
 }
 
 // setDefaultsFromStruct 使用反射设置结构体的默认值
@@ -4,10 +4,11 @@ import "time"
 
 type ServerConfig struct {
 	Port           int           `mapstructure:"port"`
-	ReadTimeout    time.Duration `mapstructure:"readTimeout"`    // 读取超时时间
-	WriteTimeout   time.Duration `mapstructure:"writeTimeout"`   // 写入超时时间
-	MaxHeaderBytes int           `mapstructure:"maxHeaderBytes"` // 最大头字节数
-	SnowflakeNode  int64         `mapstructure:"snowflakeNode"`  // 雪花算法节点
+	ReadTimeout    time.Duration `mapstructure:"readTimeout"`                            // 读取超时时间
+	WriteTimeout   time.Duration `mapstructure:"writeTimeout"`                           // 写入超时时间
+	MaxHeaderBytes int           `mapstructure:"maxHeaderBytes"`                         // 最大头字节数
+	SnowflakeNode  int64         `mapstructure:"snowflakeNode"`                          // 雪花算法节点
+	TrustedProxies []string      `mapstructure:"trustedProxies" validate:"dive,ip|cidr"` // 可信反向代理 (IP 或 CIDR), 只有经过它们的请求才读取 X-Forwarded-For, 为空时使用连接地址
 }
 
 type LogConfig struct {
@@ -45,7 +46,164 @@ type RedisConfig struct {
 }
 
 type JWTConfig struct {
-	Secret string        `mapstructure:"secret"`
-	Expire time.Duration `mapstructure:"expire"`
-	Issuer string        `mapstructure:"issuer"`
+	Secret              string        `mapstructure:"secret" validate:"required_if=Algorithm HS256"` // HS256 密钥
+	Expire              time.Duration `mapstructure:"expire"`
+	Issuer              string        `mapstructure:"issuer"`
+	RefreshExpire       time.Duration `mapstructure:"refreshExpire"`                                               // 刷新令牌有效期
+	Algorithm           string        `mapstructure:"algorithm" validate:"required,oneof=HS256 RS256 ES256 EdDSA"` // 签名算法, 必填, 无默认值
+	KeyDir              string        `mapstructure:"keyDir"`                                                      // 非对称密钥目录, 多实例需共享, 为空时密钥仅保存在内存中
+	RotateInterval      time.Duration `mapstructure:"rotateInterval" validate:"min=1m"`                            // 签名密钥轮换间隔
+	KeyRetention        time.Duration `mapstructure:"keyRetention"`                                                // 轮换后旧密钥继续用于验签的时间, 不小于 expire
+	ImpersonationExpire time.Duration `mapstructure:"impersonationExpire" validate:"min=1m,ltefield=Expire"`       // 超级管理员模拟用户登录的令牌有效期, 不可刷新
+}
+
+type RegisterConfig struct {
+	UnverifiedExpire time.Duration `mapstructure:"unverifiedExpire" validate:"min=1m"` // 未验证邮箱的注册保留时长, 过后删除, 邮箱可重新注册
+}
+
+type VerifyCodeConfig struct {
+	Length         int           `mapstructure:"length" validate:"min=4,max=10"` // 验证码长度
+	Expire         time.Duration `mapstructure:"expire"`                         // 验证码有效期
+	MaxAttempts    int           `mapstructure:"maxAttempts" validate:"min=1"`   // 最大校验次数
+	ResendInterval time.Duration `mapstructure:"resendInterval"`                 // 重发间隔
+}
+
+type MailConfig struct {
+	Driver    string        `mapstructure:"driver" validate:"required,oneof=smtp file console"` // 发送方式
+	From      string        `mapstructure:"from" validate:"required"`                           // 发件人
+	Host      string        `mapstructure:"host"`                                               // SMTP 主机
+	Port      int           `mapstructure:"port"`                                               // SMTP 端口
+	Username  string        `mapstructure:"username"`                                           // SMTP 用户名
+	Password  string        `mapstructure:"password"`                                           // SMTP 密码
+	TLS       bool          `mapstructure:"tls"`                                                // 是否使用隐式 TLS
+	Timeout   time.Duration `mapstructure:"timeout"`                                            // SMTP 超时时间
+	Directory string        `mapstructure:"directory"`                                          // file 方式的邮件目录
+}
+
+type PasswordResetConfig struct {
+	Expire time.Duration `mapstructure:"expire"`                  // 重置链接有效期
+	URL    string        `mapstructure:"url" validate:"required"` // 重置页面地址, %s 会被替换为重置令牌
+}
+
+type SMSConfig struct {
+	Driver string `mapstructure:"driver" validate:"required,oneof=log"` // 短信服务商
+}
+
+type LoginLimitConfig struct {
+	Window              time.Duration `mapstructure:"window"`                               // 失败次数统计窗口
+	DelayAfter          int           `mapstructure:"delayAfter"`                           // 失败多少次后开始递增延迟
+	BaseDelay           time.Duration `mapstructure:"baseDelay"`                            // 初始延迟
+	MaxDelay            time.Duration `mapstructure:"maxDelay"`                             // 最大延迟
+	MaxFailuresPerEmail int           `mapstructure:"maxFailuresPerEmail" validate:"min=1"` // 单个邮箱最大失败次数
+	MaxFailuresPerIP    int           `mapstructure:"maxFailuresPerIP" validate:"min=1"`    // 单个 IP 最大失败次数
+	Lockout             time.Duration `mapstructure:"lockout"`                              // 超过最大失败次数后的锁定时间
+}
+
+type MFAConfig struct {
+	Issuer          string        `mapstructure:"issuer" validate:"required"`     // 身份验证器中显示的发行方
+	EnrollExpire    time.Duration `mapstructure:"enrollExpire"`                   // 绑定流程有效期
+	ChallengeExpire time.Duration `mapstructure:"challengeExpire"`                // 登录二次验证有效期
+	MaxAttempts     int           `mapstructure:"maxAttempts" validate:"min=1"`   // 登录二次验证最大尝试次数
+	Skew            int           `mapstructure:"skew" validate:"min=0,max=2"`    // 允许的时间步偏差
+	RecoveryCodes   int           `mapstructure:"recoveryCodes" validate:"min=1"` // 恢复码数量
+}
+
+type OIDCConfig struct {
+	StateExpire time.Duration        `mapstructure:"stateExpire"`               // 授权流程有效期
+	Providers   []OIDCProviderConfig `mapstructure:"providers" validate:"dive"` // 身份提供方列表
+}
+
+type OIDCProviderConfig struct {
+	Name         string   `mapstructure:"name" validate:"required,alphanum,lowercase"` // 提供方名称, 用于路由 /user/oauth/:provider
+	Issuer       string   `mapstructure:"issuer" validate:"required,url"`              // 发行方地址, 用于自动发现
+	ClientID     string   `mapstructure:"clientId" validate:"required"`                // 客户端 ID
+	ClientSecret string   `mapstructure:"clientSecret"`                                // 客户端密钥, 公共客户端可为空
+	RedirectURL  string   `mapstructure:"redirectUrl" validate:"required,url"`         // 回调地址
+	Scopes       []string `mapstructure:"scopes"`                                      // 申请的权限范围, 默认 openid email profile
+}
+
+type PasswordHashConfig struct {
+	Memory      uint32 `mapstructure:"memory" validate:"min=8192"`         // argon2id 内存开销 (KiB)
+	Iterations  uint32 `mapstructure:"iterations" validate:"min=1"`        // argon2id 迭代次数
+	Parallelism uint8  `mapstructure:"parallelism" validate:"min=1"`       // argon2id 并行度
+	SaltLength  uint32 `mapstructure:"saltLength" validate:"min=16"`       // 盐长度 (字节)
+	KeyLength   uint32 `mapstructure:"keyLength" validate:"min=16,max=64"` // 哈希长度 (字节)
+}
+
+type PasswordPolicyConfig struct {
+	MinLength     int    `mapstructure:"minLength" validate:"min=6"`                      // 最小长度
+	MaxLength     int    `mapstructure:"maxLength" validate:"gtefield=MinLength,max=128"` // 最大长度
+	RequireLower  bool   `mapstructure:"requireLower"`                                    // 必须包含小写字母
+	RequireUpper  bool   `mapstructure:"requireUpper"`                                    // 必须包含大写字母
+	RequireDigit  bool   `mapstructure:"requireDigit"`                                    // 必须包含数字
+	RequireSymbol bool   `mapstructure:"requireSymbol"`                                   // 必须包含特殊字符
+	MinClasses    int    `mapstructure:"minClasses" validate:"min=0,max=4"`               // 至少包含几类字符 (小写, 大写, 数字, 特殊字符)
+	History       int    `mapstructure:"history" validate:"min=0"`                        // 不能与最近几次使用过的密码相同
+	BreachedFile  string `mapstructure:"breachedFile"`                                    // 泄露密码 SHA-1 列表文件, 为空时不检查
+}
+
+type AvatarConfig struct {
+	Dir       string `mapstructure:"dir" validate:"required"`                 // 头像保存目录
+	URL       string `mapstructure:"url" validate:"required,startswith=/"`    // 头像访问路径前缀, 指向 dir
+	MaxSize   int64  `mapstructure:"maxSize" validate:"min=1"`                // 上传文件最大字节数
+	MaxPixels int    `mapstructure:"maxPixels" validate:"min=1,max=16000000"` // 上传图片最大像素数, 解码前按图片头部的尺寸检查, 解码和裁剪约占用 8 字节/像素
+	Size      int    `mapstructure:"size" validate:"min=16,max=2048"`         // 头像边长 (像素)
+}
+
+type StorageConfig struct {
+	Driver            string `mapstructure:"driver" validate:"required,oneof=local s3"`      // 存储后端
+	Root              string `mapstructure:"root" validate:"required_if=Driver local"`       // local 方式的存储根目录
+	URL               string `mapstructure:"url" validate:"required_if=Driver local"`        // local 方式签名链接的地址前缀, 指向 /api/v1/storage
+	MaxSize           int64  `mapstructure:"maxSize" validate:"min=1"`                       // 上传文件最大字节数
+	S3Endpoint        string `mapstructure:"s3Endpoint" validate:"required_if=Driver s3"`    // S3 服务地址
+	S3Region          string `mapstructure:"s3Region" validate:"required_if=Driver s3"`      // S3 区域
+	S3Bucket          string `mapstructure:"s3Bucket" validate:"required_if=Driver s3"`      // S3 存储桶
+	S3AccessKeyID     string `mapstructure:"s3AccessKeyId" validate:"required_if=Driver s3"` // S3 访问密钥 ID
+	S3SecretAccessKey string `mapstructure:"s3SecretAccessKey"`                              // S3 访问密钥
+	S3PathStyle       bool   `mapstructure:"s3PathStyle"`                                    // 使用路径方式访问存储桶 (MinIO 等)
+}
+
+type SignedURLConfig struct {
+	BaseURL   string               `mapstructure:"baseUrl" validate:"required"`          // 文件签名链接的地址前缀, 指向 /api/v1/files/signed
+	Expire    time.Duration        `mapstructure:"expire" validate:"min=1s"`             // 默认有效期
+	MaxExpire time.Duration        `mapstructure:"maxExpire" validate:"gtefield=Expire"` // 最长有效期
+	Keys      []SignedURLKeyConfig `mapstructure:"keys" validate:"dive"`                 // 签名密钥, 第一个用于签名, 其余只用于验证 (轮换), 为空时随机生成且仅保存在内存中
+}
+
+type SignedURLKeyConfig struct {
+	ID     string `mapstructure:"id" validate:"required,max=32"`     // 密钥 ID, 写入链接用于选择验证密钥
+	Secret string `mapstructure:"secret" validate:"required,min=32"` // HMAC 密钥
+}
+
+type EmailChangeConfig struct {
+	RevertExpire time.Duration `mapstructure:"revertExpire" validate:"min=1m"` // 旧邮箱撤销链接有效期
+	RevertURL    string        `mapstructure:"revertUrl" validate:"required"`  // 撤销页面地址, %s 会被替换为撤销令牌
+}
+
+type AccountConfig struct {
+	DeletionGracePeriod time.Duration `mapstructure:"deletionGracePeriod" validate:"min=1m"` // 注销宽限期, 期间可恢复账号, 过后匿名化个人信息
+	RestoreURL          string        `mapstructure:"restoreUrl" validate:"required"`        // 恢复账号页面地址, %s 会被替换为恢复令牌
+	ExportURL           string        `mapstructure:"exportUrl" validate:"required"`         // 数据导出签名链接的地址前缀, 指向 /api/v1/user/data-exports/signed
+	ExportExpire        time.Duration `mapstructure:"exportExpire" validate:"min=1m"`        // 导出文件保留时长
+	ExportTimeout       time.Duration `mapstructure:"exportTimeout" validate:"min=1m"`       // 单次导出最长耗时, 超时视为失败
+	ExportInterval      time.Duration `mapstructure:"exportInterval" validate:"min=0"`       // 同一用户两次导出的最短间隔, 0 表示不限制
+	CronInterval        time.Duration `mapstructure:"cronInterval" validate:"min=1s"`        // 匿名化, 清理过期导出和过期未验证注册的执行间隔
+}
+
+type RoleCacheConfig struct {
+	TTL      time.Duration `mapstructure:"ttl" validate:"min=1s"`                  // Redis 中用户角色和权限的缓存时间
+	LocalTTL time.Duration `mapstructure:"localTtl" validate:"min=0,ltefield=TTL"` // 进程内缓存时间, 0 表示不缓存; 失效消息丢失时最多延迟这么久生效
+	Channel  string        `mapstructure:"channel" validate:"required"`            // 通知所有实例清除进程内缓存的 Redis 频道
+}
+
+type PolicyConfig struct {
+	File string `mapstructure:"file" validate:"required"` // 资源授权策略文件, 支持 yml, json, toml
+}
+
+type ReauthConfig struct {
+	MaxAge time.Duration `mapstructure:"maxAge" validate:"min=1m"` // 无密码账号 (第三方登录) 在登录后多久内可直接进行敏感操作, 之后需要邮件验证码
+}
+
+type SeedConfig struct {
+	AdminPassword string `mapstructure:"adminPassword"` // 首次启动创建的超级管理员密码, 为空时随机生成并只打印到标准输出一次
 }
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"super-web-server/internal/ctx"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/service"
+	"super-web-server/pkg/logger"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccountController interface {
+	RequestDataExport(gtx *gin.Context)
+	ListDataExports(gtx *gin.Context)
+	GetDataExport(gtx *gin.Context)
+	DownloadDataExport(gtx *gin.Context)
+	Delete(gtx *gin.Context)
+	Restore(gtx *gin.Context)
+}
+
+type accountController struct {
+	accountService service.AccountService
+	logger         *logger.Logger
+}
+
+func NewAccountController(accountService service.AccountService, logger *logger.Logger) AccountController {
+	logger.Info("NewAccountController initialized successfully")
+	return &accountController{
+		accountService: accountService,
+		logger:         logger,
+	}
+}
+
+func (c *accountController) RequestDataExport(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	data, ex := c.accountService.RequestDataExport(gtx, userUniqueID)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *accountController) ListDataExports(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	var req dto.UserDataExportListReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	list, total, ex := c.accountService.ListDataExports(gtx, userUniqueID, req)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccessPageList(list, total, &req.Pagination)
+}
+
+func (c *accountController) GetDataExport(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	uniqueID, err := strconv.ParseInt(appCtx.Param("uniqueId"), 10, 64)
+	if err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails("invalid uniqueId"))
+		return
+	}
+	data, ex := c.accountService.GetDataExport(gtx, userUniqueID, uniqueID)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+// DownloadDataExport serves the link of a ready export, no token needed.
+func (c *accountController) DownloadDataExport(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	uniqueID, err := strconv.ParseInt(appCtx.Param("uniqueId"), 10, 64)
+	if err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails("invalid uniqueId"))
+		return
+	}
+	export, reader, ex := c.accountService.OpenSignedDataExport(gtx, uniqueID, gtx.Request.URL.Query())
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	defer reader.Close()
+
+	header := gtx.Writer.Header()
+	header.Set("Content-Type", "application/zip")
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fmt.Sprintf("data-export-%d.zip", export.UniqueID)}))
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Cache-Control", "private, no-store")
+
+	var modTime time.Time
+	if export.UpdatedAt != nil {
+		modTime = *export.UpdatedAt
+	}
+	http.ServeContent(gtx.Writer, gtx.Request, "", modTime, reader)
+}
+
+func (c *accountController) Delete(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	var req dto.AccountDeleteReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, ex := c.accountService.RequestDeletion(gtx, userUniqueID, appCtx.GetSessionID(), req)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+// Restore is opened from the link mailed on deletion, no token needed.
+func (c *accountController) Restore(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	var req dto.AccountRestoreReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	if ex := c.accountService.RestoreAccount(gtx, req); ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
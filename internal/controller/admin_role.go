@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"super-web-server/internal/ctx"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/service"
+	"super-web-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminRoleController interface {
+	List(gtx *gin.Context)
+	Get(gtx *gin.Context)
+	Create(gtx *gin.Context)
+	Update(gtx *gin.Context)
+	Delete(gtx *gin.Context)
+	ListPermissions(gtx *gin.Context)
+}
+
+type adminRoleController struct {
+	adminRoleService service.AdminRoleService
+	logger           *logger.Logger
+}
+
+func NewAdminRoleController(adminRoleService service.AdminRoleService, logger *logger.Logger) AdminRoleController {
+	logger.Info("NewAdminRoleController initialized successfully")
+	return &adminRoleController{
+		adminRoleService: adminRoleService,
+		logger:           logger,
+	}
+}
+
+func (c *adminRoleController) List(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	data, ex := c.adminRoleService.List(gtx)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *adminRoleController) Get(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	data, ex := c.adminRoleService.Get(gtx, model.UserRoleEnum(gtx.Param("code")))
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *adminRoleController) Create(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	actorUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	var req dto.AdminRoleCreateReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, ex := c.adminRoleService.Create(gtx, actorUniqueID, req)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *adminRoleController) Update(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	actorUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	var req dto.AdminRoleUpdateReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, ex := c.adminRoleService.Update(gtx, actorUniqueID, model.UserRoleEnum(gtx.Param("code")), req)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *adminRoleController) Delete(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	actorUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	if ex := c.adminRoleService.Delete(gtx, actorUniqueID, model.UserRoleEnum(gtx.Param("code"))); ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
+
+func (c *adminRoleController) ListPermissions(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	data, ex := c.adminRoleService.ListPermissions(gtx)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
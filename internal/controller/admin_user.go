@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"strconv"
+	"super-web-server/internal/ctx"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/service"
+	"super-web-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminUserController interface {
+	List(gtx *gin.Context)
+	Get(gtx *gin.Context)
+	Create(gtx *gin.Context)
+	Update(gtx *gin.Context)
+	AssignRole(gtx *gin.Context)
+	RemoveRole(gtx *gin.Context)
+	SetStatus(gtx *gin.Context)
+	Delete(gtx *gin.Context)
+	Restore(gtx *gin.Context)
+	Impersonate(gtx *gin.Context)
+}
+
+type adminUserController struct {
+	adminUserService service.AdminUserService
+	logger           *logger.Logger
+}
+
+func NewAdminUserController(adminUserService service.AdminUserService, logger *logger.Logger) AdminUserController {
+	logger.Info("NewAdminUserController initialized successfully")
+	return &adminUserController{
+		adminUserService: adminUserService,
+		logger:           logger,
+	}
+}
+
+// actorAndTarget reads the acting admin from the context and the target user
+// from the uniqueId path param.
+func (c *adminUserController) actorAndTarget(appCtx *ctx.AppCtx) (int64, int64, bool) {
+	actorUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return 0, 0, false
+	}
+	uniqueID, err := strconv.ParseInt(appCtx.Param("uniqueId"), 10, 64)
+	if err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails("invalid uniqueId"))
+		return 0, 0, false
+	}
+	return actorUniqueID, uniqueID, true
+}
+
+func (c *adminUserController) List(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	var req dto.AdminUserListReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	list, total, ex := c.adminUserService.List(gtx, req)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccessPageList(list, total, &req.Pagination)
+}
+
+func (c *adminUserController) Get(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	_, uniqueID, ok := c.actorAndTarget(appCtx)
+	if !ok {
+		return
+	}
+	data, ex := c.adminUserService.Get(gtx, uniqueID)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *adminUserController) Create(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	actorUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	var req dto.AdminUserCreateReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, ex := c.adminUserService.Create(gtx, actorUniqueID, req)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *adminUserController) Update(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	actorUniqueID, uniqueID, ok := c.actorAndTarget(appCtx)
+	if !ok {
+		return
+	}
+	var req dto.AdminUserUpdateReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, ex := c.adminUserService.Update(gtx, actorUniqueID, uniqueID, req)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *adminUserController) AssignRole(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	actorUniqueID, uniqueID, ok := c.actorAndTarget(appCtx)
+	if !ok {
+		return
+	}
+	var req dto.AdminUserRoleReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	if ex := c.adminUserService.AssignRole(gtx, actorUniqueID, uniqueID, model.UserRoleEnum(req.Role)); ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
+
+func (c *adminUserController) RemoveRole(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	actorUniqueID, uniqueID, ok := c.actorAndTarget(appCtx)
+	if !ok {
+		return
+	}
+	if ex := c.adminUserService.RemoveRole(gtx, actorUniqueID, uniqueID, model.UserRoleEnum(gtx.Param("role"))); ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
+
+func (c *adminUserController) SetStatus(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	actorUniqueID, uniqueID, ok := c.actorAndTarget(appCtx)
+	if !ok {
+		return
+	}
+	var req dto.AdminUserStatusReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	if ex := c.adminUserService.SetStatus(gtx, actorUniqueID, uniqueID, req); ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
+
+func (c *adminUserController) Delete(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	actorUniqueID, uniqueID, ok := c.actorAndTarget(appCtx)
+	if !ok {
+		return
+	}
+	if ex := c.adminUserService.Delete(gtx, actorUniqueID, uniqueID); ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
+
+func (c *adminUserController) Restore(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	actorUniqueID, uniqueID, ok := c.actorAndTarget(appCtx)
+	if !ok {
+		return
+	}
+	if ex := c.adminUserService.Restore(gtx, actorUniqueID, uniqueID); ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
+
+func (c *adminUserController) Impersonate(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	actorUniqueID, uniqueID, ok := c.actorAndTarget(appCtx)
+	if !ok {
+		return
+	}
+	var req dto.AdminUserImpersonateReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, ex := c.adminUserService.Impersonate(gtx, actorUniqueID, uniqueID, req)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
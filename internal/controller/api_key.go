@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"strconv"
+	"super-web-server/internal/ctx"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/service"
+	"super-web-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyController interface {
+	Create(gtx *gin.Context)
+	List(gtx *gin.Context)
+	Revoke(gtx *gin.Context)
+}
+
+type apiKeyController struct {
+	apiKeyService service.APIKeyService
+	logger        *logger.Logger
+}
+
+func NewAPIKeyController(apiKeyService service.APIKeyService, logger *logger.Logger) APIKeyController {
+	logger.Info("NewAPIKeyController initialized successfully")
+	return &apiKeyController{
+		apiKeyService: apiKeyService,
+		logger:        logger,
+	}
+}
+
+func (c *apiKeyController) Create(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	var req dto.APIKeyCreateReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, ex := c.apiKeyService.Create(gtx, userUniqueID, req)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *apiKeyController) List(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	var req dto.APIKeyListReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	list, total, ex := c.apiKeyService.List(gtx, userUniqueID, req)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccessPageList(list, total, &req.Pagination)
+}
+
+func (c *apiKeyController) Revoke(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	id, err := strconv.ParseUint(gtx.Param("id"), 10, 64)
+	if err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails("invalid id"))
+		return
+	}
+	if ex := c.apiKeyService.Revoke(gtx, userUniqueID, id); ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
@@ -9,22 +9,49 @@ import (
 type Controller interface {
 	Hello() HelloController
 	User() UserController
+	MFA() MFAController
+	OAuth() OAuthController
+	APIKey() APIKeyController
+	Session() SessionController
+	AdminUser() AdminUserController
+	AdminRole() AdminRoleController
+	Profile() ProfileController
+	File() FileController
+	Account() AccountController
 }
 
 type controller struct {
-	helloController HelloController
-	userController  UserController
-	logger          *logger.Logger
-	jwt             *jwt.JWT
+	helloController     HelloController
+	userController      UserController
+	mfaController       MFAController
+	oauthController     OAuthController
+	apiKeyController    APIKeyController
+	sessionController   SessionController
+	adminUserController AdminUserController
+	adminRoleController AdminRoleController
+	profileController   ProfileController
+	fileController      FileController
+	accountController   AccountController
+	logger              *logger.Logger
+	jwt                 *jwt.JWT
 }
 
 func NewController(service service.Service, logger *logger.Logger, jwt *jwt.JWT) Controller {
 	logger.Info("NewController initialized successfully")
 	return &controller{
-		helloController: NewHelloController(logger),
-		userController:  NewUserController(service.User(), logger),
-		logger:          logger,
-		jwt:             jwt,
+		helloController:     NewHelloController(logger),
+		userController:      NewUserController(service.User(), service.Token(), logger),
+		mfaController:       NewMFAController(service.MFA(), logger),
+		oauthController:     NewOAuthController(service.OAuth(), logger),
+		apiKeyController:    NewAPIKeyController(service.APIKey(), logger),
+		sessionController:   NewSessionController(service.Session(), logger),
+		adminUserController: NewAdminUserController(service.AdminUser(), logger),
+		adminRoleController: NewAdminRoleController(service.AdminRole(), logger),
+		profileController:   NewProfileController(service.Profile(), logger),
+		fileController:      NewFileController(service.File(), logger),
+		accountController:   NewAccountController(service.Account(), logger),
+		logger:              logger,
+		jwt:                 jwt,
 	}
 }
 
@@ -35,3 +62,39 @@ func (c *controller) Hello() HelloController {
 func (c *controller) User() UserController {
 	return c.userController
 }
+
+func (c *controller) MFA() MFAController {
+	return c.mfaController
+}
+
+func (c *controller) OAuth() OAuthController {
+	return c.oauthController
+}
+
+func (c *controller) APIKey() APIKeyController {
+	return c.apiKeyController
+}
+
+func (c *controller) Session() SessionController {
+	return c.sessionController
+}
+
+func (c *controller) AdminUser() AdminUserController {
+	return c.adminUserController
+}
+
+func (c *controller) AdminRole() AdminRoleController {
+	return c.adminRoleController
+}
+
+func (c *controller) Profile() ProfileController {
+	return c.profileController
+}
+
+func (c *controller) File() FileController {
+	return c.fileController
+}
+
+func (c *controller) Account() AccountController {
+	return c.accountController
+}
@@ -0,0 +1,233 @@
+package controller
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"super-web-server/internal/ctx"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/service"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/storage"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FileController interface {
+	Upload(gtx *gin.Context)
+	List(gtx *gin.Context)
+	Get(gtx *gin.Context)
+	Download(gtx *gin.Context)
+	SignedURL(gtx *gin.Context)
+	Delete(gtx *gin.Context)
+	ServeSignedFile(gtx *gin.Context)
+	ServeSigned(gtx *gin.Context)
+}
+
+type fileController struct {
+	fileService service.FileService
+	logger      *logger.Logger
+}
+
+func NewFileController(fileService service.FileService, logger *logger.Logger) FileController {
+	logger.Info("NewFileController initialized successfully")
+	return &fileController{
+		fileService: fileService,
+		logger:      logger,
+	}
+}
+
+// fileID reads the file from the uniqueId path param. The user is not passed
+// on, the service authorizes the one the request was authenticated as.
+func (c *fileController) fileID(appCtx *ctx.AppCtx) (int64, bool) {
+	uniqueID, err := strconv.ParseInt(appCtx.Param("uniqueId"), 10, 64)
+	if err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails("invalid uniqueId"))
+		return 0, false
+	}
+	return uniqueID, true
+}
+
+func (c *fileController) Upload(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	ownerUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	fileHeader, err := gtx.FormFile("file")
+	if err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails("file is required"))
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionBadRequest.AppendDetails(err.Error()))
+		return
+	}
+	defer file.Close()
+
+	data, ex := c.fileService.Upload(gtx, ownerUniqueID, fileHeader.Filename, file, fileHeader.Size)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *fileController) List(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	ownerUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	var req dto.FileListReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	list, total, ex := c.fileService.List(gtx, ownerUniqueID, req)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccessPageList(list, total, &req.Pagination)
+}
+
+func (c *fileController) Get(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	uniqueID, ok := c.fileID(appCtx)
+	if !ok {
+		return
+	}
+	data, ex := c.fileService.Get(gtx, uniqueID)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *fileController) Download(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	uniqueID, ok := c.fileID(appCtx)
+	if !ok {
+		return
+	}
+	file, reader, ex := c.fileService.Open(gtx, uniqueID)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	c.serve(gtx, file, reader, "", "")
+}
+
+func (c *fileController) SignedURL(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	uniqueID, ok := c.fileID(appCtx)
+	if !ok {
+		return
+	}
+	var req dto.FileSignedURLReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, ex := c.fileService.SignedURL(gtx, uniqueID, appCtx.GetClientMeta().IP, req)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *fileController) Delete(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	uniqueID, ok := c.fileID(appCtx)
+	if !ok {
+		return
+	}
+	if ex := c.fileService.Delete(gtx, uniqueID); ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
+
+// ServeSignedFile serves a link minted by SignedURL, no token needed.
+func (c *fileController) ServeSignedFile(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	uniqueID, err := strconv.ParseInt(appCtx.Param("uniqueId"), 10, 64)
+	if err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails("invalid uniqueId"))
+		return
+	}
+	file, opts, reader, ex := c.fileService.OpenSignedFile(gtx, uniqueID, gtx.Request.URL.Query(), appCtx.GetClientMeta().IP)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	c.serve(gtx, file, reader, opts.Disposition, opts.Filename)
+}
+
+// ServeSigned serves a signed URL of the local storage, the key is the rest
+// of the path.
+func (c *fileController) ServeSigned(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	key := strings.TrimPrefix(gtx.Param("key"), "/")
+	file, reader, ex := c.fileService.OpenSigned(gtx, key, gtx.Request.URL.Query())
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	c.serve(gtx, file, reader, "", "")
+}
+
+// inlineContentTypes may be rendered by the browser, anything else (HTML,
+// XML, scripts) is always downloaded.
+var inlineContentTypes = []string{"image/", "audio/", "video/", "application/pdf", "text/plain"}
+
+func inlineAllowed(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, allowed := range inlineContentTypes {
+		if strings.HasPrefix(mediaType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// serve answers with the content, supporting range and conditional requests.
+// Uploads are untrusted: they are served as attachment unless inline is asked
+// for and the type is harmless, and the browser never sniffs or runs them.
+func (c *fileController) serve(gtx *gin.Context, file *model.File, reader *storage.Reader, disposition, filename string) {
+	defer reader.Close()
+
+	if disposition != "inline" || !inlineAllowed(file.ContentType) {
+		disposition = "attachment"
+	}
+	if filename == "" {
+		filename = file.Name
+	}
+	if formatted := mime.FormatMediaType(disposition, map[string]string{"filename": filename}); formatted != "" {
+		disposition = formatted
+	}
+	header := gtx.Writer.Header()
+	header.Set("Content-Type", file.ContentType)
+	header.Set("Content-Disposition", disposition)
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Content-Security-Policy", "sandbox")
+	header.Set("Cache-Control", "private")
+	header.Set("ETag", `"`+file.Checksum+`"`)
+
+	var modTime time.Time
+	if file.CreatedAt != nil {
+		modTime = *file.CreatedAt
+	}
+	http.ServeContent(gtx.Writer, gtx.Request, "", modTime, reader)
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"super-web-server/internal/ctx"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/service"
+	"super-web-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAController interface {
+	LoginByMFA(gtx *gin.Context)
+	EnrollTOTP(gtx *gin.Context)
+	ConfirmTOTP(gtx *gin.Context)
+	DisableTOTP(gtx *gin.Context)
+}
+
+type mfaController struct {
+	mfaService service.MFAService
+	logger     *logger.Logger
+}
+
+func NewMFAController(mfaService service.MFAService, logger *logger.Logger) MFAController {
+	logger.Info("NewMFAController initialized successfully")
+	return &mfaController{
+		mfaService: mfaService,
+		logger:     logger,
+	}
+}
+
+func (c *mfaController) LoginByMFA(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	var req dto.UserMFALoginReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, err := c.mfaService.LoginByMFA(gtx, req, appCtx.GetClientMeta())
+	if err != nil {
+		appCtx.ToError(err)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *mfaController) EnrollTOTP(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	data, ex := c.mfaService.EnrollTOTP(gtx, userUniqueID)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *mfaController) ConfirmTOTP(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	var req dto.UserTOTPConfirmReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, ex := c.mfaService.ConfirmTOTP(gtx, userUniqueID, req)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *mfaController) DisableTOTP(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	var req dto.UserTOTPDisableReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	if ex := c.mfaService.DisableTOTP(gtx, userUniqueID, appCtx.GetSessionID(), req); ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
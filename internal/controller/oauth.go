@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"net/http"
+	"super-web-server/internal/ctx"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/service"
+	"super-web-server/pkg/logger"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oauthStateCookie carries the state of a flow, the callback only accepts
+// the state of the browser that started it.
+const oauthStateCookie = "oauth_state"
+
+type OAuthController interface {
+	ListProviders(gtx *gin.Context)
+	Authorize(gtx *gin.Context)
+	Callback(gtx *gin.Context)
+	AuthorizeLink(gtx *gin.Context)
+	Link(gtx *gin.Context)
+}
+
+type oauthController struct {
+	oauthService service.OAuthService
+	logger       *logger.Logger
+}
+
+func NewOAuthController(oauthService service.OAuthService, logger *logger.Logger) OAuthController {
+	logger.Info("NewOAuthController initialized successfully")
+	return &oauthController{
+		oauthService: oauthService,
+		logger:       logger,
+	}
+}
+
+func (c *oauthController) ListProviders(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	appCtx.ToSuccess(c.oauthService.ListProviders(gtx))
+}
+
+func (c *oauthController) Authorize(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	data, ex := c.oauthService.Authorize(gtx, gtx.Param("provider"), 0)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	setOAuthStateCookie(gtx, data)
+	appCtx.ToSuccess(data)
+}
+
+func (c *oauthController) Callback(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	var req dto.UserOAuthCallbackReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, ex := c.oauthService.Callback(gtx, gtx.Param("provider"), takeOAuthStateCookie(gtx), req, appCtx.GetClientMeta())
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *oauthController) AuthorizeLink(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	data, ex := c.oauthService.Authorize(gtx, gtx.Param("provider"), userUniqueID)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	setOAuthStateCookie(gtx, data)
+	appCtx.ToSuccess(data)
+}
+
+func (c *oauthController) Link(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	var req dto.UserOAuthCallbackReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	if ex := c.oauthService.Link(gtx, userUniqueID, gtx.Param("provider"), takeOAuthStateCookie(gtx), req); ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
+
+func setOAuthStateCookie(gtx *gin.Context, data *dto.UserOAuthAuthorizeResDTO) {
+	maxAge := int(time.Until(time.UnixMilli(data.ExpireAt)).Seconds())
+	gtx.SetSameSite(http.SameSiteLaxMode)
+	gtx.SetCookie(oauthStateCookie, data.State, maxAge, "/", "", gtx.Request.TLS != nil, true)
+}
+
+// takeOAuthStateCookie reads the state cookie and clears it, a state is used once.
+func takeOAuthStateCookie(gtx *gin.Context) string {
+	state, _ := gtx.Cookie(oauthStateCookie)
+	gtx.SetSameSite(http.SameSiteLaxMode)
+	gtx.SetCookie(oauthStateCookie, "", -1, "/", "", gtx.Request.TLS != nil, true)
+	return state
+}
@@ -0,0 +1,192 @@
+package controller
+
+import (
+	"super-web-server/internal/ctx"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/service"
+	"super-web-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProfileController interface {
+	Update(gtx *gin.Context)
+	SendMobileCode(gtx *gin.Context)
+	UpdateAvatar(gtx *gin.Context)
+	ChangePassword(gtx *gin.Context)
+	SendReauthCode(gtx *gin.Context)
+	RequestEmailChange(gtx *gin.Context)
+	ConfirmEmailChange(gtx *gin.Context)
+	RevertEmailChange(gtx *gin.Context)
+}
+
+type profileController struct {
+	profileService service.ProfileService
+	logger         *logger.Logger
+}
+
+func NewProfileController(profileService service.ProfileService, logger *logger.Logger) ProfileController {
+	logger.Info("NewProfileController initialized successfully")
+	return &profileController{
+		profileService: profileService,
+		logger:         logger,
+	}
+}
+
+func (c *profileController) Update(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	var req dto.UserProfileUpdateReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, ex := c.profileService.UpdateProfile(gtx, userUniqueID, req)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *profileController) SendMobileCode(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	var req dto.UserProfileMobileCodeReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, ex := c.profileService.SendMobileCode(gtx, userUniqueID, req)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *profileController) UpdateAvatar(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	fileHeader, err := gtx.FormFile("avatar")
+	if err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails("avatar file is required"))
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionBadRequest.AppendDetails(err.Error()))
+		return
+	}
+	defer file.Close()
+
+	data, ex := c.profileService.UpdateAvatar(gtx, userUniqueID, file)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *profileController) ChangePassword(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	var req dto.UserPasswordChangeReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	if ex := c.profileService.ChangePassword(gtx, userUniqueID, appCtx.GetSessionID(), req); ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
+
+func (c *profileController) SendReauthCode(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	data, ex := c.profileService.SendReauthCode(gtx, userUniqueID)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *profileController) RequestEmailChange(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	var req dto.UserEmailChangeReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, ex := c.profileService.RequestEmailChange(gtx, userUniqueID, appCtx.GetSessionID(), req)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *profileController) ConfirmEmailChange(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	var req dto.UserEmailChangeConfirmReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, ex := c.profileService.ConfirmEmailChange(gtx, userUniqueID, req)
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+// RevertEmailChange is opened from the link mailed to the previous address,
+// no token needed.
+func (c *profileController) RevertEmailChange(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	var req dto.UserEmailRevertReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	if ex := c.profileService.RevertEmailChange(gtx, req); ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
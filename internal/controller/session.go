@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"super-web-server/internal/ctx"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/service"
+	"super-web-server/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionController interface {
+	List(gtx *gin.Context)
+	Revoke(gtx *gin.Context)
+}
+
+type sessionController struct {
+	sessionService service.SessionService
+	logger         *logger.Logger
+}
+
+func NewSessionController(sessionService service.SessionService, logger *logger.Logger) SessionController {
+	logger.Info("NewSessionController initialized successfully")
+	return &sessionController{
+		sessionService: sessionService,
+		logger:         logger,
+	}
+}
+
+func (c *sessionController) List(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	data, ex := c.sessionService.List(gtx, userUniqueID, appCtx.GetSessionID())
+	if ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *sessionController) Revoke(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	if ex := c.sessionService.Revoke(gtx, userUniqueID, gtx.Param("id")); ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
@@ -13,18 +13,30 @@ import (
 type UserController interface {
 	LoginByEmail(gtx *gin.Context)
 	Info(gtx *gin.Context)
+	Register(gtx *gin.Context)
+	RegisterVerify(gtx *gin.Context)
+	RegisterResendCode(gtx *gin.Context)
+	RefreshToken(gtx *gin.Context)
+	Logout(gtx *gin.Context)
+	LogoutAll(gtx *gin.Context)
+	ForgotPassword(gtx *gin.Context)
+	ResetPassword(gtx *gin.Context)
+	SendLoginByMobileCode(gtx *gin.Context)
+	LoginByMobile(gtx *gin.Context)
 }
 
 type userController struct {
-	userService service.UserService
-	logger      *logger.Logger
+	userService  service.UserService
+	tokenService service.TokenService
+	logger       *logger.Logger
 }
 
-func NewUserController(userService service.UserService, logger *logger.Logger) UserController {
+func NewUserController(userService service.UserService, tokenService service.TokenService, logger *logger.Logger) UserController {
 	logger.Info("NewUserController initialized successfully")
 	return &userController{
-		userService: userService,
-		logger:      logger,
+		userService:  userService,
+		tokenService: tokenService,
+		logger:       logger,
 	}
 }
 
@@ -35,7 +47,7 @@ func (c *userController) LoginByEmail(gtx *gin.Context) {
 		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
 		return
 	}
-	data, err := c.userService.LoginByEmail(gtx, req)
+	data, err := c.userService.LoginByEmail(gtx, req, appCtx.GetClientMeta())
 	if err != nil {
 		appCtx.ToError(err)
 		return
@@ -50,10 +62,158 @@ func (c *userController) Info(gtx *gin.Context) {
 		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
 		return
 	}
-	user, ex := c.userService.GetUserByUniqueID(gtx, userUniqueID)
+	info, ex := c.userService.GetUserInfo(gtx, userUniqueID)
 	if ex != nil {
 		appCtx.ToError(ex)
 		return
 	}
-	appCtx.ToSuccess(user)
+	appCtx.ToSuccess(info)
+}
+
+func (c *userController) Register(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	var req dto.UserRegisterReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, err := c.userService.Register(gtx, req)
+	if err != nil {
+		appCtx.ToError(err)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *userController) RegisterVerify(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	var req dto.UserRegisterVerifyReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	if err := c.userService.RegisterVerify(gtx, req); err != nil {
+		appCtx.ToError(err)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
+
+func (c *userController) RegisterResendCode(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	var req dto.UserRegisterResendCodeReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, err := c.userService.RegisterResendCode(gtx, req)
+	if err != nil {
+		appCtx.ToError(err)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *userController) RefreshToken(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	var req dto.UserRefreshTokenReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, err := c.tokenService.RefreshToken(gtx, req)
+	if err != nil {
+		appCtx.ToError(err)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *userController) Logout(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	var req dto.UserLogoutReqDTO
+	// the refresh token is optional, so an empty body is fine
+	if gtx.Request.ContentLength != 0 {
+		if err := appCtx.ShouldBind(&req); err != nil {
+			appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+			return
+		}
+	}
+	if err := c.tokenService.Logout(gtx, appCtx.GetTokenID(), appCtx.GetTokenExpireAt(), appCtx.GetSessionID(), req); err != nil {
+		appCtx.ToError(err)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
+
+func (c *userController) LogoutAll(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	userUniqueID, err := appCtx.GetUserUniqueID()
+	if err != nil {
+		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+		return
+	}
+	if ex := c.tokenService.RevokeUserTokens(gtx, userUniqueID); ex != nil {
+		appCtx.ToError(ex)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
+
+func (c *userController) ForgotPassword(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	var req dto.UserPasswordForgotReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	if err := c.userService.ForgotPassword(gtx, req); err != nil {
+		appCtx.ToError(err)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
+
+func (c *userController) ResetPassword(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	var req dto.UserPasswordResetReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	if err := c.userService.ResetPassword(gtx, req); err != nil {
+		appCtx.ToError(err)
+		return
+	}
+	appCtx.ToSuccess(nil)
+}
+
+func (c *userController) SendLoginByMobileCode(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	var req dto.UserLoginByMobileSendCodeReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, err := c.userService.SendLoginByMobileCode(gtx, req)
+	if err != nil {
+		appCtx.ToError(err)
+		return
+	}
+	appCtx.ToSuccess(data)
+}
+
+func (c *userController) LoginByMobile(gtx *gin.Context) {
+	appCtx := ctx.NewAppCtx(gtx)
+	var req dto.UserLoginByMobileReqDTO
+	if err := appCtx.ShouldBind(&req); err != nil {
+		appCtx.ToError(exception.ExceptionInvalidParam.AppendDetails(*err...))
+		return
+	}
+	data, err := c.userService.LoginByMobile(gtx, req, appCtx.GetClientMeta())
+	if err != nil {
+		appCtx.ToError(err)
+		return
+	}
+	appCtx.ToSuccess(data)
 }
@@ -0,0 +1,99 @@
+// Package cron runs periodic jobs in the background. Every instance runs the
+// jobs, but a run first takes a lock in redis that expires with the interval,
+// so a job runs once per interval across all instances.
+package cron
+
+import (
+	"context"
+	"super-web-server/pkg/logger"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type Cron struct {
+	redis  *redis.Client
+	logger *logger.Logger
+	jobs   []Job
+	stop   context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewCron(redis *redis.Client, logger *logger.Logger) *Cron {
+	logger.Info("NewCron initialized successfully")
+	return &Cron{
+		redis:  redis,
+		logger: logger,
+	}
+}
+
+// Add registers a job, jobs added after Start are not run.
+func (c *Cron) Add(name string, interval time.Duration, run func(ctx context.Context) error) {
+	c.jobs = append(c.jobs, Job{Name: name, Interval: interval, Run: run})
+}
+
+// Start runs every job once right away and then every interval until Stop
+// is called.
+func (c *Cron) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.stop = cancel
+
+	for _, job := range c.jobs {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			ticker := time.NewTicker(job.Interval)
+			defer ticker.Stop()
+			for {
+				c.run(ctx, job)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+}
+
+// Stop cancels the running jobs and waits for them to return.
+func (c *Cron) Stop() {
+	if c.stop == nil {
+		return
+	}
+	c.stop()
+	c.wg.Wait()
+}
+
+func (c *Cron) run(ctx context.Context, job Job) {
+	// the lock is left to expire, another instance must not run the job again
+	// before the interval has passed
+	ok, err := c.redis.SetNX(ctx, "cron:lock:"+job.Name, time.Now().UnixMilli(), job.Interval).Result()
+	if err != nil {
+		if ctx.Err() == nil {
+			c.logger.Error("Failed to lock cron job", zap.String("job", job.Name), zap.Error(err))
+		}
+		return
+	}
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, job.Interval)
+	defer cancel()
+
+	start := time.Now()
+	if err := job.Run(ctx); err != nil {
+		c.logger.Error("cron job failed", zap.String("job", job.Name), zap.Duration("duration", time.Since(start)), zap.Error(err))
+		return
+	}
+	c.logger.Debug("cron job done", zap.String("job", job.Name), zap.Duration("duration", time.Since(start)))
+}
@@ -6,6 +6,7 @@ import (
 	"super-web-server/internal/dto"
 	"super-web-server/internal/exception"
 	"super-web-server/internal/validator"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,6 +21,12 @@ const (
 	SUCCESS_MESSAGE       = "success"
 	USER_UNIQUE_ID_KEY    = "user_unique_id"
 	USER_UNIQUE_ROLES_KEY = "user_unique_roles"
+	TOKEN_ID_KEY          = "token_id"
+	TOKEN_EXPIRE_AT_KEY   = "token_expire_at"
+	API_KEY_ID_KEY        = "api_key_id"
+	SESSION_ID_KEY        = "session_id"
+	ACTOR_UNIQUE_ID_KEY   = "actor_unique_id"
+	DEVICE_NAME_HEADER    = "X-Device-Name"
 )
 
 func NewAppCtx(gtx *gin.Context) *AppCtx {
@@ -45,6 +52,62 @@ func (c *AppCtx) SetUserUniqueID(id int64) {
 	c.Set(USER_UNIQUE_ID_KEY, id)
 }
 
+func (c *AppCtx) GetTokenID() string {
+	return c.GetString(TOKEN_ID_KEY)
+}
+
+func (c *AppCtx) SetTokenID(id string) {
+	c.Set(TOKEN_ID_KEY, id)
+}
+
+func (c *AppCtx) GetTokenExpireAt() time.Time {
+	return c.GetTime(TOKEN_EXPIRE_AT_KEY)
+}
+
+func (c *AppCtx) SetTokenExpireAt(expireAt time.Time) {
+	c.Set(TOKEN_EXPIRE_AT_KEY, expireAt)
+}
+
+func (c *AppCtx) GetSessionID() string {
+	return c.GetString(SESSION_ID_KEY)
+}
+
+func (c *AppCtx) SetSessionID(id string) {
+	c.Set(SESSION_ID_KEY, id)
+}
+
+// GetActorUniqueID returns the user acting as the authenticated user with an
+// impersonation token, 0 for everybody else.
+func (c *AppCtx) GetActorUniqueID() int64 {
+	return c.GetInt64(ACTOR_UNIQUE_ID_KEY)
+}
+
+func (c *AppCtx) SetActorUniqueID(id int64) {
+	c.Set(ACTOR_UNIQUE_ID_KEY, id)
+}
+
+func (c *AppCtx) IsImpersonation() bool {
+	return c.GetActorUniqueID() != 0
+}
+
+// GetAPIKeyID returns the id of the API key the request was authenticated
+// with, 0 for requests authenticated with a JWT.
+func (c *AppCtx) GetAPIKeyID() uint64 {
+	return c.GetUint64(API_KEY_ID_KEY)
+}
+
+func (c *AppCtx) SetAPIKeyID(id uint64) {
+	c.Set(API_KEY_ID_KEY, id)
+}
+
+func (c *AppCtx) GetClientMeta() dto.ClientMeta {
+	return dto.ClientMeta{
+		IP:         c.ClientIP(),
+		UserAgent:  truncate(c.Request.UserAgent(), 512),
+		DeviceName: truncate(c.GetHeader(DEVICE_NAME_HEADER), 64),
+	}
+}
+
 func (c *AppCtx) ToError(err *exception.Exception) {
 	c.JSON(err.StatusCode, gin.H{
 		"code":    err.Code,
@@ -76,3 +139,11 @@ func (c *AppCtx) ToSuccessPageList(data any, total int64, pagination *dto.Pagina
 	})
 	c.Abort()
 }
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
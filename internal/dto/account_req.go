@@ -0,0 +1,17 @@
+package dto
+
+// AccountDeleteReqDTO needs the current password. An account that never had
+// one (e.g. created through OAuth) sends the code from /user/reauth/code,
+// unless it signed in recently.
+type AccountDeleteReqDTO struct {
+	Password string `form:"password" binding:"max=128"`
+	Code     string `form:"code" binding:"omitempty,numeric,max=16"`
+}
+
+type AccountRestoreReqDTO struct {
+	Token string `form:"token" binding:"required,max=128"`
+}
+
+type UserDataExportListReqDTO struct {
+	Pagination
+}
@@ -0,0 +1,28 @@
+package dto
+
+import (
+	"super-web-server/internal/model"
+	"time"
+)
+
+type AccountDeleteResDTO struct {
+	PurgeAt int64 `json:"purgeAt"` // 宽限期结束, 个人信息将被匿名化的时间
+}
+
+// UserDataExportResDTO carries a signed download link once the export is
+// ready.
+type UserDataExportResDTO struct {
+	*model.UserDataExport
+	URL string `json:"url,omitempty"`
+}
+
+// UserDataArchiveDTO is the data.json of a data export archive, the content
+// of the files sits next to it.
+type UserDataArchiveDTO struct {
+	ExportedAt time.Time             `json:"exportedAt"`
+	Profile    *model.User           `json:"profile"`
+	Sessions   []*model.UserSession  `json:"sessions"`
+	Identities []*model.UserIdentity `json:"identities"`
+	APIKeys    []*model.APIKey       `json:"apiKeys"`
+	Files      []*model.File         `json:"files"`
+}
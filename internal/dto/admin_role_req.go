@@ -0,0 +1,16 @@
+package dto
+
+type AdminRoleCreateReqDTO struct {
+	Code        string   `form:"code" binding:"required,max=64"` // 如 role:editor, 创建后不能修改
+	Name        string   `form:"name" binding:"required,max=64"`
+	Parents     []string `form:"parents" binding:"omitempty,dive,required,max=64"`     // 继承的角色编码
+	Permissions []string `form:"permissions" binding:"omitempty,dive,required,max=64"` // 权限编码
+}
+
+// AdminRoleUpdateReqDTO only changes the fields that are present, an empty
+// list clears the parents or permissions.
+type AdminRoleUpdateReqDTO struct {
+	Name        *string   `form:"name" binding:"omitempty,min=1,max=64"`
+	Parents     *[]string `form:"parents" binding:"omitempty,dive,required,max=64"`
+	Permissions *[]string `form:"permissions" binding:"omitempty,dive,required,max=64"`
+}
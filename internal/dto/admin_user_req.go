@@ -0,0 +1,42 @@
+package dto
+
+type AdminUserListReqDTO struct {
+	Pagination
+	Email    string `form:"email" binding:"max=128"`   // 模糊匹配
+	Mobile   string `form:"mobile" binding:"max=20"`   // 模糊匹配
+	Nickname string `form:"nickname" binding:"max=32"` // 模糊匹配
+	Role     string `form:"role" binding:"max=64"`     // 角色编码, 如 role:admin
+	Deleted  bool   `form:"deleted"`                   // 只查询已删除的用户
+	Sort     string `form:"sort" binding:"omitempty,oneof=id createdAt updatedAt email nickname"`
+	Order    string `form:"order" binding:"omitempty,oneof=asc desc"`
+}
+
+type AdminUserCreateReqDTO struct {
+	Email         string   `form:"email" binding:"required,email,max=128"`
+	Password      string   `form:"password" binding:"required,password"`
+	Nickname      string   `form:"nickname" binding:"omitempty,max=32"`
+	Mobile        string   `form:"mobile" binding:"omitempty,numeric,min=6,max=20"`
+	Roles         []string `form:"roles" binding:"omitempty,dive,required,max=64"` // 为空时为 role:user
+	EmailVerified bool     `form:"emailVerified"`                                  // 是否跳过邮箱验证
+}
+
+// AdminUserUpdateReqDTO only changes the fields that are present.
+type AdminUserUpdateReqDTO struct {
+	Nickname  *string `form:"nickname" binding:"omitempty,max=32"`
+	Mobile    *string `form:"mobile" binding:"omitempty,numeric,min=6,max=20"`
+	AvatarURL *string `form:"avatarUrl" binding:"omitempty,url,max=512"`
+}
+
+type AdminUserRoleReqDTO struct {
+	Role string `form:"role" binding:"required,max=64"`
+}
+
+type AdminUserStatusReqDTO struct {
+	Status      string `form:"status" binding:"required,oneof=active disabled banned"`
+	Reason      string `form:"reason" binding:"max=255"`              // 展示给用户的原因
+	BannedUntil int64  `form:"bannedUntil" binding:"omitempty,min=0"` // 解封时间 (毫秒时间戳), 为空表示永久封禁
+}
+
+type AdminUserImpersonateReqDTO struct {
+	Reason string `form:"reason" binding:"required,max=255"` // 记录在审计日志中, 如工单号
+}
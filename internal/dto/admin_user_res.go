@@ -0,0 +1,20 @@
+package dto
+
+import (
+	"super-web-server/internal/model"
+	"time"
+)
+
+// AdminUserResDTO is a user as admins see it, including the soft delete time.
+type AdminUserResDTO struct {
+	*model.User
+	DeletedAt *time.Time `json:"deletedAt"`
+}
+
+// AdminUserImpersonateResDTO is an access token without refresh token, it
+// cannot outlive ExpireAt.
+type AdminUserImpersonateResDTO struct {
+	Token        string `json:"token"`
+	ExpireAt     int64  `json:"expireAt"`
+	UserUniqueID int64  `json:"userUniqueId"`
+}
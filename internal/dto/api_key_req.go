@@ -0,0 +1,13 @@
+package dto
+
+type APIKeyCreateReqDTO struct {
+	Name          string   `form:"name" binding:"required,max=64"`
+	OwnerUniqueID int64    `form:"ownerUniqueId" binding:"required"` // 密钥代表的用户
+	Scopes        []string `form:"scopes" binding:"required,min=1,dive,required,max=64"`
+	ExpiresAt     int64    `form:"expiresAt" binding:"omitempty,min=0"` // 过期时间 (毫秒时间戳), 为空表示永不过期
+}
+
+type APIKeyListReqDTO struct {
+	Pagination
+	OwnerUniqueID int64 `form:"ownerUniqueId"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+import "super-web-server/internal/model"
+
+// APIKeyCreateResDTO carries the plain key, it is only ever returned here.
+type APIKeyCreateResDTO struct {
+	Key    string        `json:"key"`
+	APIKey *model.APIKey `json:"apiKey"`
+}
@@ -39,3 +39,10 @@ func (p Pagination) Value() *Pagination {
 		PageSize: p._PageSize(),
 	}
 }
+
+// ClientMeta 请求方信息
+type ClientMeta struct {
+	IP         string
+	UserAgent  string
+	DeviceName string // 客户端通过 X-Device-Name 请求头上报的设备名
+}
@@ -0,0 +1,12 @@
+package dto
+
+type FileListReqDTO struct {
+	Pagination
+}
+
+type FileSignedURLReqDTO struct {
+	Expire      int64  `form:"expire" binding:"omitempty,min=1"`                        // 有效期 (秒), 默认 signedUrl.expire
+	BindIP      bool   `form:"bindIp"`                                                  // 只允许当前 IP 使用
+	Disposition string `form:"disposition" binding:"omitempty,oneof=inline attachment"` // 覆盖 Content-Disposition
+	Filename    string `form:"filename" binding:"omitempty,max=255"`                    // 覆盖下载文件名
+}
@@ -0,0 +1,6 @@
+package dto
+
+type FileURLResDTO struct {
+	URL      string `json:"url"`
+	ExpireAt int64  `json:"expireAt"`
+}
@@ -4,3 +4,103 @@ type UserLoginByEmailReqDTO struct {
 	Email    string `form:"email" binding:"required,email"`
 	Password string `form:"password" binding:"required"`
 }
+
+type UserRegisterReqDTO struct {
+	Email    string `form:"email" binding:"required,email,max=128"`
+	Password string `form:"password" binding:"required,password"`
+	Nickname string `form:"nickname" binding:"omitempty,max=32"`
+}
+
+type UserRegisterVerifyReqDTO struct {
+	Email string `form:"email" binding:"required,email"`
+	Code  string `form:"code" binding:"required,numeric"`
+}
+
+type UserRegisterResendCodeReqDTO struct {
+	Email string `form:"email" binding:"required,email"`
+}
+
+type UserRefreshTokenReqDTO struct {
+	RefreshToken string `form:"refreshToken" binding:"required"`
+}
+
+type UserLogoutReqDTO struct {
+	RefreshToken string `form:"refreshToken"`
+}
+
+type UserPasswordForgotReqDTO struct {
+	Email string `form:"email" binding:"required,email"`
+}
+
+type UserPasswordResetReqDTO struct {
+	Token    string `form:"token" binding:"required"`
+	Password string `form:"password" binding:"required,password"`
+}
+
+type UserLoginByMobileSendCodeReqDTO struct {
+	Mobile string `form:"mobile" binding:"required,numeric,min=6,max=20"`
+}
+
+type UserLoginByMobileReqDTO struct {
+	Mobile string `form:"mobile" binding:"required,numeric,min=6,max=20"`
+	Code   string `form:"code" binding:"required,numeric"`
+}
+
+type UserMFALoginReqDTO struct {
+	MFAToken string `form:"mfaToken" binding:"required"`
+	Code     string `form:"code" binding:"required,max=32"` // TOTP 验证码或恢复码
+}
+
+type UserTOTPConfirmReqDTO struct {
+	Code string `form:"code" binding:"required,len=6,numeric"`
+}
+
+// UserTOTPDisableReqDTO needs the current password. An account that never had
+// one (e.g. created through OAuth) sends the code from /user/reauth/code as
+// reauthCode, unless it signed in recently.
+type UserTOTPDisableReqDTO struct {
+	Password   string `form:"password" binding:"max=128"`
+	Code       string `form:"code" binding:"required,max=32"`                // TOTP 验证码或恢复码
+	ReauthCode string `form:"reauthCode" binding:"omitempty,numeric,max=16"` // 无密码账号登录较久后需要 /user/reauth/code 发送的验证码
+}
+
+type UserOAuthCallbackReqDTO struct {
+	Code  string `form:"code" binding:"required,max=2048"`
+	State string `form:"state" binding:"required,max=128"`
+}
+
+// UserProfileUpdateReqDTO only changes the fields that are present. A new
+// mobile needs the code sent by /user/profile/mobile/code.
+type UserProfileUpdateReqDTO struct {
+	Nickname   *string `form:"nickname" binding:"omitempty,max=32"`
+	Mobile     *string `form:"mobile" binding:"omitempty,numeric,min=6,max=20"`
+	MobileCode string  `form:"mobileCode" binding:"omitempty,numeric"`
+}
+
+type UserProfileMobileCodeReqDTO struct {
+	Mobile string `form:"mobile" binding:"required,numeric,min=6,max=20"`
+}
+
+type UserPasswordChangeReqDTO struct {
+	CurrentPassword string `form:"currentPassword" binding:"max=128"`       // 未设置过密码的账号 (第三方登录) 可以为空
+	Code            string `form:"code" binding:"omitempty,numeric,max=16"` // 无密码账号登录较久后需要 /user/reauth/code 发送的验证码
+	NewPassword     string `form:"newPassword" binding:"required,password"`
+}
+
+// UserEmailChangeReqDTO needs the current password. An account that never had
+// one (e.g. created through OAuth) sends the code from /user/reauth/code,
+// unless it signed in recently.
+type UserEmailChangeReqDTO struct {
+	NewEmail string `form:"newEmail" binding:"required,email,max=128"`
+	Password string `form:"password" binding:"max=128"`
+	Code     string `form:"code" binding:"omitempty,numeric,max=16"`
+}
+
+type UserEmailChangeConfirmReqDTO struct {
+	NewEmail string `form:"newEmail" binding:"required,email,max=128"`
+	Code     string `form:"code" binding:"required,numeric"`
+}
+
+type UserEmailRevertReqDTO struct {
+	Token string `form:"token" binding:"required,max=128"`
+}
@@ -1,7 +1,69 @@
 package dto
 
-type UserLoginByEmailResDTO struct {
-	Token     string `json:"token"`
-	RefreshAt int64  `json:"refreshAt"`
-	ExpireAt  int64  `json:"expireAt"`
+import "super-web-server/internal/model"
+
+type UserTokenResDTO struct {
+	Token           string `json:"token"`
+	RefreshAt       int64  `json:"refreshAt"`
+	ExpireAt        int64  `json:"expireAt"`
+	RefreshToken    string `json:"refreshToken"`
+	RefreshExpireAt int64  `json:"refreshExpireAt"`
+}
+
+// UserLoginResDTO is either a token pair, or a MFA challenge when the user has
+// two-factor authentication enabled.
+type UserLoginResDTO struct {
+	MFARequired bool   `json:"mfaRequired"`
+	MFAToken    string `json:"mfaToken,omitempty"`
+	MFAExpireAt int64  `json:"mfaExpireAt,omitempty"`
+	*UserTokenResDTO
+}
+
+type UserRegisterResDTO struct {
+	UniqueID        int64  `json:"uniqueId"`
+	Email           string `json:"email"`
+	CodeExpireAt    int64  `json:"codeExpireAt"`
+	CodeResendAfter int64  `json:"codeResendAfter"`
+}
+
+type UserVerifyCodeResDTO struct {
+	CodeExpireAt    int64 `json:"codeExpireAt"`
+	CodeResendAfter int64 `json:"codeResendAfter"`
+}
+
+type UserTOTPEnrollResDTO struct {
+	Secret   string `json:"secret"`
+	URL      string `json:"url"`
+	ExpireAt int64  `json:"expireAt"`
+}
+
+type UserTOTPConfirmResDTO struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type UserOAuthProviderResDTO struct {
+	Name string `json:"name"`
+}
+
+type UserOAuthAuthorizeResDTO struct {
+	URL      string `json:"url"`
+	State    string `json:"state"`
+	ExpireAt int64  `json:"expireAt"`
+}
+
+type UserSessionResDTO struct {
+	SessionID  string `json:"sessionId"`
+	DeviceName string `json:"deviceName"`
+	UserAgent  string `json:"userAgent"`
+	IP         string `json:"ip"`
+	CreatedAt  int64  `json:"createdAt"`
+	LastSeenAt int64  `json:"lastSeenAt"`
+	Current    bool   `json:"current"` // 是否为当前请求所用的会话
+}
+
+// UserInfoResDTO is the current user with the permissions they hold through
+// their roles.
+type UserInfoResDTO struct {
+	*model.User
+	Permissions []model.PermissionEnum `json:"permissions"`
 }
@@ -0,0 +1,10 @@
+package exception
+
+import "net/http"
+
+var (
+	ExceptionFileNotFound     = New(http.StatusNotFound, 3000, "File not found")
+	ExceptionStorageError     = New(http.StatusInternalServerError, 3001, "Storage error")
+	ExceptionSignedURLInvalid = New(http.StatusForbidden, 3002, "Signed url invalid")
+	ExceptionSignedURLExpired = New(http.StatusForbidden, 3003, "Signed url expired")
+)
@@ -20,4 +20,7 @@ var (
 	ExceptionServiceError        = New(http.StatusServiceUnavailable, 1014, "Service error")
 	ExceptionServiceTimeout      = New(http.StatusServiceUnavailable, 1015, "Service timeout")
 	ExceptionDatabaseError       = New(http.StatusInternalServerError, 1016, "Database error")
+	ExceptionRefreshTokenInvalid = New(http.StatusUnauthorized, 1017, "Refresh token invalid or expired")
+	ExceptionRefreshTokenReused  = New(http.StatusUnauthorized, 1018, "Refresh token reused")
+	ExceptionTokenRevoked        = New(http.StatusUnauthorized, 1019, "Token revoked")
 )
@@ -6,4 +6,47 @@ var (
 	ExceptionUserNotFound           = New(http.StatusNotFound, 2000, "User not found")
 	ExceptionUserEmailAlreadyExists = New(http.StatusBadRequest, 2001, "User email already exists")
 	ExceptionUserPasswordIncorrect  = New(http.StatusBadRequest, 2002, "User password incorrect")
+	ExceptionUserEmailNotVerified   = New(http.StatusForbidden, 2003, "User email not verified")
+	ExceptionUserEmailVerified      = New(http.StatusBadRequest, 2004, "User email already verified")
+	ExceptionVerifyCodeInvalid      = New(http.StatusBadRequest, 2005, "Verify code invalid or expired")
+	ExceptionVerifyCodeAttempts     = New(http.StatusTooManyRequests, 2006, "Verify code attempts exceeded")
+	ExceptionVerifyCodeTooFrequent  = New(http.StatusTooManyRequests, 2007, "Verify code requested too frequently")
+	ExceptionPasswordResetInvalid   = New(http.StatusBadRequest, 2008, "Password reset token invalid or expired")
+	ExceptionUserInvalidCredentials = New(http.StatusBadRequest, 2009, "Invalid email or password")
+	ExceptionMFACodeInvalid         = New(http.StatusBadRequest, 2010, "MFA code invalid")
+	ExceptionMFAAlreadyEnabled      = New(http.StatusBadRequest, 2011, "MFA already enabled")
+	ExceptionMFANotEnabled          = New(http.StatusBadRequest, 2012, "MFA not enabled")
+	ExceptionMFAChallengeInvalid    = New(http.StatusUnauthorized, 2013, "MFA challenge invalid or expired")
+	ExceptionOAuthProviderNotFound  = New(http.StatusNotFound, 2014, "OAuth provider not found")
+	ExceptionOAuthStateInvalid      = New(http.StatusBadRequest, 2015, "OAuth state invalid or expired")
+	ExceptionOAuthLoginFailed       = New(http.StatusUnauthorized, 2016, "OAuth login failed")
+	ExceptionOAuthEmailNotVerified  = New(http.StatusForbidden, 2017, "OAuth account has no verified email")
+	ExceptionAPIKeyInvalid          = New(http.StatusUnauthorized, 2018, "API key invalid, expired or revoked")
+	ExceptionAPIKeyScopeDenied      = New(http.StatusForbidden, 2019, "API key scope not granted")
+	ExceptionAPIKeyNotFound         = New(http.StatusNotFound, 2020, "API key not found")
+	ExceptionSessionNotFound        = New(http.StatusNotFound, 2021, "Session not found")
+	ExceptionPasswordReused         = New(http.StatusBadRequest, 2022, "Password was used recently")
+	ExceptionUserDisabled           = New(http.StatusForbidden, 2023, "User disabled")
+	ExceptionUserMobileExists       = New(http.StatusBadRequest, 2024, "User mobile already exists")
+	ExceptionUserRoleNotFound       = New(http.StatusNotFound, 2025, "User role not found")
+	ExceptionUserBanned             = New(http.StatusForbidden, 2026, "User banned")
+	ExceptionImageInvalid           = New(http.StatusBadRequest, 2027, "Image must be a JPEG, PNG or GIF")
+	ExceptionFileTooLarge           = New(http.StatusRequestEntityTooLarge, 2028, "File too large")
+	ExceptionEmailChangeInvalid     = New(http.StatusBadRequest, 2029, "Email change request invalid or expired")
+	ExceptionEmailRevertInvalid     = New(http.StatusBadRequest, 2030, "Email revert link invalid or expired")
+	ExceptionDataExportNotFound     = New(http.StatusNotFound, 2031, "Data export not found")
+	ExceptionDataExportInProgress   = New(http.StatusConflict, 2032, "Data export already in progress")
+	ExceptionDataExportNotReady     = New(http.StatusConflict, 2033, "Data export not ready")
+	ExceptionAccountRestoreInvalid  = New(http.StatusBadRequest, 2034, "Account restore link invalid or expired")
+	ExceptionImpersonationDenied    = New(http.StatusForbidden, 2035, "Not allowed while impersonating a user")
+	ExceptionUserRoleExists         = New(http.StatusBadRequest, 2036, "User role already exists")
+	ExceptionUserRoleInUse          = New(http.StatusConflict, 2037, "User role still assigned or inherited")
+	ExceptionUserRoleSystem         = New(http.StatusForbidden, 2038, "Built-in user role cannot be deleted or lose permissions")
+	ExceptionUserRoleCycle          = New(http.StatusBadRequest, 2039, "User role cannot inherit from itself")
+	ExceptionPermissionNotFound     = New(http.StatusNotFound, 2040, "Permission not found")
+	ExceptionMFAEnrollExpired       = New(http.StatusBadRequest, 2041, "MFA enrollment expired")
+	ExceptionOAuthLinkRequired      = New(http.StatusConflict, 2042, "An account with this email exists, sign in and link the provider from settings")
+	ExceptionOAuthIdentityLinked    = New(http.StatusConflict, 2043, "OAuth account already linked to a user")
+	ExceptionReauthRequired         = New(http.StatusForbidden, 2044, "Sign in again or confirm with the code mailed to you")
+	ExceptionDataExportTooFrequent  = New(http.StatusTooManyRequests, 2045, "Data export requested too frequently")
 )
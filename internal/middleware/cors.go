@@ -21,7 +21,6 @@ func DefaultCORSConfig() CORSConfig {
 
 	// 需要暴露给客户端的响应headers（主要用于ExposeHeaders）
 	exposedHeaders := []string{
-		"New-Token",
 		"token",
 		"x-token",
 		"x-request-id",
@@ -29,6 +28,10 @@ func DefaultCORSConfig() CORSConfig {
 		"origin",
 		"Cache-Control",
 		"X-Requested-With",
+		"Content-Range",
+		"Content-Disposition",
+		"Accept-Ranges",
+		"ETag",
 	}
 
 	// 基础的HTTP协议headers
@@ -38,6 +41,9 @@ func DefaultCORSConfig() CORSConfig {
 		"Accept-Encoding",
 		"X-CSRF-Token",
 		"Authorization",
+		"X-Device-Name",
+		"Range",
+		"If-Range",
 	}
 
 	// 合并 headers
@@ -45,7 +51,7 @@ func DefaultCORSConfig() CORSConfig {
 
 	return CORSConfig{
 		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowMethods:     []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     allowHeaders,
 		ExposeHeaders:    exposedHeaders,
 		AllowCredentials: false,
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"super-web-server/internal/ctx"
+	"super-web-server/internal/exception"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DenyImpersonation guards the endpoints an impersonation token must not
+// reach: changing credentials, issuing keys or deleting data acts for the
+// user rather than showing what they see.
+func DenyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		appCtx := ctx.NewAppCtx(c)
+		if appCtx.IsImpersonation() {
+			appCtx.ToError(exception.ExceptionImpersonationDenied)
+			return
+		}
+		c.Next()
+	}
+}
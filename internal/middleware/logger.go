@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"fmt"
+	"super-web-server/internal/ctx"
 	"super-web-server/pkg/logger"
 	"time"
 
@@ -28,6 +29,14 @@ func Logger() gin.HandlerFunc {
 			zap.String("errors", c.Errors.ByType(gin.ErrorTypePrivate).String()),
 			zap.String("latency", fmt.Sprintf("%.2fms", latencyMs)),
 		}
+		// set by the auth middlewares, an impersonated request names both the
+		// user and the admin acting as them
+		if userUniqueID := c.GetInt64(ctx.USER_UNIQUE_ID_KEY); userUniqueID != 0 {
+			fields = append(fields, zap.Int64("user_unique_id", userUniqueID))
+		}
+		if actorUniqueID := c.GetInt64(ctx.ACTOR_UNIQUE_ID_KEY); actorUniqueID != 0 {
+			fields = append(fields, zap.Int64("actor_unique_id", actorUniqueID), zap.Bool("impersonation", true))
+		}
 
 		status := c.Writer.Status()
 		switch {
@@ -80,3 +80,30 @@ func (r *RoleCheck) RoleCheckAny(requiredRoles ...model.UserRoleEnum) gin.Handle
 		appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(fmt.Sprintf("user role %v not in any of %v", userRoleCodes, requiredRoles)))
 	}
 }
+
+// RequirePermission passes users holding all of requiredPermissions through
+// their roles.
+func (r *RoleCheck) RequirePermission(requiredPermissions ...model.PermissionEnum) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		appCtx := ctx.NewAppCtx(c)
+		userUniqueID, err := appCtx.GetUserUniqueID()
+		if err != nil {
+			appCtx.ToError(exception.ExceptionUnauthorized.AppendDetails(err.Error()))
+			return
+		}
+		permissions, ex := r.service.User().GetUserCachedPermissionsByUniqueID(c, userUniqueID)
+		if ex != nil {
+			appCtx.ToError(ex)
+			return
+		}
+
+		for _, requiredPermission := range requiredPermissions {
+			if !slices.Contains(permissions, requiredPermission) {
+				appCtx.ToError(exception.ExceptionForbidden.AppendDetails(fmt.Sprintf("missing permission %s", requiredPermission)))
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"strconv"
+	appvalidator "super-web-server/internal/validator"
+	"super-web-server/pkg/password"
 	"sync"
 
 	"github.com/gin-gonic/gin"
@@ -32,15 +35,68 @@ func initTranslator() {
 func registerZhTrans(v *validator.Validate) {
 	if trans, _ := uni.GetTranslator("zh"); trans != nil {
 		_ = zh_trans.RegisterDefaultTranslations(v, trans)
+		registerPasswordTrans(v, trans, map[password.Rule]string{
+			password.RuleMinLength: "{0}长度不能少于{1}个字符",
+			password.RuleMaxLength: "{0}长度不能超过{1}个字符",
+			password.RuleLower:     "{0}必须包含小写字母",
+			password.RuleUpper:     "{0}必须包含大写字母",
+			password.RuleDigit:     "{0}必须包含数字",
+			password.RuleSymbol:    "{0}必须包含特殊字符",
+			password.RuleClasses:   "{0}必须包含大写字母、小写字母、数字、特殊字符中的至少{1}种",
+			password.RuleBreached:  "{0}过于常见或已在数据泄露中出现, 请更换",
+		})
 	}
 }
 
 func registerEnTrans(v *validator.Validate) {
 	if trans, _ := uni.GetTranslator("en"); trans != nil {
 		_ = en_trans.RegisterDefaultTranslations(v, trans)
+		registerPasswordTrans(v, trans, map[password.Rule]string{
+			password.RuleMinLength: "{0} must be at least {1} characters long",
+			password.RuleMaxLength: "{0} must be at most {1} characters long",
+			password.RuleLower:     "{0} must contain a lowercase letter",
+			password.RuleUpper:     "{0} must contain an uppercase letter",
+			password.RuleDigit:     "{0} must contain a digit",
+			password.RuleSymbol:    "{0} must contain a special character",
+			password.RuleClasses:   "{0} must mix at least {1} of uppercase letters, lowercase letters, digits and special characters",
+			password.RuleBreached:  "{0} is too common or has appeared in a data breach, choose another one",
+		})
 	}
 }
 
+// registerPasswordTrans translates the "password" tag. The message depends on
+// the rule the value broke, so the policy is asked again.
+func registerPasswordTrans(v *validator.Validate, trans ut.Translator, messages map[password.Rule]string) {
+	_ = v.RegisterTranslation(appvalidator.PasswordTag, trans, func(ut ut.Translator) error {
+		for rule, message := range messages {
+			if err := ut.Add(appvalidator.PasswordTag+"."+string(rule), message, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		policy := appvalidator.PasswordPolicy()
+		value, _ := fe.Value().(string)
+		rule, _ := policy.Check(value)
+
+		var param string
+		switch rule {
+		case password.RuleMinLength:
+			param = strconv.Itoa(policy.MinLength)
+		case password.RuleMaxLength:
+			param = strconv.Itoa(policy.MaxLength)
+		case password.RuleClasses:
+			param = strconv.Itoa(policy.MinClasses)
+		}
+
+		message, err := ut.T(appvalidator.PasswordTag+"."+string(rule), fe.Field(), param)
+		if err != nil {
+			return fe.Error()
+		}
+		return message
+	})
+}
+
 func Translations() gin.HandlerFunc {
 	initTranslator()
 	return func(c *gin.Context) {
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// APIKey authenticates a machine client as its owner. Only the SHA-256 of the
+// key is stored, the prefix is kept to tell keys apart in listings.
+type APIKey struct {
+	BaseModel
+	Name              string     `gorm:"size:64;not null" json:"name"`
+	Prefix            string     `gorm:"size:16;index;not null" json:"prefix"`
+	KeyHash           string     `gorm:"size:64;uniqueIndex:uk_api_keys_key_hash;not null" json:"-"`
+	OwnerUniqueID     int64      `gorm:"index;not null" json:"ownerUniqueId"`
+	CreatedByUniqueID int64      `gorm:"not null" json:"createdByUniqueId"`
+	Scopes            []string   `gorm:"serializer:json" json:"scopes"`
+	ExpiresAt         *time.Time `json:"expiresAt"`
+	LastUsedAt        *time.Time `json:"lastUsedAt"`
+	RevokedAt         *time.Time `json:"revokedAt"`
+}
+
+func (k *APIKey) TableName() string {
+	return "api_keys"
+}
+
+func (k *APIKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+func (k *APIKey) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(k.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
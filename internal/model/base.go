@@ -6,6 +6,15 @@ import (
 	"gorm.io/gorm"
 )
 
+// NullableString is nil for an empty s. Optional columns with a unique index
+// store NULL for no value, so the users without one do not collide.
+func NullableString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
 type BaseModel struct {
 	ID        uint64         `gorm:"primaryKey" json:"id"`
 	CreatedAt *time.Time     `json:"createdAt"`
@@ -0,0 +1,28 @@
+package model
+
+import "super-web-server/pkg/policy"
+
+// PolicyResourceFile is the resource type of files in the policy file.
+const PolicyResourceFile = "file"
+
+// File is the metadata of an uploaded file, the content lives in the storage
+// backend under StorageKey.
+type File struct {
+	BaseModel
+	UniqueID      int64  `gorm:"uniqueIndex:uk_files_unique_id;not null" json:"uniqueId"`
+	OwnerUniqueID int64  `gorm:"index;not null" json:"ownerUniqueId"`
+	Name          string `gorm:"size:255;not null" json:"name"`
+	StorageKey    string `gorm:"size:255;uniqueIndex:uk_files_storage_key;not null" json:"-"`
+	Size          int64  `gorm:"not null" json:"size"`
+	ContentType   string `gorm:"size:128;not null" json:"contentType"`
+	Checksum      string `gorm:"size:64;not null" json:"checksum"` // SHA-256, hex
+}
+
+func (f *File) TableName() string {
+	return "files"
+}
+
+// PolicyResource is what the policy engine sees of the file.
+func (f *File) PolicyResource() policy.Resource {
+	return policy.Resource{Type: PolicyResourceFile, OwnerUniqueID: f.OwnerUniqueID}
+}
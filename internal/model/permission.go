@@ -0,0 +1,110 @@
+package model
+
+// Permission is a capability a route requires. Users hold the permissions of
+// their roles.
+type Permission struct {
+	BaseModel
+	Code PermissionEnum `gorm:"size:64;not null;unique" json:"code"`
+	Name string         `gorm:"not null" json:"name"`
+}
+
+func (p *Permission) TableName() string {
+	return "permissions"
+}
+
+type PermissionEnum string
+
+const (
+	PermissionUserRead             PermissionEnum = "user:read"
+	PermissionUserWrite            PermissionEnum = "user:write"
+	PermissionFileRead             PermissionEnum = "file:read"
+	PermissionFileWrite            PermissionEnum = "file:write"
+	PermissionAdminUserRead        PermissionEnum = "admin:user:read"
+	PermissionAdminUserWrite       PermissionEnum = "admin:user:write"
+	PermissionAdminUserImpersonate PermissionEnum = "admin:user:impersonate"
+	PermissionAdminAPIKeyRead      PermissionEnum = "admin:api_key:read"
+	PermissionAdminAPIKeyWrite     PermissionEnum = "admin:api_key:write"
+	PermissionAdminRoleRead        PermissionEnum = "admin:role:read"
+	PermissionAdminRoleWrite       PermissionEnum = "admin:role:write"
+)
+
+var PermissionSet = []Permission{
+	{
+		Code: PermissionUserRead,
+		Name: "查看自己的账号",
+	},
+	{
+		Code: PermissionUserWrite,
+		Name: "修改自己的账号",
+	},
+	{
+		Code: PermissionFileRead,
+		Name: "查看文件",
+	},
+	{
+		Code: PermissionFileWrite,
+		Name: "上传和删除文件",
+	},
+	{
+		Code: PermissionAdminUserRead,
+		Name: "查看用户",
+	},
+	{
+		Code: PermissionAdminUserWrite,
+		Name: "管理用户",
+	},
+	{
+		Code: PermissionAdminUserImpersonate,
+		Name: "模拟登录用户",
+	},
+	{
+		Code: PermissionAdminAPIKeyRead,
+		Name: "查看 API 密钥",
+	},
+	{
+		Code: PermissionAdminAPIKeyWrite,
+		Name: "管理 API 密钥",
+	},
+	{
+		Code: PermissionAdminRoleRead,
+		Name: "查看角色",
+	},
+	{
+		Code: PermissionAdminRoleWrite,
+		Name: "管理角色",
+	},
+}
+
+// UserRolePermissionSet is the permission matrix a fresh install starts with.
+var UserRolePermissionSet = map[UserRoleEnum][]PermissionEnum{
+	UserRoleCodeSuperAdmin: {
+		PermissionUserRead,
+		PermissionUserWrite,
+		PermissionFileRead,
+		PermissionFileWrite,
+		PermissionAdminUserRead,
+		PermissionAdminUserWrite,
+		PermissionAdminUserImpersonate,
+		PermissionAdminAPIKeyRead,
+		PermissionAdminAPIKeyWrite,
+		PermissionAdminRoleRead,
+		PermissionAdminRoleWrite,
+	},
+	UserRoleCodeAdmin: {
+		PermissionUserRead,
+		PermissionUserWrite,
+		PermissionFileRead,
+		PermissionFileWrite,
+		PermissionAdminUserRead,
+		PermissionAdminUserWrite,
+		PermissionAdminAPIKeyRead,
+		PermissionAdminAPIKeyWrite,
+		PermissionAdminRoleRead,
+	},
+	UserRoleCodeUser: {
+		PermissionUserRead,
+		PermissionUserWrite,
+		PermissionFileRead,
+		PermissionFileWrite,
+	},
+}
@@ -1,17 +1,72 @@
 package model
 
+import "time"
+
+type UserStatus string
+
+const (
+	UserStatusActive   UserStatus = "active"
+	UserStatusPending  UserStatus = "pending" // 邮箱未验证
+	UserStatusDisabled UserStatus = "disabled"
+	UserStatusBanned   UserStatus = "banned"
+)
+
 type User struct {
 	BaseModel
-	UniqueID  int64       `gorm:"index" json:"uniqueId"`
-	Email     string      `gorm:"index" json:"email"`
-	Mobile    string      `gorm:"index" json:"mobile"`
-	Password  string      `json:"-"`
-	Salt      string      `json:"-"`
-	Nickname  string      `gorm:"index" json:"nickname"`
-	AvatarURL string      `json:"avatarUrl"`
-	Roles     []*UserRole `gorm:"many2many:user_role_ref;" json:"roles"`
+	UniqueID            int64       `gorm:"index" json:"uniqueId"`
+	Email               string      `gorm:"uniqueIndex:uk_users_email" json:"email"`
+	Mobile              *string     `gorm:"size:20;uniqueIndex:uk_users_mobile" json:"mobile"` // 为空时存 NULL, 唯一索引不冲突
+	Password            string      `json:"-"`
+	Nickname            string      `gorm:"index" json:"nickname"`
+	AvatarURL           string      `json:"avatarUrl"`
+	EmailVerifiedAt     *time.Time  `json:"emailVerifiedAt"`
+	TOTPSecret          string      `json:"-"`
+	TOTPEnabledAt       *time.Time  `json:"totpEnabledAt"`
+	Status              UserStatus  `gorm:"size:16;not null;default:active;index" json:"status"`
+	StatusReason        string      `gorm:"size:255" json:"statusReason"`
+	BannedUntil         *time.Time  `json:"bannedUntil"`                      // 为空表示永久封禁
+	DeletionRequestedAt *time.Time  `gorm:"index" json:"deletionRequestedAt"` // 用户申请注销的时间, 宽限期过后匿名化
+	AnonymizedAt        *time.Time  `json:"anonymizedAt"`
+	Roles               []*UserRole `gorm:"many2many:user_role_ref;" json:"roles"`
 }
 
 func (u *User) TableName() string {
 	return "users"
 }
+
+// MobileNumber is the mobile, "" when the user has none.
+func (u *User) MobileNumber() string {
+	if u.Mobile == nil {
+		return ""
+	}
+	return *u.Mobile
+}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+func (u *User) IsTOTPEnabled() bool {
+	return u.TOTPEnabledAt != nil && u.TOTPSecret != ""
+}
+
+// EffectiveStatus is the status at now, a ban whose end has passed counts as active.
+func (u *User) EffectiveStatus(now time.Time) UserStatus {
+	if u.Status == UserStatusBanned && u.BannedUntil != nil && !u.BannedUntil.After(now) {
+		return UserStatusActive
+	}
+	if u.Status == "" {
+		return UserStatusActive
+	}
+	return u.Status
+}
+
+// HasRole reports whether the preloaded Roles contain code.
+func (u *User) HasRole(code UserRoleEnum) bool {
+	for _, role := range u.Roles {
+		if role.Code == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+type UserDataExportStatus string
+
+const (
+	UserDataExportStatusPending UserDataExportStatus = "pending"
+	UserDataExportStatusReady   UserDataExportStatus = "ready"
+	UserDataExportStatusFailed  UserDataExportStatus = "failed"
+)
+
+// UserDataExport is a ZIP archive of everything stored about a user, built in
+// the background. The archive lives in the storage backend under StorageKey
+// until ExpiresAt.
+type UserDataExport struct {
+	BaseModel
+	UniqueID     int64                `gorm:"uniqueIndex:uk_user_data_exports_unique_id;not null" json:"uniqueId"`
+	UserUniqueID int64                `gorm:"index;not null" json:"-"`
+	Status       UserDataExportStatus `gorm:"size:16;not null;index" json:"status"`
+	StorageKey   string               `gorm:"size:255" json:"-"`
+	Size         int64                `json:"size"`
+	ExpiresAt    *time.Time           `gorm:"index" json:"expiresAt"`
+	// PendingUserUniqueID is the user while the export is pending and NULL
+	// afterwards, the unique index allows one pending export per user.
+	PendingUserUniqueID *int64 `gorm:"uniqueIndex:uk_user_data_exports_pending" json:"-"`
+}
+
+func (e *UserDataExport) TableName() string {
+	return "user_data_exports"
+}
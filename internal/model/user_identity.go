@@ -0,0 +1,14 @@
+package model
+
+// UserIdentity links an account at an external OpenID Connect provider to a user.
+type UserIdentity struct {
+	BaseModel
+	UserUniqueID int64  `gorm:"index;not null" json:"-"`
+	Provider     string `gorm:"size:64;not null;uniqueIndex:uk_user_identities_provider_subject" json:"provider"`
+	Subject      string `gorm:"size:255;not null;uniqueIndex:uk_user_identities_provider_subject" json:"-"`
+	Email        string `json:"email"`
+}
+
+func (i *UserIdentity) TableName() string {
+	return "user_identities"
+}
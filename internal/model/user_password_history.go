@@ -0,0 +1,13 @@
+package model
+
+// UserPasswordHistory keeps the hashes of passwords a user has set, so a
+// new password can be checked against the recent ones.
+type UserPasswordHistory struct {
+	BaseModel
+	UserUniqueID int64  `gorm:"index;not null" json:"-"`
+	PasswordHash string `gorm:"not null;size:255" json:"-"`
+}
+
+func (h *UserPasswordHistory) TableName() string {
+	return "user_password_histories"
+}
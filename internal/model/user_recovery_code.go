@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// UserRecoveryCode is a one-time code that replaces a TOTP code when the
+// authenticator is lost. Only the hash of the code is stored.
+type UserRecoveryCode struct {
+	BaseModel
+	UserUniqueID int64      `gorm:"index;not null" json:"-"`
+	CodeHash     string     `gorm:"not null;size:64" json:"-"`
+	UsedAt       *time.Time `json:"usedAt"`
+}
+
+func (c *UserRecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
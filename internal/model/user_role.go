@@ -1,9 +1,13 @@
 package model
 
+// UserRole grants its permissions, and those of its parents, to its users.
 type UserRole struct {
 	BaseModel
-	Code UserRoleEnum `gorm:"not null;unique" json:"code"`
-	Name string       `gorm:"not null" json:"name"`
+	Code        UserRoleEnum  `gorm:"not null;unique" json:"code"`
+	Name        string        `gorm:"not null" json:"name"`
+	System      bool          `gorm:"not null;default:false" json:"system"` // 内置角色, 不能删除, 也不能移除其权限
+	Parents     []*UserRole   `gorm:"many2many:user_role_parent_ref;" json:"parents,omitempty"`
+	Permissions []*Permission `gorm:"many2many:user_role_permission_ref;" json:"permissions,omitempty"`
 }
 
 type UserRoleEnum string
@@ -28,3 +32,10 @@ var UserRoleSet = []UserRole{
 		Name: "用户",
 	},
 }
+
+// UserRoleParentSet is the role hierarchy a fresh install starts with, each
+// role inherits from the roles listed for it.
+var UserRoleParentSet = map[UserRoleEnum][]UserRoleEnum{
+	UserRoleCodeSuperAdmin: {UserRoleCodeAdmin},
+	UserRoleCodeAdmin:      {UserRoleCodeUser},
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// UserSession is one login of a user on a device. Its SessionID is the
+// refresh token family and the sid claim of the access tokens issued to it.
+type UserSession struct {
+	BaseModel
+	SessionID    string     `gorm:"size:32;uniqueIndex:uk_user_sessions_session_id;not null" json:"sessionId"`
+	UserUniqueID int64      `gorm:"index;not null" json:"-"`
+	DeviceName   string     `gorm:"size:64" json:"deviceName"`
+	UserAgent    string     `gorm:"size:512" json:"userAgent"`
+	IP           string     `gorm:"size:64" json:"ip"`
+	LastSeenAt   *time.Time `json:"lastSeenAt"`
+	ExpiresAt    *time.Time `json:"expiresAt"`
+	RevokedAt    *time.Time `json:"revokedAt"`
+}
+
+func (s *UserSession) TableName() string {
+	return "user_sessions"
+}
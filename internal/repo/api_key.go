@@ -0,0 +1,56 @@
+package repo
+
+import (
+	"context"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/model"
+	"super-web-server/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepo interface {
+	FindByID(ctx context.Context, id uint64) (*model.APIKey, error)
+	Create(ctx context.Context, entity *model.APIKey) error
+	Update(ctx context.Context, entity *model.APIKey) error
+	SoftDelete(ctx context.Context, id uint64) error
+	HardDelete(ctx context.Context, id uint64) error
+
+	FindOne(ctx context.Context, opts ...QueryOption) (*model.APIKey, error)
+	FindMany(ctx context.Context, opts ...QueryOption) ([]*model.APIKey, error)
+	FindPage(ctx context.Context, pagination dto.Pagination, opts ...QueryOption) ([]*model.APIKey, int64, error)
+
+	UpdateForce(ctx context.Context, entity *model.APIKey) error
+	UpdateByMap(ctx context.Context, id uint64, data map[string]any) error
+
+	FindByKeyHash(ctx context.Context, keyHash string) (*model.APIKey, error)
+
+	WithTx(tx *gorm.DB) APIKeyRepo
+}
+
+type apiKeyRepo struct {
+	BaseRepo[model.APIKey]
+	db     *gorm.DB
+	logger *logger.Logger
+}
+
+func NewAPIKeyRepo(db *gorm.DB, logger *logger.Logger) APIKeyRepo {
+	logger.Info("NewAPIKeyRepo initialized successfully")
+	return &apiKeyRepo{
+		BaseRepo: NewBaseRepo[model.APIKey](db, logger),
+		db:       db,
+		logger:   logger,
+	}
+}
+
+func (r *apiKeyRepo) FindByKeyHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	return r.BaseRepo.FindOne(ctx, Where("key_hash = ?", keyHash))
+}
+
+func (r *apiKeyRepo) WithTx(tx *gorm.DB) APIKeyRepo {
+	return &apiKeyRepo{
+		BaseRepo: r.BaseRepo.WithTx(tx),
+		db:       tx,
+		logger:   r.logger,
+	}
+}
@@ -0,0 +1,61 @@
+package repo
+
+import (
+	"context"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/model"
+	"super-web-server/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+type FileRepo interface {
+	FindByID(ctx context.Context, id uint64) (*model.File, error)
+	Create(ctx context.Context, entity *model.File) error
+	Update(ctx context.Context, entity *model.File) error
+	SoftDelete(ctx context.Context, id uint64) error
+	HardDelete(ctx context.Context, id uint64) error
+
+	FindOne(ctx context.Context, opts ...QueryOption) (*model.File, error)
+	FindMany(ctx context.Context, opts ...QueryOption) ([]*model.File, error)
+	FindPage(ctx context.Context, pagination dto.Pagination, opts ...QueryOption) ([]*model.File, int64, error)
+
+	UpdateForce(ctx context.Context, entity *model.File) error
+	UpdateByMap(ctx context.Context, id uint64, data map[string]any) error
+
+	FindByUniqueID(ctx context.Context, uniqueID int64) (*model.File, error)
+	FindByStorageKey(ctx context.Context, storageKey string) (*model.File, error)
+
+	WithTx(tx *gorm.DB) FileRepo
+}
+
+type fileRepo struct {
+	BaseRepo[model.File]
+	db     *gorm.DB
+	logger *logger.Logger
+}
+
+func NewFileRepo(db *gorm.DB, logger *logger.Logger) FileRepo {
+	logger.Info("NewFileRepo initialized successfully")
+	return &fileRepo{
+		BaseRepo: NewBaseRepo[model.File](db, logger),
+		db:       db,
+		logger:   logger,
+	}
+}
+
+func (r *fileRepo) FindByUniqueID(ctx context.Context, uniqueID int64) (*model.File, error) {
+	return r.BaseRepo.FindOne(ctx, Where("unique_id = ?", uniqueID))
+}
+
+func (r *fileRepo) FindByStorageKey(ctx context.Context, storageKey string) (*model.File, error) {
+	return r.BaseRepo.FindOne(ctx, Where("storage_key = ?", storageKey))
+}
+
+func (r *fileRepo) WithTx(tx *gorm.DB) FileRepo {
+	return &fileRepo{
+		BaseRepo: r.BaseRepo.WithTx(tx),
+		db:       tx,
+		logger:   r.logger,
+	}
+}
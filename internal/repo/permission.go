@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"context"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/model"
+	"super-web-server/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+type PermissionRepo interface {
+	FindByID(ctx context.Context, id uint64) (*model.Permission, error)
+	Create(ctx context.Context, entity *model.Permission) error
+	Update(ctx context.Context, entity *model.Permission) error
+	SoftDelete(ctx context.Context, id uint64) error
+	HardDelete(ctx context.Context, id uint64) error
+
+	FindOne(ctx context.Context, opts ...QueryOption) (*model.Permission, error)
+	FindMany(ctx context.Context, opts ...QueryOption) ([]*model.Permission, error)
+	FindPage(ctx context.Context, pagination dto.Pagination, opts ...QueryOption) ([]*model.Permission, int64, error)
+
+	UpdateForce(ctx context.Context, entity *model.Permission) error
+	UpdateByMap(ctx context.Context, id uint64, data map[string]any) error
+
+	FindByCode(ctx context.Context, code model.PermissionEnum) (*model.Permission, error)
+	FindByCodes(ctx context.Context, codes []model.PermissionEnum) ([]*model.Permission, error)
+	FindCodesByRoleIDs(ctx context.Context, roleIDs []uint64) ([]model.PermissionEnum, error)
+
+	WithTx(tx *gorm.DB) PermissionRepo
+}
+
+type permissionRepo struct {
+	BaseRepo[model.Permission]
+	db     *gorm.DB
+	logger *logger.Logger
+}
+
+func NewPermissionRepo(db *gorm.DB, logger *logger.Logger) PermissionRepo {
+	logger.Info("NewPermissionRepo initialized successfully")
+	return &permissionRepo{
+		BaseRepo: NewBaseRepo[model.Permission](db, logger),
+		db:       db,
+		logger:   logger,
+	}
+}
+
+func (r *permissionRepo) FindByCode(ctx context.Context, code model.PermissionEnum) (*model.Permission, error) {
+	return r.BaseRepo.FindOne(ctx, Where("code = ?", code))
+}
+
+// FindCodesByRoleIDs resolves the permissions granted by any of the roles.
+func (r *permissionRepo) FindCodesByRoleIDs(ctx context.Context, roleIDs []uint64) ([]model.PermissionEnum, error) {
+	var codes []model.PermissionEnum
+	err := r.db.WithContext(ctx).
+		Model(&model.Permission{}).
+		Distinct("permissions.code").
+		Joins("JOIN user_role_permission_ref rp ON rp.permission_id = permissions.id").
+		Where("rp.user_role_id IN ?", roleIDs).
+		Order("permissions.code").
+		Pluck("permissions.code", &codes).Error
+	return codes, err
+}
+
+func (r *permissionRepo) FindByCodes(ctx context.Context, codes []model.PermissionEnum) ([]*model.Permission, error) {
+	return r.BaseRepo.FindMany(ctx, Where("code IN ?", codes))
+}
+
+func (r *permissionRepo) WithTx(tx *gorm.DB) PermissionRepo {
+	return &permissionRepo{
+		BaseRepo: r.BaseRepo.WithTx(tx),
+		db:       tx,
+		logger:   r.logger,
+	}
+}
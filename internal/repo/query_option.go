@@ -61,3 +61,21 @@ func Paginate(page, pageSize int) QueryOption {
 		return db.Limit(pageSize).Offset((page - 1) * pageSize)
 	}
 }
+
+// Limit specify the number of records to be retrieved
+//
+//	db.Limit(10)
+func Limit(limit int) QueryOption {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Limit(limit)
+	}
+}
+
+// Unscoped includes soft deleted records
+//
+//	db.Unscoped().Where("deleted_at IS NOT NULL").Find(&users)
+func Unscoped() QueryOption {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}
+}
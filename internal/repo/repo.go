@@ -8,21 +8,84 @@ import (
 
 type Repo interface {
 	User() UserRepo
+	UserRole() UserRoleRepo
+	Permission() PermissionRepo
+	UserRecoveryCode() UserRecoveryCodeRepo
+	UserIdentity() UserIdentityRepo
+	APIKey() APIKeyRepo
+	UserSession() UserSessionRepo
+	UserPasswordHistory() UserPasswordHistoryRepo
+	File() FileRepo
+	UserDataExport() UserDataExportRepo
 }
 
 type repo struct {
-	userRepo UserRepo
-	logger   *logger.Logger
+	userRepo                UserRepo
+	userRoleRepo            UserRoleRepo
+	permissionRepo          PermissionRepo
+	userRecoveryCodeRepo    UserRecoveryCodeRepo
+	userIdentityRepo        UserIdentityRepo
+	apiKeyRepo              APIKeyRepo
+	userSessionRepo         UserSessionRepo
+	userPasswordHistoryRepo UserPasswordHistoryRepo
+	fileRepo                FileRepo
+	userDataExportRepo      UserDataExportRepo
+	logger                  *logger.Logger
 }
 
 func NewRepo(db *gorm.DB, logger *logger.Logger) Repo {
 	logger.Info("NewRepo initialized successfully")
 	return &repo{
-		userRepo: NewUserRepo(db, logger),
-		logger:   logger,
+		userRepo:                NewUserRepo(db, logger),
+		userRoleRepo:            NewUserRoleRepo(db, logger),
+		permissionRepo:          NewPermissionRepo(db, logger),
+		userRecoveryCodeRepo:    NewUserRecoveryCodeRepo(db, logger),
+		userIdentityRepo:        NewUserIdentityRepo(db, logger),
+		apiKeyRepo:              NewAPIKeyRepo(db, logger),
+		userSessionRepo:         NewUserSessionRepo(db, logger),
+		userPasswordHistoryRepo: NewUserPasswordHistoryRepo(db, logger),
+		fileRepo:                NewFileRepo(db, logger),
+		userDataExportRepo:      NewUserDataExportRepo(db, logger),
+		logger:                  logger,
 	}
 }
 
 func (r *repo) User() UserRepo {
 	return r.userRepo
 }
+
+func (r *repo) UserRole() UserRoleRepo {
+	return r.userRoleRepo
+}
+
+func (r *repo) Permission() PermissionRepo {
+	return r.permissionRepo
+}
+
+func (r *repo) UserRecoveryCode() UserRecoveryCodeRepo {
+	return r.userRecoveryCodeRepo
+}
+
+func (r *repo) UserIdentity() UserIdentityRepo {
+	return r.userIdentityRepo
+}
+
+func (r *repo) APIKey() APIKeyRepo {
+	return r.apiKeyRepo
+}
+
+func (r *repo) UserSession() UserSessionRepo {
+	return r.userSessionRepo
+}
+
+func (r *repo) UserPasswordHistory() UserPasswordHistoryRepo {
+	return r.userPasswordHistoryRepo
+}
+
+func (r *repo) File() FileRepo {
+	return r.fileRepo
+}
+
+func (r *repo) UserDataExport() UserDataExportRepo {
+	return r.userDataExportRepo
+}
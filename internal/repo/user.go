@@ -5,8 +5,10 @@ import (
 	"super-web-server/internal/dto"
 	"super-web-server/internal/model"
 	"super-web-server/pkg/logger"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepo interface {
@@ -25,6 +27,16 @@ type UserRepo interface {
 
 	FindByUniqueID(ctx context.Context, uniqueID int64) (*model.User, error)
 	FindByEmail(ctx context.Context, email string) (*model.User, error)
+	FindByMobile(ctx context.Context, mobile string) (*model.User, error)
+	FindByUniqueIDUnscoped(ctx context.Context, uniqueID int64) (*model.User, error)
+	Restore(ctx context.Context, id uint64) error
+	AddRole(ctx context.Context, user *model.User, role *model.UserRole) error
+	RemoveRole(ctx context.Context, user *model.User, role *model.UserRole) error
+	ChangeEmail(ctx context.Context, id uint64, oldEmail, newEmail string, data map[string]any) error
+	FindDeletionDue(ctx context.Context, before time.Time, limit int) ([]*model.User, error)
+	FindUnverifiedBefore(ctx context.Context, before time.Time, limit int) ([]*model.User, error)
+	DeleteUnverified(ctx context.Context, user *model.User, before time.Time) error
+	Anonymize(ctx context.Context, user *model.User, data map[string]any) error
 
 	WithTx(tx *gorm.DB) UserRepo
 }
@@ -60,6 +72,145 @@ func (r *userRepo) FindByEmail(ctx context.Context, email string) (*model.User,
 	return r.BaseRepo.FindOne(ctx, opts...)
 }
 
+func (r *userRepo) FindByMobile(ctx context.Context, mobile string) (*model.User, error) {
+	var opts = []QueryOption{
+		Preload("Roles"),
+		Where("mobile = ?", mobile),
+	}
+	return r.BaseRepo.FindOne(ctx, opts...)
+}
+
+// FindByUniqueIDUnscoped also finds soft deleted users.
+func (r *userRepo) FindByUniqueIDUnscoped(ctx context.Context, uniqueID int64) (*model.User, error) {
+	var opts = []QueryOption{
+		Unscoped(),
+		Preload("Roles"),
+		Where("unique_id = ?", uniqueID),
+	}
+	return r.BaseRepo.FindOne(ctx, opts...)
+}
+
+// Restore undoes a soft delete, a pending deletion requested by the user
+// included.
+func (r *userRepo) Restore(ctx context.Context, id uint64) error {
+	return r.db.WithContext(ctx).Unscoped().Model(&model.User{}).Where("id = ?", id).
+		Updates(map[string]any{"deleted_at": nil, "deletion_requested_at": nil}).Error
+}
+
+func (r *userRepo) AddRole(ctx context.Context, user *model.User, role *model.UserRole) error {
+	return r.db.WithContext(ctx).Model(user).Association("Roles").Append(role)
+}
+
+func (r *userRepo) RemoveRole(ctx context.Context, user *model.User, role *model.UserRole) error {
+	return r.db.WithContext(ctx).Model(user).Association("Roles").Delete(role)
+}
+
+// ChangeEmail moves the user from oldEmail to newEmail and applies data in the
+// same transaction. It fails with gorm.ErrDuplicatedKey when newEmail belongs
+// to another user (soft deleted ones included, they keep their address) and
+// with gorm.ErrRecordNotFound when the email is no longer oldEmail.
+func (r *userRepo) ChangeEmail(ctx context.Context, id uint64, oldEmail, newEmail string, data map[string]any) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var owners []model.User
+		err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("email = ?", newEmail).Limit(1).Find(&owners).Error
+		if err != nil {
+			return err
+		}
+		if len(owners) > 0 {
+			return gorm.ErrDuplicatedKey
+		}
+
+		updates := map[string]any{"email": newEmail}
+		for key, value := range data {
+			updates[key] = value
+		}
+		result := tx.Model(&model.User{}).Where("id = ? AND email = ?", id, oldEmail).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// FindDeletionDue finds users that asked for their account to be deleted
+// before before and have not been anonymized yet. Users restored in the
+// meantime are no longer soft deleted and not found.
+func (r *userRepo) FindDeletionDue(ctx context.Context, before time.Time, limit int) ([]*model.User, error) {
+	var opts = []QueryOption{
+		Unscoped(),
+		Where("deleted_at IS NOT NULL AND deletion_requested_at IS NOT NULL AND deletion_requested_at < ? AND anonymized_at IS NULL", before),
+		Order("deletion_requested_at"),
+		Limit(limit),
+	}
+	return r.BaseRepo.FindMany(ctx, opts...)
+}
+
+// FindUnverifiedBefore finds pending users created before before that never
+// verified their email.
+func (r *userRepo) FindUnverifiedBefore(ctx context.Context, before time.Time, limit int) ([]*model.User, error) {
+	var opts = []QueryOption{
+		Where("status = ? AND email_verified_at IS NULL AND created_at < ?", model.UserStatusPending, before),
+		Order("created_at"),
+		Limit(limit),
+	}
+	return r.BaseRepo.FindMany(ctx, opts...)
+}
+
+// DeleteUnverified deletes a user found by FindUnverifiedBefore for good,
+// with their roles and password history, so the email can be registered
+// again. It fails with gorm.ErrRecordNotFound when the user verified the
+// email in the meantime.
+func (r *userRepo) DeleteUnverified(ctx context.Context, user *model.User, before time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().
+			Where("id = ? AND status = ? AND email_verified_at IS NULL AND created_at < ?", user.ID, model.UserStatusPending, before).
+			Delete(&model.User{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Model(user).Association("Roles").Clear(); err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_unique_id = ?", user.UniqueID).Delete(&model.UserPasswordHistory{}).Error
+	})
+}
+
+// Anonymize overwrites the personal fields of a deleted user with data and,
+// in the same transaction, drops what links the row to a person: external
+// identities, recovery codes, password history and sessions (with their IP
+// and user agent) are deleted, API keys revoked. The row itself stays, other
+// tables reference it.
+func (r *userRepo) Anonymize(ctx context.Context, user *model.User, data map[string]any) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&model.User{}).
+			Where("id = ? AND deleted_at IS NOT NULL AND anonymized_at IS NULL", user.ID).
+			Updates(data)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		now := time.Now()
+		for _, entity := range []any{&model.UserIdentity{}, &model.UserRecoveryCode{}, &model.UserPasswordHistory{}, &model.UserSession{}} {
+			if err := tx.Unscoped().Where("user_unique_id = ?", user.UniqueID).Delete(entity).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&model.APIKey{}).
+			Where("owner_unique_id = ? AND revoked_at IS NULL", user.UniqueID).
+			Update("revoked_at", now).Error
+	})
+}
+
 func (r *userRepo) WithTx(tx *gorm.DB) UserRepo {
 	return &userRepo{
 		BaseRepo: r.BaseRepo.WithTx(tx),
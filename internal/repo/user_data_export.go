@@ -0,0 +1,78 @@
+package repo
+
+import (
+	"context"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/model"
+	"super-web-server/pkg/logger"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UserDataExportRepo interface {
+	FindByID(ctx context.Context, id uint64) (*model.UserDataExport, error)
+	Create(ctx context.Context, entity *model.UserDataExport) error
+	Update(ctx context.Context, entity *model.UserDataExport) error
+	SoftDelete(ctx context.Context, id uint64) error
+	HardDelete(ctx context.Context, id uint64) error
+
+	FindOne(ctx context.Context, opts ...QueryOption) (*model.UserDataExport, error)
+	FindMany(ctx context.Context, opts ...QueryOption) ([]*model.UserDataExport, error)
+	FindPage(ctx context.Context, pagination dto.Pagination, opts ...QueryOption) ([]*model.UserDataExport, int64, error)
+
+	UpdateForce(ctx context.Context, entity *model.UserDataExport) error
+	UpdateByMap(ctx context.Context, id uint64, data map[string]any) error
+
+	FindByUniqueID(ctx context.Context, uniqueID int64) (*model.UserDataExport, error)
+	FindExpired(ctx context.Context, before time.Time, limit int) ([]*model.UserDataExport, error)
+	FailStale(ctx context.Context, createdBefore time.Time) (int64, error)
+
+	WithTx(tx *gorm.DB) UserDataExportRepo
+}
+
+type userDataExportRepo struct {
+	BaseRepo[model.UserDataExport]
+	db     *gorm.DB
+	logger *logger.Logger
+}
+
+func NewUserDataExportRepo(db *gorm.DB, logger *logger.Logger) UserDataExportRepo {
+	logger.Info("NewUserDataExportRepo initialized successfully")
+	return &userDataExportRepo{
+		BaseRepo: NewBaseRepo[model.UserDataExport](db, logger),
+		db:       db,
+		logger:   logger,
+	}
+}
+
+func (r *userDataExportRepo) FindByUniqueID(ctx context.Context, uniqueID int64) (*model.UserDataExport, error) {
+	return r.BaseRepo.FindOne(ctx, Where("unique_id = ?", uniqueID))
+}
+
+// FindExpired finds the exports whose archive expired before before, failed
+// exports included.
+func (r *userDataExportRepo) FindExpired(ctx context.Context, before time.Time, limit int) ([]*model.UserDataExport, error) {
+	return r.BaseRepo.FindMany(ctx,
+		Where("expires_at < ?", before),
+		Order("expires_at"),
+		Limit(limit),
+	)
+}
+
+// FailStale fails the exports still pending since createdBefore, e.g. because
+// the instance building them was stopped.
+func (r *userDataExportRepo) FailStale(ctx context.Context, createdBefore time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&model.UserDataExport{}).
+		Where("status = ? AND created_at < ?", model.UserDataExportStatusPending, createdBefore).
+		Updates(map[string]any{"status": model.UserDataExportStatusFailed, "pending_user_unique_id": nil})
+	return result.RowsAffected, result.Error
+}
+
+func (r *userDataExportRepo) WithTx(tx *gorm.DB) UserDataExportRepo {
+	return &userDataExportRepo{
+		BaseRepo: r.BaseRepo.WithTx(tx),
+		db:       tx,
+		logger:   r.logger,
+	}
+}
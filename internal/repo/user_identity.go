@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"context"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/model"
+	"super-web-server/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+type UserIdentityRepo interface {
+	FindByID(ctx context.Context, id uint64) (*model.UserIdentity, error)
+	Create(ctx context.Context, entity *model.UserIdentity) error
+	Update(ctx context.Context, entity *model.UserIdentity) error
+	SoftDelete(ctx context.Context, id uint64) error
+	HardDelete(ctx context.Context, id uint64) error
+
+	FindOne(ctx context.Context, opts ...QueryOption) (*model.UserIdentity, error)
+	FindMany(ctx context.Context, opts ...QueryOption) ([]*model.UserIdentity, error)
+	FindPage(ctx context.Context, pagination dto.Pagination, opts ...QueryOption) ([]*model.UserIdentity, int64, error)
+
+	UpdateForce(ctx context.Context, entity *model.UserIdentity) error
+	UpdateByMap(ctx context.Context, id uint64, data map[string]any) error
+
+	FindByProviderSubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error)
+	CreateWithUser(ctx context.Context, user *model.User, identity *model.UserIdentity) error
+
+	WithTx(tx *gorm.DB) UserIdentityRepo
+}
+
+type userIdentityRepo struct {
+	BaseRepo[model.UserIdentity]
+	db     *gorm.DB
+	logger *logger.Logger
+}
+
+func NewUserIdentityRepo(db *gorm.DB, logger *logger.Logger) UserIdentityRepo {
+	logger.Info("NewUserIdentityRepo initialized successfully")
+	return &userIdentityRepo{
+		BaseRepo: NewBaseRepo[model.UserIdentity](db, logger),
+		db:       db,
+		logger:   logger,
+	}
+}
+
+func (r *userIdentityRepo) FindByProviderSubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error) {
+	return r.BaseRepo.FindOne(ctx, Where("provider = ? AND subject = ?", provider, subject))
+}
+
+// CreateWithUser creates a new user together with its first identity.
+func (r *userIdentityRepo) CreateWithUser(ctx context.Context, user *model.User, identity *model.UserIdentity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserUniqueID = user.UniqueID
+		return tx.Create(identity).Error
+	})
+}
+
+func (r *userIdentityRepo) WithTx(tx *gorm.DB) UserIdentityRepo {
+	return &userIdentityRepo{
+		BaseRepo: r.BaseRepo.WithTx(tx),
+		db:       tx,
+		logger:   r.logger,
+	}
+}
@@ -0,0 +1,84 @@
+package repo
+
+import (
+	"context"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/model"
+	"super-web-server/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+type UserPasswordHistoryRepo interface {
+	FindByID(ctx context.Context, id uint64) (*model.UserPasswordHistory, error)
+	Create(ctx context.Context, entity *model.UserPasswordHistory) error
+	Update(ctx context.Context, entity *model.UserPasswordHistory) error
+	SoftDelete(ctx context.Context, id uint64) error
+	HardDelete(ctx context.Context, id uint64) error
+
+	FindOne(ctx context.Context, opts ...QueryOption) (*model.UserPasswordHistory, error)
+	FindMany(ctx context.Context, opts ...QueryOption) ([]*model.UserPasswordHistory, error)
+	FindPage(ctx context.Context, pagination dto.Pagination, opts ...QueryOption) ([]*model.UserPasswordHistory, int64, error)
+
+	UpdateForce(ctx context.Context, entity *model.UserPasswordHistory) error
+	UpdateByMap(ctx context.Context, id uint64, data map[string]any) error
+
+	FindRecentByUserUniqueID(ctx context.Context, userUniqueID int64, limit int) ([]*model.UserPasswordHistory, error)
+	PruneByUserUniqueID(ctx context.Context, userUniqueID int64, keep int) error
+	DeleteByUserUniqueID(ctx context.Context, userUniqueID int64) error
+
+	WithTx(tx *gorm.DB) UserPasswordHistoryRepo
+}
+
+type userPasswordHistoryRepo struct {
+	BaseRepo[model.UserPasswordHistory]
+	db     *gorm.DB
+	logger *logger.Logger
+}
+
+func NewUserPasswordHistoryRepo(db *gorm.DB, logger *logger.Logger) UserPasswordHistoryRepo {
+	logger.Info("NewUserPasswordHistoryRepo initialized successfully")
+	return &userPasswordHistoryRepo{
+		BaseRepo: NewBaseRepo[model.UserPasswordHistory](db, logger),
+		db:       db,
+		logger:   logger,
+	}
+}
+
+// FindRecentByUserUniqueID returns the last limit passwords of the user, newest first.
+func (r *userPasswordHistoryRepo) FindRecentByUserUniqueID(ctx context.Context, userUniqueID int64, limit int) ([]*model.UserPasswordHistory, error) {
+	var entries []*model.UserPasswordHistory
+	err := r.db.WithContext(ctx).
+		Where("user_unique_id = ?", userUniqueID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
+
+// PruneByUserUniqueID deletes all but the newest keep entries of the user.
+func (r *userPasswordHistoryRepo) PruneByUserUniqueID(ctx context.Context, userUniqueID int64, keep int) error {
+	var ids []uint64
+	err := r.db.WithContext(ctx).Model(&model.UserPasswordHistory{}).
+		Where("user_unique_id = ?", userUniqueID).
+		Order("id DESC").
+		Offset(keep).
+		Limit(-1).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	return r.db.WithContext(ctx).Unscoped().Delete(&model.UserPasswordHistory{}, ids).Error
+}
+
+func (r *userPasswordHistoryRepo) DeleteByUserUniqueID(ctx context.Context, userUniqueID int64) error {
+	return r.db.WithContext(ctx).Unscoped().Where("user_unique_id = ?", userUniqueID).Delete(&model.UserPasswordHistory{}).Error
+}
+
+func (r *userPasswordHistoryRepo) WithTx(tx *gorm.DB) UserPasswordHistoryRepo {
+	return &userPasswordHistoryRepo{
+		BaseRepo: r.BaseRepo.WithTx(tx),
+		db:       tx,
+		logger:   r.logger,
+	}
+}
@@ -0,0 +1,85 @@
+package repo
+
+import (
+	"context"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/model"
+	"super-web-server/pkg/logger"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UserRecoveryCodeRepo interface {
+	FindByID(ctx context.Context, id uint64) (*model.UserRecoveryCode, error)
+	Create(ctx context.Context, entity *model.UserRecoveryCode) error
+	Update(ctx context.Context, entity *model.UserRecoveryCode) error
+	SoftDelete(ctx context.Context, id uint64) error
+	HardDelete(ctx context.Context, id uint64) error
+
+	FindOne(ctx context.Context, opts ...QueryOption) (*model.UserRecoveryCode, error)
+	FindMany(ctx context.Context, opts ...QueryOption) ([]*model.UserRecoveryCode, error)
+	FindPage(ctx context.Context, pagination dto.Pagination, opts ...QueryOption) ([]*model.UserRecoveryCode, int64, error)
+
+	UpdateForce(ctx context.Context, entity *model.UserRecoveryCode) error
+	UpdateByMap(ctx context.Context, id uint64, data map[string]any) error
+
+	ReplaceByUserUniqueID(ctx context.Context, userUniqueID int64, codeHashes []string) error
+	DeleteByUserUniqueID(ctx context.Context, userUniqueID int64) error
+	UseCode(ctx context.Context, userUniqueID int64, codeHash string) (bool, error)
+
+	WithTx(tx *gorm.DB) UserRecoveryCodeRepo
+}
+
+type userRecoveryCodeRepo struct {
+	BaseRepo[model.UserRecoveryCode]
+	db     *gorm.DB
+	logger *logger.Logger
+}
+
+func NewUserRecoveryCodeRepo(db *gorm.DB, logger *logger.Logger) UserRecoveryCodeRepo {
+	logger.Info("NewUserRecoveryCodeRepo initialized successfully")
+	return &userRecoveryCodeRepo{
+		BaseRepo: NewBaseRepo[model.UserRecoveryCode](db, logger),
+		db:       db,
+		logger:   logger,
+	}
+}
+
+// ReplaceByUserUniqueID drops every existing code of the user and stores the new ones.
+func (r *userRecoveryCodeRepo) ReplaceByUserUniqueID(ctx context.Context, userUniqueID int64, codeHashes []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_unique_id = ?", userUniqueID).Delete(&model.UserRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]*model.UserRecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, &model.UserRecoveryCode{UserUniqueID: userUniqueID, CodeHash: hash})
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *userRecoveryCodeRepo) DeleteByUserUniqueID(ctx context.Context, userUniqueID int64) error {
+	return r.db.WithContext(ctx).Unscoped().Where("user_unique_id = ?", userUniqueID).Delete(&model.UserRecoveryCode{}).Error
+}
+
+// UseCode marks an unused code as used. It reports false when no unused code
+// matches, the conditional update keeps a code from being used twice.
+func (r *userRecoveryCodeRepo) UseCode(ctx context.Context, userUniqueID int64, codeHash string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.UserRecoveryCode{}).
+		Where("user_unique_id = ? AND code_hash = ? AND used_at IS NULL", userUniqueID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *userRecoveryCodeRepo) WithTx(tx *gorm.DB) UserRecoveryCodeRepo {
+	return &userRecoveryCodeRepo{
+		BaseRepo: r.BaseRepo.WithTx(tx),
+		db:       tx,
+		logger:   r.logger,
+	}
+}
@@ -0,0 +1,125 @@
+package repo
+
+import (
+	"context"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/model"
+	"super-web-server/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+type UserRoleRepo interface {
+	FindByID(ctx context.Context, id uint64) (*model.UserRole, error)
+	Create(ctx context.Context, entity *model.UserRole) error
+	Update(ctx context.Context, entity *model.UserRole) error
+	SoftDelete(ctx context.Context, id uint64) error
+	HardDelete(ctx context.Context, id uint64) error
+
+	FindOne(ctx context.Context, opts ...QueryOption) (*model.UserRole, error)
+	FindMany(ctx context.Context, opts ...QueryOption) ([]*model.UserRole, error)
+	FindPage(ctx context.Context, pagination dto.Pagination, opts ...QueryOption) ([]*model.UserRole, int64, error)
+
+	UpdateForce(ctx context.Context, entity *model.UserRole) error
+	UpdateByMap(ctx context.Context, id uint64, data map[string]any) error
+
+	FindByCode(ctx context.Context, code model.UserRoleEnum) (*model.UserRole, error)
+	FindByCodes(ctx context.Context, codes []model.UserRoleEnum) ([]*model.UserRole, error)
+	CountUsers(ctx context.Context, id uint64) (int64, error)
+	CountChildren(ctx context.Context, id uint64) (int64, error)
+	UpdateWithRefs(ctx context.Context, role *model.UserRole, data map[string]any, parents []*model.UserRole, permissions []*model.Permission) error
+	DeleteWithRefs(ctx context.Context, role *model.UserRole) error
+
+	WithTx(tx *gorm.DB) UserRoleRepo
+}
+
+type userRoleRepo struct {
+	BaseRepo[model.UserRole]
+	db     *gorm.DB
+	logger *logger.Logger
+}
+
+func NewUserRoleRepo(db *gorm.DB, logger *logger.Logger) UserRoleRepo {
+	logger.Info("NewUserRoleRepo initialized successfully")
+	return &userRoleRepo{
+		BaseRepo: NewBaseRepo[model.UserRole](db, logger),
+		db:       db,
+		logger:   logger,
+	}
+}
+
+func (r *userRoleRepo) FindByCode(ctx context.Context, code model.UserRoleEnum) (*model.UserRole, error) {
+	return r.BaseRepo.FindOne(ctx, Where("code = ?", code))
+}
+
+func (r *userRoleRepo) FindByCodes(ctx context.Context, codes []model.UserRoleEnum) ([]*model.UserRole, error) {
+	return r.BaseRepo.FindMany(ctx, Where("code IN ?", codes))
+}
+
+// CountUsers counts the users holding the role directly, soft deleted users
+// included since they can be restored.
+func (r *userRoleRepo) CountUsers(ctx context.Context, id uint64) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table("user_role_ref").Where("user_role_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// CountChildren counts the roles inheriting from the role.
+func (r *userRoleRepo) CountChildren(ctx context.Context, id uint64) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table("user_role_parent_ref").Where("parent_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// UpdateWithRefs applies data and replaces the parents and permissions of the
+// role in one transaction. A nil slice leaves them as they are, an empty one
+// clears them.
+func (r *userRoleRepo) UpdateWithRefs(ctx context.Context, role *model.UserRole, data map[string]any, parents []*model.UserRole, permissions []*model.Permission) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(data) > 0 {
+			if err := tx.Model(&model.UserRole{}).Where("id = ?", role.ID).Updates(data).Error; err != nil {
+				return err
+			}
+		}
+		if parents != nil {
+			if err := replaceAssociation(tx.Model(role).Association("Parents"), parents); err != nil {
+				return err
+			}
+		}
+		if permissions != nil {
+			if err := replaceAssociation(tx.Model(role).Association("Permissions"), permissions); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func replaceAssociation[T any](association *gorm.Association, values []T) error {
+	if len(values) == 0 {
+		return association.Clear()
+	}
+	return association.Replace(values)
+}
+
+// DeleteWithRefs removes the role for good, with its links to parents and
+// permissions, so its code can be used again.
+func (r *userRoleRepo) DeleteWithRefs(ctx context.Context, role *model.UserRole) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Association("Parents").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&model.UserRole{}, role.ID).Error
+	})
+}
+
+func (r *userRoleRepo) WithTx(tx *gorm.DB) UserRoleRepo {
+	return &userRoleRepo{
+		BaseRepo: r.BaseRepo.WithTx(tx),
+		db:       tx,
+		logger:   r.logger,
+	}
+}
@@ -0,0 +1,85 @@
+package repo
+
+import (
+	"context"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/model"
+	"super-web-server/pkg/logger"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UserSessionRepo interface {
+	FindByID(ctx context.Context, id uint64) (*model.UserSession, error)
+	Create(ctx context.Context, entity *model.UserSession) error
+	Update(ctx context.Context, entity *model.UserSession) error
+	SoftDelete(ctx context.Context, id uint64) error
+	HardDelete(ctx context.Context, id uint64) error
+
+	FindOne(ctx context.Context, opts ...QueryOption) (*model.UserSession, error)
+	FindMany(ctx context.Context, opts ...QueryOption) ([]*model.UserSession, error)
+	FindPage(ctx context.Context, pagination dto.Pagination, opts ...QueryOption) ([]*model.UserSession, int64, error)
+
+	UpdateForce(ctx context.Context, entity *model.UserSession) error
+	UpdateByMap(ctx context.Context, id uint64, data map[string]any) error
+
+	FindBySessionID(ctx context.Context, sessionID string) (*model.UserSession, error)
+	FindActiveByUserUniqueID(ctx context.Context, userUniqueID int64) ([]*model.UserSession, error)
+	UpdateBySessionID(ctx context.Context, sessionID string, data map[string]any) error
+	RevokeBySessionID(ctx context.Context, sessionID string) error
+	RevokeByUserUniqueID(ctx context.Context, userUniqueID int64) error
+
+	WithTx(tx *gorm.DB) UserSessionRepo
+}
+
+type userSessionRepo struct {
+	BaseRepo[model.UserSession]
+	db     *gorm.DB
+	logger *logger.Logger
+}
+
+func NewUserSessionRepo(db *gorm.DB, logger *logger.Logger) UserSessionRepo {
+	logger.Info("NewUserSessionRepo initialized successfully")
+	return &userSessionRepo{
+		BaseRepo: NewBaseRepo[model.UserSession](db, logger),
+		db:       db,
+		logger:   logger,
+	}
+}
+
+func (r *userSessionRepo) FindBySessionID(ctx context.Context, sessionID string) (*model.UserSession, error) {
+	return r.BaseRepo.FindOne(ctx, Where("session_id = ?", sessionID))
+}
+
+// FindActiveByUserUniqueID returns the sessions that are neither revoked nor expired, most recent first.
+func (r *userSessionRepo) FindActiveByUserUniqueID(ctx context.Context, userUniqueID int64) ([]*model.UserSession, error) {
+	return r.BaseRepo.FindMany(ctx,
+		Where("user_unique_id = ? AND revoked_at IS NULL AND expires_at > ?", userUniqueID, time.Now()),
+		Order("last_seen_at DESC"),
+	)
+}
+
+func (r *userSessionRepo) UpdateBySessionID(ctx context.Context, sessionID string, data map[string]any) error {
+	return r.db.WithContext(ctx).Model(&model.UserSession{}).Where("session_id = ?", sessionID).Updates(data).Error
+}
+
+func (r *userSessionRepo) RevokeBySessionID(ctx context.Context, sessionID string) error {
+	return r.db.WithContext(ctx).Model(&model.UserSession{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *userSessionRepo) RevokeByUserUniqueID(ctx context.Context, userUniqueID int64) error {
+	return r.db.WithContext(ctx).Model(&model.UserSession{}).
+		Where("user_unique_id = ? AND revoked_at IS NULL", userUniqueID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *userSessionRepo) WithTx(tx *gorm.DB) UserSessionRepo {
+	return &userSessionRepo{
+		BaseRepo: r.BaseRepo.WithTx(tx),
+		db:       tx,
+		logger:   r.logger,
+	}
+}
@@ -0,0 +1,53 @@
+package seed
+
+import (
+	"slices"
+	"super-web-server/internal/model"
+	"super-web-server/pkg/database"
+)
+
+// SeedPermission creates the missing permissions and grants each new one to
+// the roles of the default matrix. Permissions that already exist are left
+// alone, so grants changed since are not undone on restart.
+func SeedPermission(db *database.DB) error {
+	tx := db.Begin()
+	for _, permission := range model.PermissionSet {
+		var count int64
+		if err := tx.Model(&model.Permission{}).Where("code = ?", permission.Code).Count(&count).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		if err := tx.Create(&permission).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		var codes []model.UserRoleEnum
+		for code, permissions := range model.UserRolePermissionSet {
+			if slices.Contains(permissions, permission.Code) {
+				codes = append(codes, code)
+			}
+		}
+		var roles []*model.UserRole
+		if err := tx.Where("code IN ?", codes).Find(&roles).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		for _, role := range roles {
+			if err := tx.Model(role).Association("Permissions").Append(&permission); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
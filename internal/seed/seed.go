@@ -2,15 +2,20 @@ package seed
 
 import (
 	"super-web-server/pkg/database"
+	"super-web-server/pkg/password"
 	"super-web-server/pkg/snowflake"
 )
 
-func Seed(db *database.DB, snowflake *snowflake.Snowflake) error {
+func Seed(db *database.DB, snowflake *snowflake.Snowflake, hasher *password.Hasher, adminPassword string) error {
 	if err := SeedUserRole(db); err != nil {
 		return err
 	}
 
-	if err := SeedUser(db, snowflake); err != nil {
+	if err := SeedPermission(db); err != nil {
+		return err
+	}
+
+	if err := SeedUser(db, snowflake, hasher, adminPassword); err != nil {
 		return err
 	}
 
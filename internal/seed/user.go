@@ -1,17 +1,26 @@
 package seed
 
 import (
+	"fmt"
+	"os"
 	"super-web-server/internal/model"
 	"super-web-server/pkg/database"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/password"
 	"super-web-server/pkg/snowflake"
 	"super-web-server/pkg/utils"
+	"time"
+
+	"go.uber.org/zap"
 )
 
-func SeedUser(db *database.DB, snowflake *snowflake.Snowflake) error {
+// SeedUser creates the super admin on the first start. Without a configured
+// password a random one is generated and printed to stdout, never to the log
+// which may be shipped elsewhere and kept.
+func SeedUser(db *database.DB, snowflake *snowflake.Snowflake, hasher *password.Hasher, adminPassword string) error {
 	tx := db.Begin()
 
 	const adminEmail = "admin@example.com"
-	const adminPassword = "123456"
 
 	adminRole := model.UserRole{}
 	if err := tx.Model(&model.UserRole{}).Where("code = ?", model.UserRoleCodeSuperAdmin).First(&adminRole).Error; err != nil {
@@ -30,13 +39,18 @@ func SeedUser(db *database.DB, snowflake *snowflake.Snowflake) error {
 	}
 
 	uniqueID := snowflake.GenerateID()
+	now := time.Now()
 
-	salt, err := utils.GenerateSalt(6)
-	if err != nil {
-		tx.Rollback()
-		return err
+	generated := adminPassword == ""
+	if generated {
+		var err error
+		if adminPassword, err = utils.GenerateSecureToken(18); err != nil {
+			tx.Rollback()
+			return err
+		}
 	}
-	hashedPassword, err := utils.CryptHash(adminPassword, salt)
+
+	hashedPassword, err := hasher.Hash(adminPassword)
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -46,8 +60,9 @@ func SeedUser(db *database.DB, snowflake *snowflake.Snowflake) error {
 		UniqueID: uniqueID,
 		Email:    adminEmail,
 		Password: hashedPassword,
-		Salt:     salt,
 		Roles:    []*model.UserRole{&adminRole},
+
+		EmailVerifiedAt: &now,
 	}
 
 	if err := tx.Create(&adminUser).Error; err != nil {
@@ -59,5 +74,11 @@ func SeedUser(db *database.DB, snowflake *snowflake.Snowflake) error {
 		tx.Rollback()
 		return err
 	}
+
+	logger.Warn("seeded super admin, change its password after the first login",
+		zap.String("email", adminEmail), zap.Bool("generatedPassword", generated))
+	if generated {
+		fmt.Fprintf(os.Stdout, "super admin %s created with password %s, it is not shown again\n", adminEmail, adminPassword)
+	}
 	return nil
 }
@@ -3,20 +3,30 @@ package seed
 import (
 	"super-web-server/internal/model"
 	"super-web-server/pkg/database"
+
+	"gorm.io/gorm"
 )
 
 func SeedUserRole(db *database.DB) error {
 	tx := db.Begin()
+	var created []model.UserRoleEnum
 	for _, role := range model.UserRoleSet {
 		var count int64
 		tx.Model(&model.UserRole{}).Where("code = ?", role.Code).Count(&count)
 		if count > 0 {
 			continue
 		}
+		role.System = true
 		if err := tx.Create(&role).Error; err != nil {
 			tx.Rollback()
 			return err
 		}
+		created = append(created, role.Code)
+	}
+
+	if err := LinkUserRoleParents(tx, created); err != nil {
+		tx.Rollback()
+		return err
 	}
 
 	if err := tx.Commit().Error; err != nil {
@@ -26,3 +36,32 @@ func SeedUserRole(db *database.DB) error {
 
 	return nil
 }
+
+// LinkUserRoleParents gives the roles of codes the parents of the default
+// hierarchy, only roles that exist are linked.
+func LinkUserRoleParents(tx *gorm.DB, codes []model.UserRoleEnum) error {
+	for _, code := range codes {
+		parentCodes := model.UserRoleParentSet[code]
+		if len(parentCodes) == 0 {
+			continue
+		}
+		var role model.UserRole
+		if err := tx.Where("code = ?", code).Limit(1).Find(&role).Error; err != nil {
+			return err
+		}
+		if role.ID == 0 {
+			continue
+		}
+		var parents []*model.UserRole
+		if err := tx.Where("code IN ?", parentCodes).Find(&parents).Error; err != nil {
+			return err
+		}
+		if len(parents) == 0 {
+			continue
+		}
+		if err := tx.Model(&role).Association("Parents").Append(parents); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,565 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"super-web-server/internal/config"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/mailer"
+	"super-web-server/pkg/password"
+	"super-web-server/pkg/signurl"
+	"super-web-server/pkg/snowflake"
+	"super-web-server/pkg/storage"
+	"super-web-server/pkg/utils"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AccountService serves the data subject requests of a user: a copy of their
+// data and the deletion of their account.
+type AccountService interface {
+	RequestDataExport(ctx context.Context, userUniqueID int64) (*model.UserDataExport, *exception.Exception)
+	ListDataExports(ctx context.Context, userUniqueID int64, data dto.UserDataExportListReqDTO) ([]*dto.UserDataExportResDTO, int64, *exception.Exception)
+	GetDataExport(ctx context.Context, userUniqueID int64, uniqueID int64) (*dto.UserDataExportResDTO, *exception.Exception)
+	OpenSignedDataExport(ctx context.Context, uniqueID int64, query url.Values) (*model.UserDataExport, *storage.Reader, *exception.Exception)
+	RequestDeletion(ctx context.Context, userUniqueID int64, sessionID string, data dto.AccountDeleteReqDTO) (*dto.AccountDeleteResDTO, *exception.Exception)
+	RestoreAccount(ctx context.Context, data dto.AccountRestoreReqDTO) *exception.Exception
+
+	// run periodically by the cron
+	PurgeDeletedAccounts(ctx context.Context) error
+	CleanupDataExports(ctx context.Context) error
+
+	// Stop cancels the exports still being built and waits for them
+	Stop()
+}
+
+// accountBatchSize caps the rows a single cron run works through.
+const accountBatchSize = 100
+
+type accountService struct {
+	userRepo         repo.UserRepo
+	userSessionRepo  repo.UserSessionRepo
+	userIdentityRepo repo.UserIdentityRepo
+	apiKeyRepo       repo.APIKeyRepo
+	fileRepo         repo.FileRepo
+	exportRepo       repo.UserDataExportRepo
+	tokenService     TokenService
+	logger           *logger.Logger
+	redis            *redis.Client
+	snowflake        *snowflake.Snowflake
+	mailer           mailer.Mailer
+	hasher           *password.Hasher
+	storage          storage.Storage
+	signer           *signurl.Signer
+	config           *config.Config
+	statusCache      *userStatusCache
+	roleCache        *userRoleCache
+	reauth           *reauthenticator
+
+	// exports are built in ctx, Stop cancels it
+	ctx     context.Context
+	stop    context.CancelFunc
+	exports sync.WaitGroup
+}
+
+func NewAccountService(userRepo repo.UserRepo, userSessionRepo repo.UserSessionRepo, userIdentityRepo repo.UserIdentityRepo, apiKeyRepo repo.APIKeyRepo, fileRepo repo.FileRepo, exportRepo repo.UserDataExportRepo, tokenService TokenService, roleCache *userRoleCache, logger *logger.Logger, redis *redis.Client, snowflake *snowflake.Snowflake, mailer mailer.Mailer, hasher *password.Hasher, storage storage.Storage, signer *signurl.Signer, config *config.Config) AccountService {
+	logger.Info("NewAccountService initialized successfully")
+	ctx, stop := context.WithCancel(context.Background())
+	return &accountService{
+		userRepo:         userRepo,
+		userSessionRepo:  userSessionRepo,
+		userIdentityRepo: userIdentityRepo,
+		apiKeyRepo:       apiKeyRepo,
+		fileRepo:         fileRepo,
+		exportRepo:       exportRepo,
+		tokenService:     tokenService,
+		logger:           logger,
+		redis:            redis,
+		snowflake:        snowflake,
+		mailer:           mailer,
+		hasher:           hasher,
+		storage:          storage,
+		signer:           signer,
+		config:           config,
+		statusCache:      newUserStatusCache(userRepo, redis, logger),
+		roleCache:        roleCache,
+		reauth:           newReauthenticator(userSessionRepo, newVerifyCodeStore(redis, config.VerifyCode), hasher, mailer, logger, config.Reauth.MaxAge, config.VerifyCode.Expire, config.VerifyCode.ResendInterval),
+		ctx:              ctx,
+		stop:             stop,
+	}
+}
+
+func (s *accountService) findUser(ctx context.Context, userUniqueID int64) (*model.User, *exception.Exception) {
+	user, err := s.userRepo.FindByUniqueID(ctx, userUniqueID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, exception.ExceptionUserNotFound
+	} else if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	return user, nil
+}
+
+// signedExportResource is what a signed export link authorizes.
+func signedExportResource(uniqueID int64) string {
+	return fmt.Sprintf("export:%d", uniqueID)
+}
+
+func (s *accountService) exportLimitKey(userUniqueID int64) string {
+	return fmt.Sprintf("user:data-export:limit:%d", userUniqueID)
+}
+
+func (s *accountService) exportStorageKey(userUniqueID, uniqueID int64) string {
+	return fmt.Sprintf("exports/%d/%d.zip", userUniqueID, uniqueID)
+}
+
+// exportURL signs a link to a ready export that works until the export
+// expires.
+func (s *accountService) exportURL(export *model.UserDataExport) string {
+	query := s.signer.Sign(signedExportResource(export.UniqueID), signurl.Options{ExpireAt: *export.ExpiresAt})
+	return fmt.Sprintf("%s/%d?%s", strings.TrimSuffix(s.config.Account.ExportURL, "/"), export.UniqueID, query.Encode())
+}
+
+func (s *accountService) toExportResDTO(export *model.UserDataExport) *dto.UserDataExportResDTO {
+	res := &dto.UserDataExportResDTO{UserDataExport: export}
+	if export.Status == model.UserDataExportStatusReady && export.ExpiresAt != nil {
+		res.URL = s.exportURL(export)
+	}
+	return res
+}
+
+// RequestDataExport starts building the archive in the background, the user
+// is mailed a link once it is ready. Only one export runs per user at a time
+// and a user starts at most one every ExportInterval.
+func (s *accountService) RequestDataExport(ctx context.Context, userUniqueID int64) (*model.UserDataExport, *exception.Exception) {
+	user, ex := s.findUser(ctx, userUniqueID)
+	if ex != nil {
+		return nil, ex
+	}
+
+	limitKey := s.exportLimitKey(userUniqueID)
+	if interval := s.config.Account.ExportInterval; interval > 0 {
+		ok, err := s.redis.SetNX(ctx, limitKey, time.Now().UnixMilli(), interval).Result()
+		if err != nil {
+			return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+		}
+		if !ok {
+			return nil, exception.ExceptionDataExportTooFrequent
+		}
+	}
+
+	// a pending export also expires, so a failed one is cleaned up as well
+	expiresAt := time.Now().Add(s.config.Account.ExportTimeout + s.config.Account.ExportExpire)
+	export := &model.UserDataExport{
+		UniqueID:     s.snowflake.GenerateID(),
+		UserUniqueID: userUniqueID,
+		Status:       model.UserDataExportStatusPending,
+		ExpiresAt:    &expiresAt,
+		// taken by the pending export of the user, if any
+		PendingUserUniqueID: &userUniqueID,
+	}
+	if err := s.exportRepo.Create(ctx, export); err != nil {
+		// only started exports count against the interval
+		s.redis.Del(context.WithoutCancel(ctx), limitKey)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, exception.ExceptionDataExportInProgress
+		}
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	s.exports.Add(1)
+	go func() {
+		defer s.exports.Done()
+		s.runDataExport(export, user.Email)
+	}()
+
+	return export, nil
+}
+
+// runDataExport outlives the request that started it, but not Stop.
+func (s *accountService) runDataExport(export *model.UserDataExport, email string) {
+	ctx, cancel := context.WithTimeout(s.ctx, s.config.Account.ExportTimeout)
+	defer cancel()
+
+	if err := s.buildDataExport(ctx, export); err != nil {
+		s.logger.Error("Failed to build data export", zap.Int64("uniqueId", export.UniqueID), zap.Int64("userUniqueId", export.UserUniqueID), zap.Error(err))
+		// the build may have failed because ctx timed out or was cancelled
+		updates := map[string]any{"status": model.UserDataExportStatusFailed, "pending_user_unique_id": nil}
+		if err := s.exportRepo.UpdateByMap(context.WithoutCancel(ctx), export.ID, updates); err != nil {
+			s.logger.Error("Failed to mark data export as failed", zap.Int64("uniqueId", export.UniqueID), zap.Error(err))
+		}
+		return
+	}
+	s.logger.Info("data export ready", zap.Int64("uniqueId", export.UniqueID), zap.Int64("userUniqueId", export.UserUniqueID), zap.Int64("size", export.Size))
+
+	if err := s.mailer.Send(ctx, newDataExportReadyMail(email, s.exportURL(export), *export.ExpiresAt)); err != nil {
+		s.logger.Error("Failed to send data export mail", zap.String("email", email), zap.Error(err))
+	}
+}
+
+// buildDataExport writes everything keyed to the user into a ZIP archive:
+// data.json with the profile, roles, sessions (the login history), external
+// identities, API keys and file metadata, and the content of every file.
+// Secrets (password and key hashes, TOTP secrets) are never exported.
+func (s *accountService) buildDataExport(ctx context.Context, export *model.UserDataExport) error {
+	userUniqueID := export.UserUniqueID
+
+	user, err := s.userRepo.FindByUniqueID(ctx, userUniqueID)
+	if err != nil {
+		return err
+	}
+	sessions, err := s.userSessionRepo.FindMany(ctx, repo.Where("user_unique_id = ?", userUniqueID), repo.Order("id"))
+	if err != nil {
+		return err
+	}
+	identities, err := s.userIdentityRepo.FindMany(ctx, repo.Where("user_unique_id = ?", userUniqueID), repo.Order("id"))
+	if err != nil {
+		return err
+	}
+	apiKeys, err := s.apiKeyRepo.FindMany(ctx, repo.Where("owner_unique_id = ?", userUniqueID), repo.Order("id"))
+	if err != nil {
+		return err
+	}
+	files, err := s.fileRepo.FindMany(ctx, repo.Where("owner_unique_id = ?", userUniqueID), repo.Order("id"))
+	if err != nil {
+		return err
+	}
+
+	// the archive can be large, it is built on disk rather than in memory
+	tmp, err := os.CreateTemp("", "data-export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	archive := zip.NewWriter(tmp)
+	writer, err := archive.Create("data.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(&dto.UserDataArchiveDTO{
+		ExportedAt: time.Now(),
+		Profile:    user,
+		Sessions:   sessions,
+		Identities: identities,
+		APIKeys:    apiKeys,
+		Files:      files,
+	}); err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := s.archiveFile(ctx, archive, file); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	key := s.exportStorageKey(userUniqueID, export.UniqueID)
+	if _, err := s.storage.Put(ctx, key, tmp, size, storage.PutOptions{ContentType: "application/zip"}); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(s.config.Account.ExportExpire)
+	updates := map[string]any{
+		"status":                 model.UserDataExportStatusReady,
+		"storage_key":            key,
+		"size":                   size,
+		"expires_at":             expiresAt,
+		"pending_user_unique_id": nil,
+	}
+	if err := s.exportRepo.UpdateByMap(ctx, export.ID, updates); err != nil {
+		s.storage.Delete(ctx, key)
+		return err
+	}
+	export.Status, export.StorageKey, export.Size, export.ExpiresAt, export.PendingUserUniqueID = model.UserDataExportStatusReady, key, size, &expiresAt, nil
+	return nil
+}
+
+// archiveFile adds the content of file under files/, prefixed with its
+// unique id as names are not unique. A file whose object is gone is skipped.
+func (s *accountService) archiveFile(ctx context.Context, archive *zip.Writer, file *model.File) error {
+	body, err := s.storage.Get(ctx, file.StorageKey, 0, -1)
+	if errors.Is(err, storage.ErrNotFound) {
+		s.logger.Warn("Skipped missing file in data export", zap.Int64("fileUniqueId", file.UniqueID))
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	header := &zip.FileHeader{
+		Name:   path.Join("files", strconv.FormatInt(file.UniqueID, 10)+"-"+cleanFileName(file.Name)),
+		Method: zip.Deflate,
+	}
+	if file.CreatedAt != nil {
+		header.Modified = *file.CreatedAt
+	}
+	writer, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(writer, body)
+	return err
+}
+
+func (s *accountService) ListDataExports(ctx context.Context, userUniqueID int64, data dto.UserDataExportListReqDTO) ([]*dto.UserDataExportResDTO, int64, *exception.Exception) {
+	list, total, err := s.exportRepo.FindPage(ctx, data.Pagination,
+		repo.Where("user_unique_id = ?", userUniqueID),
+		repo.Order("id DESC"),
+	)
+	if err != nil {
+		return nil, 0, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	res := make([]*dto.UserDataExportResDTO, 0, len(list))
+	for _, export := range list {
+		res = append(res, s.toExportResDTO(export))
+	}
+	return res, total, nil
+}
+
+// GetDataExport only finds exports of the user, somebody else's export is
+// reported as missing.
+func (s *accountService) GetDataExport(ctx context.Context, userUniqueID int64, uniqueID int64) (*dto.UserDataExportResDTO, *exception.Exception) {
+	export, err := s.exportRepo.FindByUniqueID(ctx, uniqueID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, exception.ExceptionDataExportNotFound
+	} else if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	if export.UserUniqueID != userUniqueID {
+		return nil, exception.ExceptionDataExportNotFound
+	}
+	return s.toExportResDTO(export), nil
+}
+
+// OpenSignedDataExport serves a link minted for a ready export, the
+// signature replaces the owner check.
+func (s *accountService) OpenSignedDataExport(ctx context.Context, uniqueID int64, query url.Values) (*model.UserDataExport, *storage.Reader, *exception.Exception) {
+	if _, err := s.signer.Verify(signedExportResource(uniqueID), query, "", time.Now()); errors.Is(err, signurl.ErrExpired) {
+		return nil, nil, exception.ExceptionSignedURLExpired
+	} else if err != nil {
+		return nil, nil, exception.ExceptionSignedURLInvalid
+	}
+
+	export, err := s.exportRepo.FindByUniqueID(ctx, uniqueID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, exception.ExceptionDataExportNotFound
+	} else if err != nil {
+		return nil, nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	if export.Status != model.UserDataExportStatusReady {
+		return nil, nil, exception.ExceptionDataExportNotReady
+	}
+	return export, storage.NewReader(ctx, s.storage, export.StorageKey, export.Size), nil
+}
+
+func (s *accountService) accountRestoreKey(token string) string {
+	return fmt.Sprintf("user:account:restore:%s", utils.SHA256Hex(token))
+}
+
+// RequestDeletion soft deletes the account right away, every session ends
+// and the user can no longer sign in. The personal data is only anonymized
+// once the grace period has passed, until then the link mailed to the user
+// restores the account.
+func (s *accountService) RequestDeletion(ctx context.Context, userUniqueID int64, sessionID string, data dto.AccountDeleteReqDTO) (*dto.AccountDeleteResDTO, *exception.Exception) {
+	user, ex := s.findUser(ctx, userUniqueID)
+	if ex != nil {
+		return nil, ex
+	}
+
+	if ex := s.reauth.Check(ctx, user, sessionID, data.Password, data.Code); ex != nil {
+		return nil, ex
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+
+	gracePeriod := s.config.Account.DeletionGracePeriod
+	now := time.Now()
+	purgeAt := now.Add(gracePeriod)
+	if err := s.userRepo.UpdateByMap(ctx, user.ID, map[string]any{"deletion_requested_at": now, "deleted_at": now}); err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	s.statusCache.Invalidate(ctx, userUniqueID)
+	s.roleCache.InvalidateUser(ctx, userUniqueID)
+	s.logger.Info("account deletion requested", zap.Int64("userUniqueId", userUniqueID), zap.Time("purgeAt", purgeAt))
+
+	if ex := s.tokenService.RevokeUserTokens(ctx, userUniqueID); ex != nil {
+		return nil, ex
+	}
+
+	// the deletion stands even if the restore link cannot be sent
+	if err := s.redis.Set(ctx, s.accountRestoreKey(token), userUniqueID, gracePeriod).Err(); err != nil {
+		s.logger.Error("Failed to store account restore token", zap.Int64("userUniqueId", userUniqueID), zap.Error(err))
+	} else if err := s.mailer.Send(ctx, newAccountDeletionMail(user.Email, fmt.Sprintf(s.config.Account.RestoreURL, token), purgeAt)); err != nil {
+		s.logger.Error("Failed to send account deletion mail", zap.String("email", user.Email), zap.Error(err))
+	}
+
+	return &dto.AccountDeleteResDTO{PurgeAt: purgeAt.UnixMilli()}, nil
+}
+
+// RestoreAccount undoes a deletion within the grace period.
+func (s *accountService) RestoreAccount(ctx context.Context, data dto.AccountRestoreReqDTO) *exception.Exception {
+	// GETDEL makes the link single-use even under concurrent requests
+	value, err := s.redis.GetDel(ctx, s.accountRestoreKey(data.Token)).Result()
+	if err == redis.Nil {
+		return exception.ExceptionAccountRestoreInvalid
+	} else if err != nil {
+		return exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	uniqueID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return exception.ExceptionAccountRestoreInvalid
+	}
+
+	user, err := s.userRepo.FindByUniqueIDUnscoped(ctx, uniqueID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return exception.ExceptionAccountRestoreInvalid
+	} else if err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	if !user.DeletedAt.Valid || user.DeletionRequestedAt == nil || user.AnonymizedAt != nil {
+		return exception.ExceptionAccountRestoreInvalid
+	}
+
+	if err := s.userRepo.Restore(ctx, user.ID); err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	s.statusCache.Invalidate(ctx, uniqueID)
+	s.roleCache.InvalidateUser(ctx, uniqueID)
+
+	s.logger.Info("account restored", zap.Int64("userUniqueId", uniqueID))
+	return nil
+}
+
+// PurgeDeletedAccounts anonymizes the accounts whose grace period has
+// passed. The rows stay, other tables reference them, but nothing in them
+// identifies the person any more. Files, exports and the avatar are deleted.
+func (s *accountService) PurgeDeletedAccounts(ctx context.Context) error {
+	users, err := s.userRepo.FindDeletionDue(ctx, time.Now().Add(-s.config.Account.DeletionGracePeriod), accountBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if err := s.anonymize(ctx, user); err != nil {
+			return fmt.Errorf("anonymize user %d: %w", user.UniqueID, err)
+		}
+		s.logger.Info("account anonymized", zap.Int64("userUniqueId", user.UniqueID))
+	}
+	return nil
+}
+
+func (s *accountService) anonymize(ctx context.Context, user *model.User) error {
+	// files deleted earlier still have their name in the row
+	files, err := s.fileRepo.FindMany(ctx, repo.Unscoped(), repo.Where("owner_unique_id = ?", user.UniqueID))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := s.storage.Delete(ctx, file.StorageKey); err != nil {
+			return err
+		}
+		if err := s.fileRepo.HardDelete(ctx, file.ID); err != nil {
+			return err
+		}
+	}
+
+	exports, err := s.exportRepo.FindMany(ctx, repo.Where("user_unique_id = ?", user.UniqueID))
+	if err != nil {
+		return err
+	}
+	for _, export := range exports {
+		if err := s.deleteDataExport(ctx, export); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	err = s.userRepo.Anonymize(ctx, user, map[string]any{
+		// the email stays unique and can never receive mail
+		"email":             fmt.Sprintf("deleted-%d@deleted.invalid", user.UniqueID),
+		"mobile":            nil,
+		"password":          "",
+		"nickname":          "",
+		"avatar_url":        "",
+		"email_verified_at": nil,
+		"totp_secret":       "",
+		"totp_enabled_at":   nil,
+		"status_reason":     "",
+		"anonymized_at":     now,
+	})
+	if err != nil {
+		return err
+	}
+	removeAvatar(s.config.Avatar, s.logger, user.AvatarURL)
+	s.statusCache.Invalidate(ctx, user.UniqueID)
+	return nil
+}
+
+func (s *accountService) deleteDataExport(ctx context.Context, export *model.UserDataExport) error {
+	if export.StorageKey != "" {
+		if err := s.storage.Delete(ctx, export.StorageKey); err != nil {
+			return err
+		}
+	}
+	return s.exportRepo.HardDelete(ctx, export.ID)
+}
+
+// CleanupDataExports fails exports that never finished and deletes the
+// expired ones.
+func (s *accountService) CleanupDataExports(ctx context.Context) error {
+	now := time.Now()
+	failed, err := s.exportRepo.FailStale(ctx, now.Add(-s.config.Account.ExportTimeout))
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		s.logger.Warn("data exports timed out", zap.Int64("count", failed))
+	}
+
+	exports, err := s.exportRepo.FindExpired(ctx, now, accountBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, export := range exports {
+		if err := s.deleteDataExport(ctx, export); err != nil {
+			return fmt.Errorf("delete data export %d: %w", export.UniqueID, err)
+		}
+	}
+	return nil
+}
+
+func (s *accountService) Stop() {
+	s.stop()
+	s.exports.Wait()
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"super-web-server/internal/config"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/snowflake"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeUserDataExportRepo enforces the unique index on the pending user like
+// the database does.
+type fakeUserDataExportRepo struct {
+	repo.UserDataExportRepo
+	mu      sync.Mutex
+	exports []*model.UserDataExport
+	updates []map[string]any
+}
+
+func (r *fakeUserDataExportRepo) Create(ctx context.Context, entity *model.UserDataExport) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, export := range r.exports {
+		if entity.PendingUserUniqueID != nil && export.PendingUserUniqueID != nil && *export.PendingUserUniqueID == *entity.PendingUserUniqueID {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	entity.ID = uint64(len(r.exports) + 1)
+	r.exports = append(r.exports, entity)
+	return nil
+}
+
+func (r *fakeUserDataExportRepo) UpdateByMap(ctx context.Context, id uint64, data map[string]any) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.updates = append(r.updates, data)
+	return nil
+}
+
+// blockingSessionRepo holds the export build until its context ends.
+type blockingSessionRepo struct {
+	repo.UserSessionRepo
+	started chan struct{}
+}
+
+func (r *blockingSessionRepo) FindMany(ctx context.Context, opts ...repo.QueryOption) ([]*model.UserSession, error) {
+	r.started <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func newTestAccountService(t *testing.T, interval time.Duration) (*accountService, *fakeUserDataExportRepo, *blockingSessionRepo) {
+	t.Helper()
+	node, err := snowflake.NewSnowflake(1)
+	if err != nil {
+		t.Fatalf("NewSnowflake: %v", err)
+	}
+	rdb, _ := newTestRedis(t)
+	exports := &fakeUserDataExportRepo{}
+	sessions := &blockingSessionRepo{started: make(chan struct{}, 8)}
+	ctx, stop := context.WithCancel(context.Background())
+	s := &accountService{
+		userRepo:        &fakeUserRepo{users: []*model.User{{UniqueID: 1, Email: "a@example.com"}, {UniqueID: 2, Email: "b@example.com"}}},
+		userSessionRepo: sessions,
+		exportRepo:      exports,
+		logger:          newTestLogger(),
+		redis:           rdb,
+		snowflake:       node,
+		config:          &config.Config{Account: config.AccountConfig{ExportTimeout: time.Hour, ExportExpire: time.Hour, ExportInterval: interval}},
+		ctx:             ctx,
+		stop:            stop,
+	}
+	t.Cleanup(s.Stop)
+	return s, exports, sessions
+}
+
+func TestRequestDataExportOnePendingPerUser(t *testing.T) {
+	s, exports, sessions := newTestAccountService(t, 0)
+
+	var wg sync.WaitGroup
+	results := make([]*exception.Exception, 4)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, results[i] = s.RequestDataExport(context.Background(), 1)
+		}()
+	}
+	wg.Wait()
+	<-sessions.started
+
+	started := 0
+	for _, ex := range results {
+		if ex == nil {
+			started++
+		} else if !ex.Is(exception.ExceptionDataExportInProgress) {
+			t.Errorf("RequestDataExport = %v, want %v", ex, exception.ExceptionDataExportInProgress)
+		}
+	}
+	if started != 1 || len(exports.exports) != 1 {
+		t.Fatalf("started %d exports, stored %d, want 1", started, len(exports.exports))
+	}
+
+	// another user is not held up
+	if _, ex := s.RequestDataExport(context.Background(), 2); ex != nil {
+		t.Fatalf("RequestDataExport of another user: %v", ex)
+	}
+	<-sessions.started
+}
+
+func TestRequestDataExportRateLimit(t *testing.T) {
+	s, exports, sessions := newTestAccountService(t, time.Hour)
+
+	if _, ex := s.RequestDataExport(context.Background(), 1); ex != nil {
+		t.Fatalf("RequestDataExport: %v", ex)
+	}
+	<-sessions.started
+	// the first export is over, the interval still holds
+	exports.exports[0].PendingUserUniqueID = nil
+	if _, ex := s.RequestDataExport(context.Background(), 1); ex == nil || !ex.Is(exception.ExceptionDataExportTooFrequent) {
+		t.Fatalf("RequestDataExport = %v, want %v", ex, exception.ExceptionDataExportTooFrequent)
+	}
+}
+
+func TestRequestDataExportConflictKeepsInterval(t *testing.T) {
+	s, exports, _ := newTestAccountService(t, time.Hour)
+	pending := int64(1)
+	exports.exports = append(exports.exports, &model.UserDataExport{UserUniqueID: 1, PendingUserUniqueID: &pending})
+
+	if _, ex := s.RequestDataExport(context.Background(), 1); ex == nil || !ex.Is(exception.ExceptionDataExportInProgress) {
+		t.Fatalf("RequestDataExport = %v, want %v", ex, exception.ExceptionDataExportInProgress)
+	}
+	// the refused request did not use up the interval
+	if n, err := s.redis.Exists(context.Background(), s.exportLimitKey(1)).Result(); err != nil || n != 0 {
+		t.Errorf("limit key exists = %d, %v, want it released", n, err)
+	}
+}
+
+func TestStopFailsRunningExports(t *testing.T) {
+	s, exports, sessions := newTestAccountService(t, 0)
+	if _, ex := s.RequestDataExport(context.Background(), 1); ex != nil {
+		t.Fatalf("RequestDataExport: %v", ex)
+	}
+	<-sessions.started
+
+	done := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop did not wait for the export to return")
+	}
+
+	if len(exports.updates) != 1 {
+		t.Fatalf("updates = %v, want the export marked failed", exports.updates)
+	}
+	update := exports.updates[0]
+	pending, cleared := update["pending_user_unique_id"]
+	if update["status"] != model.UserDataExportStatusFailed || !cleared || pending != nil {
+		t.Errorf("update = %v, want failed and no longer pending", update)
+	}
+}
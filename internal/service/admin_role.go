@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/logger"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type AdminRoleService interface {
+	List(ctx context.Context) ([]*model.UserRole, *exception.Exception)
+	Get(ctx context.Context, code model.UserRoleEnum) (*model.UserRole, *exception.Exception)
+	Create(ctx context.Context, actorUniqueID int64, data dto.AdminRoleCreateReqDTO) (*model.UserRole, *exception.Exception)
+	Update(ctx context.Context, actorUniqueID int64, code model.UserRoleEnum, data dto.AdminRoleUpdateReqDTO) (*model.UserRole, *exception.Exception)
+	Delete(ctx context.Context, actorUniqueID int64, code model.UserRoleEnum) *exception.Exception
+	ListPermissions(ctx context.Context) ([]*model.Permission, *exception.Exception)
+}
+
+// userRoleCodePattern keeps role codes usable as path params.
+var userRoleCodePattern = regexp.MustCompile(`^role:[a-z0-9_]{1,59}$`)
+
+type adminRoleService struct {
+	userRepo       repo.UserRepo
+	userRoleRepo   repo.UserRoleRepo
+	permissionRepo repo.PermissionRepo
+	roleCache      *userRoleCache
+	logger         *logger.Logger
+	roles          *userRoleResolver
+}
+
+func NewAdminRoleService(userRepo repo.UserRepo, userRoleRepo repo.UserRoleRepo, permissionRepo repo.PermissionRepo, roleCache *userRoleCache, logger *logger.Logger) AdminRoleService {
+	logger.Info("NewAdminRoleService initialized successfully")
+	return &adminRoleService{
+		userRepo:       userRepo,
+		userRoleRepo:   userRoleRepo,
+		permissionRepo: permissionRepo,
+		roleCache:      roleCache,
+		logger:         logger,
+		roles:          newUserRoleResolver(userRoleRepo),
+	}
+}
+
+func (s *adminRoleService) List(ctx context.Context) ([]*model.UserRole, *exception.Exception) {
+	roles, err := s.userRoleRepo.FindMany(ctx, repo.Preload("Parents"), repo.Preload("Permissions"), repo.Order("id ASC"))
+	if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	return roles, nil
+}
+
+func (s *adminRoleService) Get(ctx context.Context, code model.UserRoleEnum) (*model.UserRole, *exception.Exception) {
+	role, err := s.userRoleRepo.FindOne(ctx, repo.Preload("Parents"), repo.Preload("Permissions"), repo.Where("code = ?", code))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, exception.ExceptionUserRoleNotFound.AppendDetails(string(code))
+	} else if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	return role, nil
+}
+
+// findParents never returns a nil slice, UpdateWithRefs takes nil for
+// unchanged.
+func (s *adminRoleService) findParents(ctx context.Context, codes []string) ([]*model.UserRole, *exception.Exception) {
+	if len(codes) == 0 {
+		return []*model.UserRole{}, nil
+	}
+	roleCodes := make([]model.UserRoleEnum, 0, len(codes))
+	for _, code := range codes {
+		roleCodes = append(roleCodes, model.UserRoleEnum(code))
+	}
+	roles, err := s.userRoleRepo.FindByCodes(ctx, roleCodes)
+	if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	for _, code := range roleCodes {
+		if !slices.ContainsFunc(roles, func(role *model.UserRole) bool { return role.Code == code }) {
+			return nil, exception.ExceptionUserRoleNotFound.AppendDetails(string(code))
+		}
+	}
+	return roles, nil
+}
+
+func (s *adminRoleService) findPermissions(ctx context.Context, codes []string) ([]*model.Permission, *exception.Exception) {
+	if len(codes) == 0 {
+		return []*model.Permission{}, nil
+	}
+	permissionCodes := make([]model.PermissionEnum, 0, len(codes))
+	for _, code := range codes {
+		permissionCodes = append(permissionCodes, model.PermissionEnum(code))
+	}
+	permissions, err := s.permissionRepo.FindByCodes(ctx, permissionCodes)
+	if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	for _, code := range permissionCodes {
+		if !slices.ContainsFunc(permissions, func(permission *model.Permission) bool { return permission.Code == code }) {
+			return nil, exception.ExceptionPermissionNotFound.AppendDetails(string(code))
+		}
+	}
+	return permissions, nil
+}
+
+// checkParents refuses parents that would make role inherit from itself, and
+// parents that make super admins unless the actor is one.
+func (s *adminRoleService) checkParents(ctx context.Context, actorUniqueID int64, role *model.UserRole, parents []*model.UserRole) *exception.Exception {
+	inherited, err := s.roles.Expand(ctx, parents)
+	if err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	if role != nil && slices.ContainsFunc(inherited, func(parent *model.UserRole) bool { return parent.ID == role.ID }) {
+		return exception.ExceptionUserRoleCycle.AppendDetails(string(role.Code))
+	}
+	if !hasUserRole(inherited, model.UserRoleCodeSuperAdmin) {
+		return nil
+	}
+
+	actor, err := s.userRepo.FindByUniqueID(ctx, actorUniqueID)
+	if err != nil {
+		return exception.ExceptionUserNotFound.AppendDetails(err.Error())
+	}
+	actorRoles, err := s.roles.Expand(ctx, actor.Roles)
+	if err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	if !hasUserRole(actorRoles, model.UserRoleCodeSuperAdmin) {
+		return exception.ExceptionForbidden.AppendDetails("only super admins can inherit from " + string(model.UserRoleCodeSuperAdmin))
+	}
+	return nil
+}
+
+// checkGrants refuses a role granting what the actor does not hold, its own
+// permissions and those of every role it inherits from.
+func (s *adminRoleService) checkGrants(ctx context.Context, actorUniqueID int64, parents []*model.UserRole, permissions []*model.Permission) *exception.Exception {
+	codes := make([]model.PermissionEnum, 0, len(permissions))
+	for _, permission := range permissions {
+		codes = append(codes, permission.Code)
+	}
+	return s.roleCache.CheckGrants(ctx, actorUniqueID, parents, codes)
+}
+
+// checkSystem keeps built-in roles from losing parents or permissions, the
+// application relies on what they grant.
+func (s *adminRoleService) checkSystem(role *model.UserRole, parents []*model.UserRole, permissions []*model.Permission) *exception.Exception {
+	if !role.System {
+		return nil
+	}
+	if parents != nil {
+		for _, parent := range role.Parents {
+			if !slices.ContainsFunc(parents, func(p *model.UserRole) bool { return p.ID == parent.ID }) {
+				return exception.ExceptionUserRoleSystem.AppendDetails("cannot remove parent " + string(parent.Code))
+			}
+		}
+	}
+	if permissions != nil {
+		for _, permission := range role.Permissions {
+			if !slices.ContainsFunc(permissions, func(p *model.Permission) bool { return p.Code == permission.Code }) {
+				return exception.ExceptionUserRoleSystem.AppendDetails("cannot remove permission " + string(permission.Code))
+			}
+		}
+	}
+	return nil
+}
+
+// Create adds a role, the code is what routes and clients refer to and
+// cannot be changed afterwards.
+func (s *adminRoleService) Create(ctx context.Context, actorUniqueID int64, data dto.AdminRoleCreateReqDTO) (*model.UserRole, *exception.Exception) {
+	code := model.UserRoleEnum(data.Code)
+	if !userRoleCodePattern.MatchString(data.Code) {
+		return nil, exception.ExceptionInvalidParam.AppendDetails("code must look like role:name, with lower case letters, digits and _")
+	}
+
+	parents, ex := s.findParents(ctx, data.Parents)
+	if ex != nil {
+		return nil, ex
+	}
+	if ex := s.checkParents(ctx, actorUniqueID, nil, parents); ex != nil {
+		return nil, ex
+	}
+	permissions, ex := s.findPermissions(ctx, data.Permissions)
+	if ex != nil {
+		return nil, ex
+	}
+	if ex := s.checkGrants(ctx, actorUniqueID, parents, permissions); ex != nil {
+		return nil, ex
+	}
+
+	role := &model.UserRole{
+		Code:        code,
+		Name:        data.Name,
+		Parents:     parents,
+		Permissions: permissions,
+	}
+	if err := s.userRoleRepo.Create(ctx, role); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, exception.ExceptionUserRoleExists.AppendDetails(data.Code)
+		}
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	s.logger.Info("admin created role", zap.String("role", data.Code), zap.Int64("actorUniqueId", actorUniqueID))
+	return s.Get(ctx, code)
+}
+
+// Update renames the role and replaces its parents or permissions, the cached
+// roles of every user are dropped since any of them may inherit the role.
+// Only an actor holding everything the role grants afterwards may change it.
+func (s *adminRoleService) Update(ctx context.Context, actorUniqueID int64, code model.UserRoleEnum, data dto.AdminRoleUpdateReqDTO) (*model.UserRole, *exception.Exception) {
+	role, ex := s.Get(ctx, code)
+	if ex != nil {
+		return nil, ex
+	}
+
+	updates := map[string]any{}
+	if data.Name != nil {
+		updates["name"] = *data.Name
+	}
+
+	var parents []*model.UserRole
+	if data.Parents != nil {
+		parents, ex = s.findParents(ctx, *data.Parents)
+		if ex != nil {
+			return nil, ex
+		}
+		if ex := s.checkParents(ctx, actorUniqueID, role, parents); ex != nil {
+			return nil, ex
+		}
+	}
+
+	var permissions []*model.Permission
+	if data.Permissions != nil {
+		permissions, ex = s.findPermissions(ctx, *data.Permissions)
+		if ex != nil {
+			return nil, ex
+		}
+	}
+
+	if ex := s.checkSystem(role, parents, permissions); ex != nil {
+		return nil, ex
+	}
+	grantedParents, grantedPermissions := role.Parents, role.Permissions
+	if parents != nil {
+		grantedParents = parents
+	}
+	if permissions != nil {
+		grantedPermissions = permissions
+	}
+	if ex := s.checkGrants(ctx, actorUniqueID, grantedParents, grantedPermissions); ex != nil {
+		return nil, ex
+	}
+
+	if err := s.userRoleRepo.UpdateWithRefs(ctx, role, updates, parents, permissions); err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	s.roleCache.InvalidateAll(ctx)
+
+	s.logger.Info("admin updated role",
+		zap.String("role", string(code)),
+		zap.Any("name", data.Name),
+		zap.Any("parents", data.Parents),
+		zap.Any("permissions", data.Permissions),
+		zap.Int64("actorUniqueId", actorUniqueID),
+	)
+	return s.Get(ctx, code)
+}
+
+// Delete removes a role nobody holds or inherits from, built-in roles are
+// kept.
+func (s *adminRoleService) Delete(ctx context.Context, actorUniqueID int64, code model.UserRoleEnum) *exception.Exception {
+	role, ex := s.Get(ctx, code)
+	if ex != nil {
+		return ex
+	}
+	if role.System {
+		return exception.ExceptionUserRoleSystem.AppendDetails(string(code))
+	}
+
+	users, err := s.userRoleRepo.CountUsers(ctx, role.ID)
+	if err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	if users > 0 {
+		return exception.ExceptionUserRoleInUse.AppendDetails("assigned to users")
+	}
+	children, err := s.userRoleRepo.CountChildren(ctx, role.ID)
+	if err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	if children > 0 {
+		return exception.ExceptionUserRoleInUse.AppendDetails("inherited by other roles")
+	}
+
+	if err := s.userRoleRepo.DeleteWithRefs(ctx, role); err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	s.roleCache.InvalidateAll(ctx)
+
+	s.logger.Info("admin deleted role", zap.String("role", string(code)), zap.Int64("actorUniqueId", actorUniqueID))
+	return nil
+}
+
+func (s *adminRoleService) ListPermissions(ctx context.Context) ([]*model.Permission, *exception.Exception) {
+	permissions, err := s.permissionRepo.FindMany(ctx, repo.Order("code ASC"))
+	if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	return permissions, nil
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"testing"
+)
+
+const testRoleAuditor model.UserRoleEnum = "role:auditor"
+
+// newTestAdminRoleService holds the built-in roles and an auditor role
+// granting impersonation, which only the super admin holds besides.
+func newTestAdminRoleService(t *testing.T) (*adminRoleService, *fakeUserRoleRepo) {
+	t.Helper()
+	permissions := func(codes ...model.PermissionEnum) []*model.Permission {
+		list := make([]*model.Permission, 0, len(codes))
+		for _, code := range codes {
+			list = append(list, &model.Permission{Code: code})
+		}
+		return list
+	}
+	user := &model.UserRole{BaseModel: model.BaseModel{ID: 3}, Code: model.UserRoleCodeUser, System: true,
+		Permissions: permissions(model.PermissionUserRead)}
+	admin := &model.UserRole{BaseModel: model.BaseModel{ID: 2}, Code: model.UserRoleCodeAdmin, System: true,
+		Parents: []*model.UserRole{user}, Permissions: permissions(model.PermissionAdminRoleWrite)}
+	superAdmin := &model.UserRole{BaseModel: model.BaseModel{ID: 1}, Code: model.UserRoleCodeSuperAdmin, System: true,
+		Parents: []*model.UserRole{admin}, Permissions: permissions(model.PermissionAdminUserImpersonate)}
+	auditor := &model.UserRole{BaseModel: model.BaseModel{ID: 4}, Code: testRoleAuditor,
+		Permissions: permissions(model.PermissionAdminUserImpersonate)}
+
+	users := &fakeUserRepo{users: []*model.User{
+		{UniqueID: testSuperAdmin, Roles: []*model.UserRole{superAdmin}},
+		{UniqueID: testAdmin, Roles: []*model.UserRole{admin}},
+	}}
+	roleRepo := &fakeUserRoleRepo{roles: []*model.UserRole{superAdmin, admin, user, auditor}}
+	permissionRepo := &fakePermissionRepo{granted: map[uint64][]model.PermissionEnum{
+		1: {model.PermissionAdminUserImpersonate},
+		2: {model.PermissionAdminRoleWrite},
+		3: {model.PermissionUserRead},
+		4: {model.PermissionAdminUserImpersonate},
+	}}
+	return &adminRoleService{
+		userRepo:       users,
+		userRoleRepo:   roleRepo,
+		permissionRepo: permissionRepo,
+		roleCache:      newTestRoleCache(t, users, roleRepo, permissionRepo),
+		logger:         newTestLogger(),
+		roles:          newUserRoleResolver(roleRepo),
+	}, roleRepo
+}
+
+func TestAdminRoleCreateChecksGrants(t *testing.T) {
+	tests := []struct {
+		name        string
+		actor       int64
+		parents     []string
+		permissions []string
+		want        *exception.Exception
+	}{
+		{"held permission", testAdmin, nil, []string{string(model.PermissionUserRead)}, nil},
+		{"inherited permission", testAdmin, nil, []string{string(model.PermissionUserRead), string(model.PermissionAdminRoleWrite)}, nil},
+		{"permission not held", testAdmin, nil, []string{string(model.PermissionAdminUserImpersonate)}, exception.ExceptionForbidden},
+		{"held parent", testAdmin, []string{string(model.UserRoleCodeUser)}, nil, nil},
+		{"parent granting more", testAdmin, []string{string(testRoleAuditor)}, nil, exception.ExceptionForbidden},
+		{"super admin parent", testAdmin, []string{string(model.UserRoleCodeSuperAdmin)}, nil, exception.ExceptionForbidden},
+		{"super admin grants anything", testSuperAdmin, []string{string(testRoleAuditor)}, []string{string(model.PermissionAdminRoleWrite)}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, roleRepo := newTestAdminRoleService(t)
+			role, ex := s.Create(context.Background(), tt.actor, dto.AdminRoleCreateReqDTO{
+				Code:        "role:new",
+				Name:        "new",
+				Parents:     tt.parents,
+				Permissions: tt.permissions,
+			})
+			if tt.want == nil {
+				if ex != nil {
+					t.Fatalf("Create: %v", ex)
+				}
+				if role.Code != "role:new" {
+					t.Errorf("Create() = %+v", role)
+				}
+				return
+			}
+			if ex == nil || !ex.Is(tt.want) {
+				t.Fatalf("Create = %v, want %v", ex, tt.want)
+			}
+			if len(roleRepo.roles) != 4 {
+				t.Errorf("roles = %d, want none created", len(roleRepo.roles))
+			}
+		})
+	}
+}
+
+func TestAdminRoleUpdateChecksGrants(t *testing.T) {
+	newName := "renamed"
+	list := func(codes ...string) *[]string { return &codes }
+	tests := []struct {
+		name  string
+		actor int64
+		role  model.UserRoleEnum
+		data  dto.AdminRoleUpdateReqDTO
+		want  *exception.Exception
+	}{
+		{"rename a held role", testAdmin, model.UserRoleCodeUser, dto.AdminRoleUpdateReqDTO{Name: &newName}, nil},
+		{"rename a role granting more", testAdmin, testRoleAuditor, dto.AdminRoleUpdateReqDTO{Name: &newName}, exception.ExceptionForbidden},
+		{"add a held permission", testAdmin, model.UserRoleCodeUser,
+			dto.AdminRoleUpdateReqDTO{Permissions: list(string(model.PermissionUserRead), string(model.PermissionAdminRoleWrite))}, nil},
+		{"add a permission not held", testAdmin, model.UserRoleCodeUser,
+			dto.AdminRoleUpdateReqDTO{Permissions: list(string(model.PermissionUserRead), string(model.PermissionAdminUserImpersonate))}, exception.ExceptionForbidden},
+		{"add a parent granting more", testAdmin, model.UserRoleCodeUser,
+			dto.AdminRoleUpdateReqDTO{Parents: list(string(testRoleAuditor))}, exception.ExceptionForbidden},
+		{"strip a custom role", testSuperAdmin, testRoleAuditor, dto.AdminRoleUpdateReqDTO{Permissions: list()}, nil},
+		{"strip a system role", testSuperAdmin, model.UserRoleCodeUser, dto.AdminRoleUpdateReqDTO{Permissions: list()}, exception.ExceptionUserRoleSystem},
+		{"replace a system permission", testSuperAdmin, model.UserRoleCodeUser,
+			dto.AdminRoleUpdateReqDTO{Permissions: list(string(model.PermissionAdminRoleWrite))}, exception.ExceptionUserRoleSystem},
+		{"drop a system parent", testSuperAdmin, model.UserRoleCodeAdmin, dto.AdminRoleUpdateReqDTO{Parents: list()}, exception.ExceptionUserRoleSystem},
+		{"extend a system role", testSuperAdmin, model.UserRoleCodeAdmin,
+			dto.AdminRoleUpdateReqDTO{Parents: list(string(model.UserRoleCodeUser), string(testRoleAuditor))}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, roleRepo := newTestAdminRoleService(t)
+			for _, role := range roleRepo.roles {
+				if role.Code == tt.role {
+					roleRepo.found = role
+				}
+			}
+
+			_, ex := s.Update(context.Background(), tt.actor, tt.role, tt.data)
+			if tt.want == nil {
+				if ex != nil {
+					t.Fatalf("Update: %v", ex)
+				}
+				if roleRepo.updates != 1 {
+					t.Errorf("updates = %d, want the role updated", roleRepo.updates)
+				}
+				return
+			}
+			if ex == nil || !ex.Is(tt.want) {
+				t.Fatalf("Update = %v, want %v", ex, tt.want)
+			}
+			if roleRepo.updates != 0 {
+				t.Errorf("updates = %d, want the role kept", roleRepo.updates)
+			}
+		})
+	}
+}
@@ -0,0 +1,526 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"super-web-server/internal/config"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/jwt"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/password"
+	"super-web-server/pkg/snowflake"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type AdminUserService interface {
+	List(ctx context.Context, data dto.AdminUserListReqDTO) ([]*dto.AdminUserResDTO, int64, *exception.Exception)
+	Get(ctx context.Context, uniqueID int64) (*dto.AdminUserResDTO, *exception.Exception)
+	Create(ctx context.Context, actorUniqueID int64, data dto.AdminUserCreateReqDTO) (*dto.AdminUserResDTO, *exception.Exception)
+	Update(ctx context.Context, actorUniqueID, uniqueID int64, data dto.AdminUserUpdateReqDTO) (*dto.AdminUserResDTO, *exception.Exception)
+	AssignRole(ctx context.Context, actorUniqueID, uniqueID int64, code model.UserRoleEnum) *exception.Exception
+	RemoveRole(ctx context.Context, actorUniqueID, uniqueID int64, code model.UserRoleEnum) *exception.Exception
+	SetStatus(ctx context.Context, actorUniqueID, uniqueID int64, data dto.AdminUserStatusReqDTO) *exception.Exception
+	Delete(ctx context.Context, actorUniqueID, uniqueID int64) *exception.Exception
+	Restore(ctx context.Context, actorUniqueID, uniqueID int64) *exception.Exception
+	Impersonate(ctx context.Context, actorUniqueID, uniqueID int64, data dto.AdminUserImpersonateReqDTO) (*dto.AdminUserImpersonateResDTO, *exception.Exception)
+	CheckImpersonation(ctx context.Context, claims *jwt.JWTClaims) *exception.Exception
+}
+
+// adminUserSortColumns maps the sort fields of the list request to columns.
+var adminUserSortColumns = map[string]string{
+	"id":        "id",
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+	"email":     "email",
+	"nickname":  "nickname",
+}
+
+type adminUserService struct {
+	userRepo     repo.UserRepo
+	userRoleRepo repo.UserRoleRepo
+	tokenService TokenService
+	logger       *logger.Logger
+	redis        *redis.Client
+	jwt          *jwt.JWT
+	snowflake    *snowflake.Snowflake
+	hasher       *password.Hasher
+	history      *passwordHistory
+	statusCache  *userStatusCache
+	roleCache    *userRoleCache
+	roles        *userRoleResolver
+}
+
+func NewAdminUserService(userRepo repo.UserRepo, userRoleRepo repo.UserRoleRepo, passwordHistoryRepo repo.UserPasswordHistoryRepo, tokenService TokenService, roleCache *userRoleCache, logger *logger.Logger, redis *redis.Client, jwt *jwt.JWT, snowflake *snowflake.Snowflake, hasher *password.Hasher, config *config.Config) AdminUserService {
+	logger.Info("NewAdminUserService initialized successfully")
+	return &adminUserService{
+		userRepo:     userRepo,
+		userRoleRepo: userRoleRepo,
+		tokenService: tokenService,
+		logger:       logger,
+		redis:        redis,
+		jwt:          jwt,
+		snowflake:    snowflake,
+		hasher:       hasher,
+		history:      newPasswordHistory(passwordHistoryRepo, hasher, logger, config.PasswordPolicy.History),
+		statusCache:  newUserStatusCache(userRepo, redis, logger),
+		roleCache:    roleCache,
+		roles:        newUserRoleResolver(userRoleRepo),
+	}
+}
+
+func (s *adminUserService) toResDTO(user *model.User) *dto.AdminUserResDTO {
+	res := &dto.AdminUserResDTO{User: user}
+	if user.DeletedAt.Valid {
+		res.DeletedAt = &user.DeletedAt.Time
+	}
+	return res
+}
+
+// containsPattern builds a LIKE pattern matching value anywhere, with the
+// wildcards in value escaped.
+func containsPattern(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+	return "%" + value + "%"
+}
+
+func (s *adminUserService) List(ctx context.Context, data dto.AdminUserListReqDTO) ([]*dto.AdminUserResDTO, int64, *exception.Exception) {
+	opts := []repo.QueryOption{repo.Preload("Roles")}
+	if data.Deleted {
+		opts = append(opts, repo.Unscoped(), repo.Where("deleted_at IS NOT NULL"))
+	}
+	if data.Email != "" {
+		opts = append(opts, repo.Where("email LIKE ?", containsPattern(data.Email)))
+	}
+	if data.Mobile != "" {
+		opts = append(opts, repo.Where("mobile LIKE ?", containsPattern(data.Mobile)))
+	}
+	if data.Nickname != "" {
+		opts = append(opts, repo.Where("nickname LIKE ?", containsPattern(data.Nickname)))
+	}
+	if data.Role != "" {
+		opts = append(opts, repo.Where(
+			"id IN (SELECT ref.user_id FROM user_role_ref ref JOIN user_roles r ON r.id = ref.user_role_id WHERE r.code = ?)",
+			data.Role,
+		))
+	}
+
+	column, ok := adminUserSortColumns[data.Sort]
+	if !ok {
+		column = "id"
+	}
+	direction := "DESC"
+	if data.Order == "asc" {
+		direction = "ASC"
+	}
+	opts = append(opts, repo.Order(column+" "+direction))
+
+	users, total, err := s.userRepo.FindPage(ctx, data.Pagination, opts...)
+	if err != nil {
+		return nil, 0, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	res := make([]*dto.AdminUserResDTO, 0, len(users))
+	for _, user := range users {
+		res = append(res, s.toResDTO(user))
+	}
+	return res, total, nil
+}
+
+func (s *adminUserService) Get(ctx context.Context, uniqueID int64) (*dto.AdminUserResDTO, *exception.Exception) {
+	user, ex := s.findUser(ctx, uniqueID, true)
+	if ex != nil {
+		return nil, ex
+	}
+	return s.toResDTO(user), nil
+}
+
+func (s *adminUserService) findUser(ctx context.Context, uniqueID int64, withDeleted bool) (*model.User, *exception.Exception) {
+	find := s.userRepo.FindByUniqueID
+	if withDeleted {
+		find = s.userRepo.FindByUniqueIDUnscoped
+	}
+	user, err := find(ctx, uniqueID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, exception.ExceptionUserNotFound
+	} else if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	return user, nil
+}
+
+func (s *adminUserService) findRoles(ctx context.Context, codes []model.UserRoleEnum) ([]*model.UserRole, *exception.Exception) {
+	roles, err := s.userRoleRepo.FindByCodes(ctx, codes)
+	if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	for _, code := range codes {
+		if !slices.ContainsFunc(roles, func(role *model.UserRole) bool { return role.Code == code }) {
+			return nil, exception.ExceptionUserRoleNotFound.AppendDetails(string(code))
+		}
+	}
+	return roles, nil
+}
+
+// isSuperAdmin follows inheritance, a role inheriting from the super admin
+// role makes a super admin as well.
+func (s *adminUserService) isSuperAdmin(ctx context.Context, roles []*model.UserRole) (bool, *exception.Exception) {
+	expanded, err := s.roles.Expand(ctx, roles)
+	if err != nil {
+		return false, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	return hasUserRole(expanded, model.UserRoleCodeSuperAdmin), nil
+}
+
+// checkActor makes sure the actor may manage target and tells whether the
+// actor is a super admin. Admins cannot act on themselves, so nobody locks
+// themselves out, and only super admins may manage super admins.
+func (s *adminUserService) checkActor(ctx context.Context, actorUniqueID int64, target *model.User) (bool, *exception.Exception) {
+	if target.UniqueID == actorUniqueID {
+		return false, exception.ExceptionForbidden.AppendDetails("cannot manage your own account here")
+	}
+	actor, ex := s.findUser(ctx, actorUniqueID, false)
+	if ex != nil {
+		return false, ex
+	}
+	actorIsSuperAdmin, ex := s.isSuperAdmin(ctx, actor.Roles)
+	if ex != nil {
+		return false, ex
+	}
+	targetIsSuperAdmin, ex := s.isSuperAdmin(ctx, target.Roles)
+	if ex != nil {
+		return false, ex
+	}
+	if targetIsSuperAdmin && !actorIsSuperAdmin {
+		return false, exception.ExceptionForbidden.AppendDetails("only super admins can manage super admins")
+	}
+	return actorIsSuperAdmin, nil
+}
+
+// checkGrant refuses roles granting permissions the actor does not hold,
+// inheritance followed, so admins cannot hand out more than they have.
+func (s *adminUserService) checkGrant(ctx context.Context, actorUniqueID int64, roles []*model.UserRole) *exception.Exception {
+	return s.roleCache.CheckGrants(ctx, actorUniqueID, roles, nil)
+}
+
+func (s *adminUserService) checkMobileFree(ctx context.Context, mobile string, uniqueID int64) *exception.Exception {
+	user, err := s.userRepo.FindByMobile(ctx, mobile)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	if user.UniqueID != uniqueID {
+		return exception.ExceptionUserMobileExists
+	}
+	return nil
+}
+
+func (s *adminUserService) Create(ctx context.Context, actorUniqueID int64, data dto.AdminUserCreateReqDTO) (*dto.AdminUserResDTO, *exception.Exception) {
+	codes := []model.UserRoleEnum{model.UserRoleCodeUser}
+	if len(data.Roles) > 0 {
+		codes = make([]model.UserRoleEnum, 0, len(data.Roles))
+		for _, code := range data.Roles {
+			codes = append(codes, model.UserRoleEnum(code))
+		}
+	}
+	roles, ex := s.findRoles(ctx, codes)
+	if ex != nil {
+		return nil, ex
+	}
+
+	if ex := s.checkGrant(ctx, actorUniqueID, roles); ex != nil {
+		return nil, ex
+	}
+
+	if data.Mobile != "" {
+		if ex := s.checkMobileFree(ctx, data.Mobile, 0); ex != nil {
+			return nil, ex
+		}
+	}
+
+	hashedPassword, err := s.hasher.Hash(data.Password)
+	if err != nil {
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+
+	user := &model.User{
+		UniqueID: s.snowflake.GenerateID(),
+		Email:    data.Email,
+		Mobile:   model.NullableString(data.Mobile),
+		Password: hashedPassword,
+		Nickname: data.Nickname,
+		Status:   model.UserStatusPending,
+		Roles:    roles,
+	}
+	if data.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		user.Status = model.UserStatusActive
+	}
+
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			// the mobile may have been taken since it was checked
+			if data.Mobile != "" {
+				if ex := s.checkMobileFree(ctx, data.Mobile, 0); ex != nil {
+					return nil, ex
+				}
+			}
+			return nil, exception.ExceptionUserEmailAlreadyExists
+		}
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	s.history.Record(ctx, user.UniqueID, hashedPassword)
+
+	s.logger.Info("admin created user", zap.Int64("uniqueId", user.UniqueID), zap.Int64("actorUniqueId", actorUniqueID))
+	return s.toResDTO(user), nil
+}
+
+func (s *adminUserService) Update(ctx context.Context, actorUniqueID, uniqueID int64, data dto.AdminUserUpdateReqDTO) (*dto.AdminUserResDTO, *exception.Exception) {
+	user, ex := s.findUser(ctx, uniqueID, false)
+	if ex != nil {
+		return nil, ex
+	}
+	// editing one's own profile is fine, the other checks still apply
+	if user.UniqueID != actorUniqueID {
+		if _, ex := s.checkActor(ctx, actorUniqueID, user); ex != nil {
+			return nil, ex
+		}
+	}
+
+	updates := map[string]any{}
+	if data.Nickname != nil {
+		updates["nickname"] = *data.Nickname
+	}
+	if data.Mobile != nil {
+		// an empty mobile unbinds it
+		if *data.Mobile != "" {
+			if ex := s.checkMobileFree(ctx, *data.Mobile, user.UniqueID); ex != nil {
+				return nil, ex
+			}
+		}
+		updates["mobile"] = model.NullableString(*data.Mobile)
+	}
+	if data.AvatarURL != nil {
+		updates["avatar_url"] = *data.AvatarURL
+	}
+	if len(updates) > 0 {
+		if err := s.userRepo.UpdateByMap(ctx, user.ID, updates); errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, exception.ExceptionUserMobileExists
+		} else if err != nil {
+			return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+		}
+	}
+
+	return s.Get(ctx, uniqueID)
+}
+
+func (s *adminUserService) changeRole(ctx context.Context, actorUniqueID, uniqueID int64, code model.UserRoleEnum, assign bool) *exception.Exception {
+	user, ex := s.findUser(ctx, uniqueID, false)
+	if ex != nil {
+		return ex
+	}
+	if _, ex := s.checkActor(ctx, actorUniqueID, user); ex != nil {
+		return ex
+	}
+	if user.HasRole(code) == assign {
+		return nil
+	}
+
+	roles, ex := s.findRoles(ctx, []model.UserRoleEnum{code})
+	if ex != nil {
+		return ex
+	}
+	// taking a role away needs the same rights as handing it out
+	if ex := s.checkGrant(ctx, actorUniqueID, roles); ex != nil {
+		return ex
+	}
+
+	var err error
+	if assign {
+		err = s.userRepo.AddRole(ctx, user, roles[0])
+	} else {
+		err = s.userRepo.RemoveRole(ctx, user, roles[0])
+	}
+	if err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	s.roleCache.InvalidateUser(ctx, uniqueID)
+
+	s.logger.Info("admin changed user role",
+		zap.Int64("uniqueId", uniqueID),
+		zap.String("role", string(code)),
+		zap.Bool("assign", assign),
+		zap.Int64("actorUniqueId", actorUniqueID),
+	)
+	return nil
+}
+
+func (s *adminUserService) AssignRole(ctx context.Context, actorUniqueID, uniqueID int64, code model.UserRoleEnum) *exception.Exception {
+	return s.changeRole(ctx, actorUniqueID, uniqueID, code, true)
+}
+
+func (s *adminUserService) RemoveRole(ctx context.Context, actorUniqueID, uniqueID int64, code model.UserRoleEnum) *exception.Exception {
+	return s.changeRole(ctx, actorUniqueID, uniqueID, code, false)
+}
+
+// SetStatus changes the account status. Disabling or banning revokes every
+// token of the user, the cached status is dropped so the JWT middleware sees
+// the change on the next request.
+func (s *adminUserService) SetStatus(ctx context.Context, actorUniqueID, uniqueID int64, data dto.AdminUserStatusReqDTO) *exception.Exception {
+	user, ex := s.findUser(ctx, uniqueID, false)
+	if ex != nil {
+		return ex
+	}
+	if _, ex := s.checkActor(ctx, actorUniqueID, user); ex != nil {
+		return ex
+	}
+
+	status := model.UserStatus(data.Status)
+	updates := map[string]any{
+		"status":        status,
+		"status_reason": data.Reason,
+		"banned_until":  nil,
+	}
+	if status == model.UserStatusBanned && data.BannedUntil > 0 {
+		bannedUntil := time.UnixMilli(data.BannedUntil)
+		if !bannedUntil.After(time.Now()) {
+			return exception.ExceptionInvalidParam.AppendDetails("bannedUntil must be in the future")
+		}
+		updates["banned_until"] = bannedUntil
+	}
+	if status == model.UserStatusActive {
+		updates["status_reason"] = ""
+	}
+
+	if err := s.userRepo.UpdateByMap(ctx, user.ID, updates); err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	s.statusCache.Invalidate(ctx, uniqueID)
+
+	s.logger.Info("admin changed user status",
+		zap.Int64("uniqueId", uniqueID),
+		zap.String("status", data.Status),
+		zap.String("reason", data.Reason),
+		zap.Int64("actorUniqueId", actorUniqueID),
+	)
+
+	if status == model.UserStatusActive {
+		return nil
+	}
+	return s.tokenService.RevokeUserTokens(ctx, uniqueID)
+}
+
+// Delete soft deletes the user and revokes every token, Restore undoes it.
+func (s *adminUserService) Delete(ctx context.Context, actorUniqueID, uniqueID int64) *exception.Exception {
+	user, ex := s.findUser(ctx, uniqueID, false)
+	if ex != nil {
+		return ex
+	}
+	if _, ex := s.checkActor(ctx, actorUniqueID, user); ex != nil {
+		return ex
+	}
+
+	if err := s.userRepo.SoftDelete(ctx, user.ID); err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	s.roleCache.InvalidateUser(ctx, uniqueID)
+	s.statusCache.Invalidate(ctx, uniqueID)
+
+	s.logger.Info("admin deleted user", zap.Int64("uniqueId", uniqueID), zap.Int64("actorUniqueId", actorUniqueID))
+	return s.tokenService.RevokeUserTokens(ctx, uniqueID)
+}
+
+func (s *adminUserService) Restore(ctx context.Context, actorUniqueID, uniqueID int64) *exception.Exception {
+	user, ex := s.findUser(ctx, uniqueID, true)
+	if ex != nil {
+		return ex
+	}
+	if _, ex := s.checkActor(ctx, actorUniqueID, user); ex != nil {
+		return ex
+	}
+	if !user.DeletedAt.Valid {
+		return nil
+	}
+	// the personal data of an anonymized user is gone for good
+	if user.AnonymizedAt != nil {
+		return exception.ExceptionUserNotFound
+	}
+
+	if err := s.userRepo.Restore(ctx, user.ID); err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	s.roleCache.InvalidateUser(ctx, uniqueID)
+	s.statusCache.Invalidate(ctx, uniqueID)
+
+	s.logger.Info("admin restored user", zap.Int64("uniqueId", uniqueID), zap.Int64("actorUniqueId", actorUniqueID))
+	return nil
+}
+
+// Impersonate lets a super admin see exactly what the user sees. The token
+// names both, is short lived and cannot be refreshed. Super admins cannot be
+// impersonated, and neither can users that could not sign in themselves.
+func (s *adminUserService) Impersonate(ctx context.Context, actorUniqueID, uniqueID int64, data dto.AdminUserImpersonateReqDTO) (*dto.AdminUserImpersonateResDTO, *exception.Exception) {
+	user, ex := s.findUser(ctx, uniqueID, false)
+	if ex != nil {
+		return nil, ex
+	}
+	if _, ex := s.checkActor(ctx, actorUniqueID, user); ex != nil {
+		return nil, ex
+	}
+	isSuperAdmin, ex := s.isSuperAdmin(ctx, user.Roles)
+	if ex != nil {
+		return nil, ex
+	}
+	if isSuperAdmin {
+		return nil, exception.ExceptionForbidden.AppendDetails("super admins cannot be impersonated")
+	}
+	if ex := s.statusCache.Check(ctx, uniqueID); ex != nil {
+		return nil, ex
+	}
+
+	token, claims, err := s.jwt.GenerateImpersonationToken(actorUniqueID, uniqueID)
+	if err != nil {
+		return nil, exception.ExceptionTokenGenerateFailed.AppendDetails(err.Error())
+	}
+
+	s.logger.Info("admin started impersonation",
+		zap.Int64("uniqueId", uniqueID),
+		zap.Int64("actorUniqueId", actorUniqueID),
+		zap.String("tokenId", claims.ID),
+		zap.String("reason", data.Reason),
+	)
+	return &dto.AdminUserImpersonateResDTO{
+		Token:        token,
+		ExpireAt:     claims.ExpiresAt.UnixMilli(),
+		UserUniqueID: uniqueID,
+	}, nil
+}
+
+// CheckImpersonation is a jwt.TokenValidator: an impersonation token stops
+// working as soon as its actor is no longer active or loses the permission.
+func (s *adminUserService) CheckImpersonation(ctx context.Context, claims *jwt.JWTClaims) *exception.Exception {
+	if !claims.IsImpersonation() {
+		return nil
+	}
+	if ex := s.statusCache.Check(ctx, claims.ActorUniqueID); ex != nil {
+		return ex
+	}
+	ok, ex := s.roleCache.HasPermissions(ctx, claims.ActorUniqueID, model.PermissionAdminUserImpersonate)
+	if ex != nil {
+		return ex
+	}
+	if !ok {
+		return exception.ExceptionTokenRevoked
+	}
+	return nil
+}
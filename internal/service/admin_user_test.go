@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"testing"
+)
+
+const (
+	testRoleEscalator model.UserRoleEnum = "role:escalator"
+	testAuditor       int64              = 100
+)
+
+// fakeRoleUserRepo records the role changes and the users created.
+type fakeRoleUserRepo struct {
+	*fakeUserRepo
+	added   []model.UserRoleEnum
+	removed []model.UserRoleEnum
+	created int
+}
+
+func (r *fakeRoleUserRepo) AddRole(ctx context.Context, user *model.User, role *model.UserRole) error {
+	r.added = append(r.added, role.Code)
+	return nil
+}
+
+func (r *fakeRoleUserRepo) RemoveRole(ctx context.Context, user *model.User, role *model.UserRole) error {
+	r.removed = append(r.removed, role.Code)
+	return nil
+}
+
+func (r *fakeRoleUserRepo) Create(ctx context.Context, entity *model.User) error {
+	r.created++
+	return nil
+}
+
+// newTestAdminUserService holds the roles of newTestAdminRoleService and an
+// escalator role granting nothing itself but inheriting from the auditor.
+// testUser holds the user role, testAuditor the auditor role.
+func newTestAdminUserService(t *testing.T) (*adminUserService, *fakeRoleUserRepo) {
+	t.Helper()
+	roleService, roleRepo := newTestAdminRoleService(t)
+	roles := make(map[model.UserRoleEnum]*model.UserRole, len(roleRepo.roles))
+	for _, role := range roleRepo.roles {
+		roles[role.Code] = role
+	}
+	roleRepo.roles = append(roleRepo.roles, &model.UserRole{BaseModel: model.BaseModel{ID: 5}, Code: testRoleEscalator,
+		Parents: []*model.UserRole{roles[testRoleAuditor]}})
+
+	users := roleService.userRepo.(*fakeUserRepo)
+	users.users = append(users.users,
+		&model.User{UniqueID: testUser, Roles: []*model.UserRole{roles[model.UserRoleCodeUser]}},
+		&model.User{UniqueID: testAuditor, Roles: []*model.UserRole{roles[model.UserRoleCodeUser], roles[testRoleAuditor]}},
+	)
+	userRepo := &fakeRoleUserRepo{fakeUserRepo: users}
+	return &adminUserService{
+		userRepo:     userRepo,
+		userRoleRepo: roleRepo,
+		logger:       newTestLogger(),
+		roleCache:    roleService.roleCache,
+		roles:        newUserRoleResolver(roleRepo),
+	}, userRepo
+}
+
+func TestAdminUserChangeRoleChecksGrants(t *testing.T) {
+	tests := []struct {
+		name   string
+		actor  int64
+		target int64
+		role   model.UserRoleEnum
+		assign bool
+		want   *exception.Exception
+	}{
+		{"assign a held role", testAdmin, testUser, model.UserRoleCodeAdmin, true, nil},
+		{"assign a role granting more", testAdmin, testUser, testRoleAuditor, true, exception.ExceptionForbidden},
+		{"assign a role inheriting more", testAdmin, testUser, testRoleEscalator, true, exception.ExceptionForbidden},
+		{"assign super admin", testAdmin, testUser, model.UserRoleCodeSuperAdmin, true, exception.ExceptionForbidden},
+		{"super admin assigns anything", testSuperAdmin, testUser, testRoleEscalator, true, nil},
+		{"remove a role granting more", testAdmin, testAuditor, testRoleAuditor, false, exception.ExceptionForbidden},
+		{"super admin removes anything", testSuperAdmin, testAuditor, testRoleAuditor, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, userRepo := newTestAdminUserService(t)
+			var ex *exception.Exception
+			if tt.assign {
+				ex = s.AssignRole(context.Background(), tt.actor, tt.target, tt.role)
+			} else {
+				ex = s.RemoveRole(context.Background(), tt.actor, tt.target, tt.role)
+			}
+			changed := len(userRepo.added) + len(userRepo.removed)
+			if tt.want == nil {
+				if ex != nil {
+					t.Fatalf("changeRole: %v", ex)
+				}
+				if changed != 1 {
+					t.Errorf("added %v, removed %v, want the role changed", userRepo.added, userRepo.removed)
+				}
+				return
+			}
+			if ex == nil || !ex.Is(tt.want) {
+				t.Fatalf("changeRole = %v, want %v", ex, tt.want)
+			}
+			if changed != 0 {
+				t.Errorf("added %v, removed %v, want the roles kept", userRepo.added, userRepo.removed)
+			}
+		})
+	}
+}
+
+func TestAdminUserCreateChecksGrants(t *testing.T) {
+	for _, role := range []model.UserRoleEnum{testRoleAuditor, testRoleEscalator, model.UserRoleCodeSuperAdmin} {
+		t.Run(string(role), func(t *testing.T) {
+			s, userRepo := newTestAdminUserService(t)
+			_, ex := s.Create(context.Background(), testAdmin, dto.AdminUserCreateReqDTO{
+				Email:    "new@example.com",
+				Password: "password",
+				Roles:    []string{string(role)},
+			})
+			if ex == nil || !ex.Is(exception.ExceptionForbidden) {
+				t.Fatalf("Create = %v, want %v", ex, exception.ExceptionForbidden)
+			}
+			if userRepo.created != 0 {
+				t.Errorf("created %d users, want none", userRepo.created)
+			}
+		})
+	}
+}
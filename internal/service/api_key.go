@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/utils"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type APIKeyService interface {
+	Create(ctx context.Context, creatorUniqueID int64, data dto.APIKeyCreateReqDTO) (*dto.APIKeyCreateResDTO, *exception.Exception)
+	List(ctx context.Context, actorUniqueID int64, data dto.APIKeyListReqDTO) ([]*model.APIKey, int64, *exception.Exception)
+	Revoke(ctx context.Context, actorUniqueID int64, id uint64) *exception.Exception
+	Authenticate(ctx context.Context, key string) (*model.APIKey, *exception.Exception)
+}
+
+// API keys look like "sk_<prefix>_<secret>". The prefix is stored in plain
+// text so admins can recognise a key, lookups go through the hash of the
+// whole key.
+const (
+	apiKeyMarker        = "sk_"
+	apiKeyPrefixLength  = 8
+	apiKeyLastUsedEvery = 1 * time.Minute
+)
+
+type apiKeyService struct {
+	apiKeyRepo  repo.APIKeyRepo
+	userRepo    repo.UserRepo
+	roleCache   *userRoleCache
+	logger      *logger.Logger
+	redis       *redis.Client
+	statusCache *userStatusCache
+}
+
+func NewAPIKeyService(apiKeyRepo repo.APIKeyRepo, userRepo repo.UserRepo, roleCache *userRoleCache, logger *logger.Logger, redis *redis.Client) APIKeyService {
+	logger.Info("NewAPIKeyService initialized successfully")
+	return &apiKeyService{
+		apiKeyRepo:  apiKeyRepo,
+		userRepo:    userRepo,
+		roleCache:   roleCache,
+		logger:      logger,
+		redis:       redis,
+		statusCache: newUserStatusCache(userRepo, redis, logger),
+	}
+}
+
+func (s *apiKeyService) lastUsedKey(id uint64) string {
+	return fmt.Sprintf("auth:api_key:last_used:%d", id)
+}
+
+// checkOwner makes sure the actor may manage the keys of owner. A key acts
+// as its owner, so the actor must hold every permission the owner holds, or
+// an admin could mint themselves the rights of a super admin.
+func (s *apiKeyService) checkOwner(ctx context.Context, actorUniqueID, ownerUniqueID int64) *exception.Exception {
+	if actorUniqueID == ownerUniqueID {
+		return nil
+	}
+	owned, ex := s.roleCache.Permissions(ctx, ownerUniqueID)
+	if ex != nil {
+		return ex
+	}
+	granted, ex := s.roleCache.Permissions(ctx, actorUniqueID)
+	if ex != nil {
+		return ex
+	}
+	for _, permission := range owned {
+		if !slices.Contains(granted, permission) {
+			return exception.ExceptionForbidden.AppendDetails("cannot manage the API keys of a user holding permissions you lack")
+		}
+	}
+	return nil
+}
+
+func (s *apiKeyService) Create(ctx context.Context, creatorUniqueID int64, data dto.APIKeyCreateReqDTO) (*dto.APIKeyCreateResDTO, *exception.Exception) {
+	var expiresAt *time.Time
+	if data.ExpiresAt > 0 {
+		t := time.UnixMilli(data.ExpiresAt)
+		if !t.After(time.Now()) {
+			return nil, exception.ExceptionInvalidParam.AppendDetails("expiresAt must be in the future")
+		}
+		expiresAt = &t
+	}
+
+	if _, err := s.userRepo.FindByUniqueID(ctx, data.OwnerUniqueID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, exception.ExceptionUserNotFound
+	} else if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	if ex := s.checkOwner(ctx, creatorUniqueID, data.OwnerUniqueID); ex != nil {
+		return nil, ex
+	}
+
+	prefix := utils.GenerateRandomCode(apiKeyPrefixLength)
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	key := apiKeyMarker + prefix + "_" + secret
+
+	apiKey := &model.APIKey{
+		Name:              data.Name,
+		Prefix:            prefix,
+		KeyHash:           utils.SHA256Hex(key),
+		OwnerUniqueID:     data.OwnerUniqueID,
+		CreatedByUniqueID: creatorUniqueID,
+		Scopes:            data.Scopes,
+		ExpiresAt:         expiresAt,
+	}
+	if err := s.apiKeyRepo.Create(ctx, apiKey); err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	s.logger.Info("api key created",
+		zap.Uint64("id", apiKey.ID),
+		zap.String("prefix", prefix),
+		zap.Int64("ownerUniqueId", apiKey.OwnerUniqueID),
+		zap.Int64("createdByUniqueId", creatorUniqueID),
+	)
+
+	return &dto.APIKeyCreateResDTO{Key: key, APIKey: apiKey}, nil
+}
+
+// List returns the keys of an owner the actor may manage. Without an owner
+// super admins see every key, other admins the keys they created or own.
+func (s *apiKeyService) List(ctx context.Context, actorUniqueID int64, data dto.APIKeyListReqDTO) ([]*model.APIKey, int64, *exception.Exception) {
+	opts := []repo.QueryOption{repo.Order("id DESC")}
+	if data.OwnerUniqueID != 0 {
+		if ex := s.checkOwner(ctx, actorUniqueID, data.OwnerUniqueID); ex != nil {
+			return nil, 0, ex
+		}
+		opts = append(opts, repo.Where("owner_unique_id = ?", data.OwnerUniqueID))
+	} else {
+		roles, ex := s.roleCache.Roles(ctx, actorUniqueID)
+		if ex != nil {
+			return nil, 0, ex
+		}
+		if !hasUserRole(roles, model.UserRoleCodeSuperAdmin) {
+			opts = append(opts, repo.Where("created_by_unique_id = ? OR owner_unique_id = ?", actorUniqueID, actorUniqueID))
+		}
+	}
+	keys, total, err := s.apiKeyRepo.FindPage(ctx, data.Pagination, opts...)
+	if err != nil {
+		return nil, 0, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	return keys, total, nil
+}
+
+// Revoke is allowed to whoever created the key, or may manage its owner.
+func (s *apiKeyService) Revoke(ctx context.Context, actorUniqueID int64, id uint64) *exception.Exception {
+	apiKey, err := s.apiKeyRepo.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return exception.ExceptionAPIKeyNotFound
+	} else if err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	if apiKey.CreatedByUniqueID != actorUniqueID {
+		if ex := s.checkOwner(ctx, actorUniqueID, apiKey.OwnerUniqueID); ex != nil {
+			return ex
+		}
+	}
+	if apiKey.RevokedAt != nil {
+		return nil
+	}
+
+	if err := s.apiKeyRepo.UpdateByMap(ctx, id, map[string]any{"revoked_at": time.Now()}); err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	s.logger.Info("api key revoked", zap.Uint64("id", id), zap.String("prefix", apiKey.Prefix))
+	return nil
+}
+
+func (s *apiKeyService) Authenticate(ctx context.Context, key string) (*model.APIKey, *exception.Exception) {
+	if !strings.HasPrefix(key, apiKeyMarker) {
+		return nil, exception.ExceptionAPIKeyInvalid
+	}
+
+	apiKey, err := s.apiKeyRepo.FindByKeyHash(ctx, utils.SHA256Hex(key))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, exception.ExceptionAPIKeyInvalid
+	} else if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	now := time.Now()
+	if !apiKey.IsActive(now) {
+		return nil, exception.ExceptionAPIKeyInvalid
+	}
+	// a key acts as its owner and stops working with the owner's account
+	if ex := s.statusCache.Check(ctx, apiKey.OwnerUniqueID); ex != nil {
+		return nil, ex
+	}
+
+	// batch jobs may call many times a second, the last used time only
+	// needs minute precision
+	if ok, err := s.redis.SetNX(ctx, s.lastUsedKey(apiKey.ID), 1, apiKeyLastUsedEvery).Result(); err != nil {
+		s.logger.Warn("api key last used throttle failed", zap.Error(err))
+	} else if ok {
+		if err := s.apiKeyRepo.UpdateByMap(ctx, apiKey.ID, map[string]any{"last_used_at": now}); err != nil {
+			s.logger.Warn("api key last used update failed", zap.Uint64("id", apiKey.ID), zap.Error(err))
+		}
+	}
+
+	return apiKey, nil
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+type fakeAPIKeyRepo struct {
+	repo.APIKeyRepo
+	keys    []*model.APIKey
+	updates []map[string]any
+}
+
+func (r *fakeAPIKeyRepo) Create(ctx context.Context, entity *model.APIKey) error {
+	entity.ID = uint64(len(r.keys) + 1)
+	r.keys = append(r.keys, entity)
+	return nil
+}
+
+func (r *fakeAPIKeyRepo) FindByID(ctx context.Context, id uint64) (*model.APIKey, error) {
+	for _, key := range r.keys {
+		if key.ID == id {
+			return key, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeAPIKeyRepo) UpdateByMap(ctx context.Context, id uint64, data map[string]any) error {
+	r.updates = append(r.updates, data)
+	return nil
+}
+
+// Users of the API key tests, the admins hold a subset of the permissions
+// of the super admin.
+const (
+	testSuperAdmin int64 = iota + 1
+	testAdmin
+	testOtherAdmin
+	testUser
+)
+
+func newTestAPIKeyService(t *testing.T) (*apiKeyService, *fakeAPIKeyRepo) {
+	t.Helper()
+	superAdmin := &model.UserRole{BaseModel: model.BaseModel{ID: 1}, Code: model.UserRoleCodeSuperAdmin}
+	admin := &model.UserRole{BaseModel: model.BaseModel{ID: 2}, Code: model.UserRoleCodeAdmin}
+	user := &model.UserRole{BaseModel: model.BaseModel{ID: 3}, Code: model.UserRoleCodeUser}
+	users := &fakeUserRepo{users: []*model.User{
+		{UniqueID: testSuperAdmin, Roles: []*model.UserRole{superAdmin}},
+		{UniqueID: testAdmin, Roles: []*model.UserRole{admin}},
+		{UniqueID: testOtherAdmin, Roles: []*model.UserRole{admin}},
+		{UniqueID: testUser, Roles: []*model.UserRole{user}},
+	}}
+	roleCache := newTestRoleCache(t, users, &fakeUserRoleRepo{roles: []*model.UserRole{superAdmin, admin, user}}, &fakePermissionRepo{
+		granted: map[uint64][]model.PermissionEnum{
+			1: {model.PermissionUserRead, model.PermissionAdminAPIKeyWrite, model.PermissionAdminRoleWrite},
+			2: {model.PermissionUserRead, model.PermissionAdminAPIKeyWrite},
+			3: {model.PermissionUserRead},
+		},
+	})
+	rdb, _ := newTestRedis(t)
+	keys := &fakeAPIKeyRepo{}
+	return &apiKeyService{
+		apiKeyRepo: keys,
+		userRepo:   users,
+		roleCache:  roleCache,
+		logger:     newTestLogger(),
+		redis:      rdb,
+	}, keys
+}
+
+func TestAPIKeyCreateChecksOwner(t *testing.T) {
+	tests := []struct {
+		name  string
+		actor int64
+		owner int64
+		want  *exception.Exception
+	}{
+		{"own key", testAdmin, testAdmin, nil},
+		{"for a user", testAdmin, testUser, nil},
+		{"for an equal admin", testAdmin, testOtherAdmin, nil},
+		{"for a super admin", testAdmin, testSuperAdmin, exception.ExceptionForbidden},
+		{"super admin for an admin", testSuperAdmin, testAdmin, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, keys := newTestAPIKeyService(t)
+			res, ex := s.Create(context.Background(), tt.actor, dto.APIKeyCreateReqDTO{Name: "ci", OwnerUniqueID: tt.owner, Scopes: []string{"user:read"}})
+			if tt.want == nil {
+				if ex != nil {
+					t.Fatalf("Create: %v", ex)
+				}
+				if res.APIKey.OwnerUniqueID != tt.owner || res.APIKey.CreatedByUniqueID != tt.actor {
+					t.Errorf("key = %+v, want owner %d created by %d", res.APIKey, tt.owner, tt.actor)
+				}
+				return
+			}
+			if ex == nil || !ex.Is(tt.want) {
+				t.Fatalf("Create = %v, want %v", ex, tt.want)
+			}
+			if len(keys.keys) != 0 {
+				t.Errorf("keys = %+v, want none created", keys.keys)
+			}
+		})
+	}
+}
+
+func TestAPIKeyRevokeChecksOwner(t *testing.T) {
+	tests := []struct {
+		name    string
+		actor   int64
+		owner   int64
+		creator int64
+		want    *exception.Exception
+	}{
+		{"created by the actor", testAdmin, testUser, testAdmin, nil},
+		{"of a user", testOtherAdmin, testUser, testAdmin, nil},
+		{"of a super admin", testAdmin, testSuperAdmin, testSuperAdmin, exception.ExceptionForbidden},
+		{"by a user", testUser, testAdmin, testAdmin, exception.ExceptionForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, keys := newTestAPIKeyService(t)
+			keys.keys = append(keys.keys, &model.APIKey{BaseModel: model.BaseModel{ID: 1}, OwnerUniqueID: tt.owner, CreatedByUniqueID: tt.creator})
+
+			ex := s.Revoke(context.Background(), tt.actor, 1)
+			if tt.want == nil {
+				if ex != nil {
+					t.Fatalf("Revoke: %v", ex)
+				}
+				if len(keys.updates) != 1 {
+					t.Errorf("updates = %v, want the key revoked", keys.updates)
+				}
+				return
+			}
+			if ex == nil || !ex.Is(tt.want) {
+				t.Fatalf("Revoke = %v, want %v", ex, tt.want)
+			}
+			if len(keys.updates) != 0 {
+				t.Errorf("updates = %v, want the key kept", keys.updates)
+			}
+		})
+	}
+}
+
+func TestAPIKeyListChecksOwner(t *testing.T) {
+	s, _ := newTestAPIKeyService(t)
+	_, _, ex := s.List(context.Background(), testAdmin, dto.APIKeyListReqDTO{OwnerUniqueID: testSuperAdmin})
+	if ex == nil || !ex.Is(exception.ExceptionForbidden) {
+		t.Fatalf("List = %v, want %v", ex, exception.ExceptionForbidden)
+	}
+}
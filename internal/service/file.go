@@ -0,0 +1,285 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"super-web-server/internal/config"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/policy"
+	"super-web-server/pkg/signurl"
+	"super-web-server/pkg/snowflake"
+	"super-web-server/pkg/storage"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type FileService interface {
+	Upload(ctx context.Context, ownerUniqueID int64, name string, file io.Reader, size int64) (*model.File, *exception.Exception)
+	List(ctx context.Context, ownerUniqueID int64, data dto.FileListReqDTO) ([]*model.File, int64, *exception.Exception)
+	Get(ctx context.Context, uniqueID int64) (*model.File, *exception.Exception)
+	Open(ctx context.Context, uniqueID int64) (*model.File, *storage.Reader, *exception.Exception)
+	SignedURL(ctx context.Context, uniqueID int64, clientIP string, data dto.FileSignedURLReqDTO) (*dto.FileURLResDTO, *exception.Exception)
+	OpenSignedFile(ctx context.Context, uniqueID int64, query url.Values, clientIP string) (*model.File, *signurl.Options, *storage.Reader, *exception.Exception)
+	OpenSigned(ctx context.Context, key string, query url.Values) (*model.File, *storage.Reader, *exception.Exception)
+	Delete(ctx context.Context, uniqueID int64) *exception.Exception
+}
+
+const fileNameMaxLength = 255
+
+type fileService struct {
+	fileRepo  repo.FileRepo
+	storage   storage.Storage
+	signer    *signurl.Signer
+	policy    PolicyService
+	logger    *logger.Logger
+	snowflake *snowflake.Snowflake
+	config    *config.Config
+}
+
+func NewFileService(fileRepo repo.FileRepo, storage storage.Storage, signer *signurl.Signer, policy PolicyService, logger *logger.Logger, snowflake *snowflake.Snowflake, config *config.Config) FileService {
+	logger.Info("NewFileService initialized successfully")
+	return &fileService{
+		fileRepo:  fileRepo,
+		storage:   storage,
+		signer:    signer,
+		policy:    policy,
+		logger:    logger,
+		snowflake: snowflake,
+		config:    config,
+	}
+}
+
+// signedFileResource is what a signed file link authorizes.
+func signedFileResource(uniqueID int64) string {
+	return fmt.Sprintf("file:%d", uniqueID)
+}
+
+func (s *fileService) storageKey(ownerUniqueID, uniqueID int64) string {
+	return fmt.Sprintf("files/%d/%d", ownerUniqueID, uniqueID)
+}
+
+func (s *fileService) storageException(err error) *exception.Exception {
+	if errors.Is(err, storage.ErrNotFound) {
+		return exception.ExceptionFileNotFound
+	}
+	s.logger.Error("storage operation failed", zap.Error(err))
+	return exception.ExceptionStorageError
+}
+
+// Upload stores the file under a key of its own, the name given by the client
+// is only kept as metadata. The content type is sniffed from the content
+// rather than taken from the request.
+func (s *fileService) Upload(ctx context.Context, ownerUniqueID int64, name string, file io.Reader, size int64) (*model.File, *exception.Exception) {
+	if size > s.config.Storage.MaxSize {
+		return nil, exception.ExceptionFileTooLarge.AppendDetails(fmt.Sprintf("max %d bytes", s.config.Storage.MaxSize))
+	}
+	name = cleanFileName(name)
+	if name == "" {
+		return nil, exception.ExceptionInvalidParam.AppendDetails("file name is required")
+	}
+
+	reader := bufio.NewReaderSize(file, 512)
+	head, err := reader.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, exception.ExceptionBadRequest.AppendDetails(err.Error())
+	}
+	contentType := http.DetectContentType(head)
+
+	hash := sha256.New()
+	uniqueID := s.snowflake.GenerateID()
+	key := s.storageKey(ownerUniqueID, uniqueID)
+	object, err := s.storage.Put(ctx, key, io.TeeReader(reader, hash), size, storage.PutOptions{ContentType: contentType})
+	if err != nil {
+		return nil, s.storageException(err)
+	}
+
+	entity := &model.File{
+		UniqueID:      uniqueID,
+		OwnerUniqueID: ownerUniqueID,
+		Name:          name,
+		StorageKey:    key,
+		Size:          object.Size,
+		ContentType:   contentType,
+		Checksum:      hex.EncodeToString(hash.Sum(nil)),
+	}
+	if err := s.fileRepo.Create(ctx, entity); err != nil {
+		if err := s.storage.Delete(ctx, key); err != nil {
+			s.logger.Warn("Failed to delete orphaned object", zap.String("key", key), zap.Error(err))
+		}
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	return entity, nil
+}
+
+func (s *fileService) List(ctx context.Context, ownerUniqueID int64, data dto.FileListReqDTO) ([]*model.File, int64, *exception.Exception) {
+	list, total, err := s.fileRepo.FindPage(ctx, data.Pagination,
+		repo.Where("owner_unique_id = ?", ownerUniqueID),
+		repo.Order("id DESC"),
+	)
+	if err != nil {
+		return nil, 0, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	return list, total, nil
+}
+
+// find only finds files the policy lets the user of the request act on, a
+// file the user may not see is reported as missing rather than forbidden.
+func (s *fileService) find(ctx context.Context, uniqueID int64, action policy.Action) (*model.File, *exception.Exception) {
+	file, err := s.fileRepo.FindByUniqueID(ctx, uniqueID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, exception.ExceptionFileNotFound
+	} else if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	if ex := s.policy.Authorize(ctx, action, file.PolicyResource()); ex != nil {
+		if ex.Is(exception.ExceptionForbidden) {
+			return nil, exception.ExceptionFileNotFound
+		}
+		return nil, ex
+	}
+	return file, nil
+}
+
+func (s *fileService) Get(ctx context.Context, uniqueID int64) (*model.File, *exception.Exception) {
+	return s.find(ctx, uniqueID, policy.ActionRead)
+}
+
+func (s *fileService) Open(ctx context.Context, uniqueID int64) (*model.File, *storage.Reader, *exception.Exception) {
+	file, ex := s.Get(ctx, uniqueID)
+	if ex != nil {
+		return nil, nil, ex
+	}
+	return file, storage.NewReader(ctx, s.storage, file.StorageKey, file.Size), nil
+}
+
+// SignedURL mints a link to the file that works without a session, e.g. in
+// mails or <img> tags. A link bound to the IP of the caller is useless to
+// anybody it is forwarded to.
+func (s *fileService) SignedURL(ctx context.Context, uniqueID int64, clientIP string, data dto.FileSignedURLReqDTO) (*dto.FileURLResDTO, *exception.Exception) {
+	signedURLConfig := s.config.SignedURL
+
+	expire := signedURLConfig.Expire
+	if data.Expire > 0 {
+		expire = time.Duration(data.Expire) * time.Second
+	}
+	if expire > signedURLConfig.MaxExpire {
+		return nil, exception.ExceptionInvalidParam.AppendDetails(fmt.Sprintf("expire must not exceed %d seconds", int64(signedURLConfig.MaxExpire.Seconds())))
+	}
+
+	file, ex := s.Get(ctx, uniqueID)
+	if ex != nil {
+		return nil, ex
+	}
+
+	opts := signurl.Options{
+		ExpireAt:    time.Now().Add(expire),
+		Disposition: data.Disposition,
+		Filename:    cleanFileName(data.Filename),
+	}
+	if data.BindIP {
+		opts.IP = clientIP
+	}
+	query := s.signer.Sign(signedFileResource(file.UniqueID), opts)
+	signedURL := fmt.Sprintf("%s/%d?%s", strings.TrimSuffix(signedURLConfig.BaseURL, "/"), file.UniqueID, query.Encode())
+
+	return &dto.FileURLResDTO{URL: signedURL, ExpireAt: opts.ExpireAt.UnixMilli()}, nil
+}
+
+func (s *fileService) signedURLException(err error) *exception.Exception {
+	if errors.Is(err, signurl.ErrExpired) {
+		return exception.ExceptionSignedURLExpired
+	}
+	return exception.ExceptionSignedURLInvalid
+}
+
+// OpenSignedFile serves a link minted by SignedURL, the signature replaces
+// the owner check.
+func (s *fileService) OpenSignedFile(ctx context.Context, uniqueID int64, query url.Values, clientIP string) (*model.File, *signurl.Options, *storage.Reader, *exception.Exception) {
+	opts, err := s.signer.Verify(signedFileResource(uniqueID), query, clientIP, time.Now())
+	if err != nil {
+		return nil, nil, nil, s.signedURLException(err)
+	}
+
+	file, err := s.fileRepo.FindByUniqueID(ctx, uniqueID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, nil, exception.ExceptionFileNotFound
+	} else if err != nil {
+		return nil, nil, nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	return file, opts, storage.NewReader(ctx, s.storage, file.StorageKey, file.Size), nil
+}
+
+// OpenSigned serves the signed URLs of storage backends that cannot serve
+// them themselves.
+func (s *fileService) OpenSigned(ctx context.Context, key string, query url.Values) (*model.File, *storage.Reader, *exception.Exception) {
+	verifier, ok := s.storage.(storage.SignedURLVerifier)
+	if !ok {
+		return nil, nil, exception.ExceptionNotFound
+	}
+	if err := verifier.VerifySignedURL(key, query); err != nil {
+		return nil, nil, s.signedURLException(err)
+	}
+
+	file, err := s.fileRepo.FindByStorageKey(ctx, key)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, exception.ExceptionFileNotFound
+	} else if err != nil {
+		return nil, nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	return file, storage.NewReader(ctx, s.storage, file.StorageKey, file.Size), nil
+}
+
+// Delete keeps the metadata row (soft deleted) for auditing, the content is
+// removed.
+func (s *fileService) Delete(ctx context.Context, uniqueID int64) *exception.Exception {
+	file, ex := s.find(ctx, uniqueID, policy.ActionDelete)
+	if ex != nil {
+		return ex
+	}
+	if err := s.fileRepo.SoftDelete(ctx, file.ID); err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	if err := s.storage.Delete(ctx, file.StorageKey); err != nil {
+		s.logger.Warn("Failed to delete object of deleted file", zap.String("key", file.StorageKey), zap.Error(err))
+	}
+	return nil
+}
+
+// cleanFileName drops any directory part and control characters of a client
+// supplied file name and caps its length.
+func cleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "." || name == "/" {
+		return ""
+	}
+	for len(name) > fileNameMaxLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
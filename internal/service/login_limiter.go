@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"super-web-server/internal/config"
+	"super-web-server/internal/exception"
+	"super-web-server/pkg/logger"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// incrWithWindowScript increments a failure counter and starts its window on
+// the first failure only, so the window is not extended by later failures.
+var incrWithWindowScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// loginLimiter counts failed logins per email and per client ip. After
+// DelayAfter failures every further attempt has to wait an exponentially
+// growing delay, after the max failures the email or ip is locked out.
+type loginLimiter struct {
+	redis  *redis.Client
+	logger *logger.Logger
+	config config.LoginLimitConfig
+}
+
+func newLoginLimiter(redis *redis.Client, logger *logger.Logger, config config.LoginLimitConfig) *loginLimiter {
+	return &loginLimiter{
+		redis:  redis,
+		logger: logger,
+		config: config,
+	}
+}
+
+func (l *loginLimiter) failuresKey(kind, value string) string {
+	return fmt.Sprintf("auth:login:failures:%s:%s", kind, value)
+}
+
+func (l *loginLimiter) lockKey(kind, value string) string {
+	return fmt.Sprintf("auth:login:lock:%s:%s", kind, value)
+}
+
+func (l *loginLimiter) normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Check rejects the attempt while the email or the ip is delayed or locked.
+func (l *loginLimiter) Check(ctx context.Context, email, ip string) *exception.Exception {
+	pipe := l.redis.Pipeline()
+	emailTTL := pipe.PTTL(ctx, l.lockKey("email", l.normalizeEmail(email)))
+	ipTTL := pipe.PTTL(ctx, l.lockKey("ip", ip))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+
+	wait := max(emailTTL.Val(), ipTTL.Val())
+	if wait > 0 {
+		return exception.ExceptionTooManyRequests.AppendDetails(fmt.Sprintf("retry after %d seconds", int(math.Ceil(wait.Seconds()))))
+	}
+	return nil
+}
+
+// Fail records a failed attempt and delays or locks the email and the ip.
+func (l *loginLimiter) Fail(ctx context.Context, email, ip string) {
+	l.fail(ctx, "email", l.normalizeEmail(email), l.config.MaxFailuresPerEmail)
+	l.fail(ctx, "ip", ip, l.config.MaxFailuresPerIP)
+}
+
+func (l *loginLimiter) fail(ctx context.Context, kind, value string, maxFailures int) {
+	failures, err := incrWithWindowScript.Run(ctx, l.redis, []string{l.failuresKey(kind, value)}, l.config.Window.Milliseconds()).Int()
+	if err != nil {
+		l.logger.Warn("Failed to record login failure", zap.String(kind, value), zap.Error(err))
+		return
+	}
+
+	var lock time.Duration
+	switch {
+	case failures >= maxFailures:
+		lock = l.config.Lockout
+		l.logger.Warn("Login locked out", zap.String(kind, value), zap.Int("failures", failures))
+	case failures >= l.config.DelayAfter:
+		lock = l.config.BaseDelay << (failures - l.config.DelayAfter)
+		if lock <= 0 || lock > l.config.MaxDelay {
+			lock = l.config.MaxDelay
+		}
+	}
+	if lock <= 0 {
+		return
+	}
+
+	if err := l.redis.Set(ctx, l.lockKey(kind, value), failures, lock).Err(); err != nil {
+		l.logger.Warn("Failed to lock login", zap.String(kind, value), zap.Error(err))
+	}
+}
+
+// Succeed resets the failures of the email. The ip counter is kept, otherwise
+// an attacker could reset it by logging into an account of their own.
+func (l *loginLimiter) Succeed(ctx context.Context, email string) {
+	email = l.normalizeEmail(email)
+	if err := l.redis.Del(ctx, l.failuresKey("email", email), l.lockKey("email", email)).Err(); err != nil {
+		l.logger.Warn("Failed to reset login failures", zap.String("email", email), zap.Error(err))
+	}
+}
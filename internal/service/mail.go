@@ -0,0 +1,72 @@
+package service
+
+import (
+	"fmt"
+	"super-web-server/pkg/mailer"
+	"time"
+)
+
+func newRegisterCodeMail(to, code string, expire time.Duration) mailer.Message {
+	return mailer.Message{
+		To:      []string{to},
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Your verification code is %s.\n\n"+
+			"The code expires in %s. If you did not sign up, you can ignore this email.\n", code, expire),
+	}
+}
+
+func newPasswordResetMail(to, link string, expire time.Duration) mailer.Message {
+	return mailer.Message{
+		To:      []string{to},
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Open the link below to reset your password:\n\n%s\n\n"+
+			"The link can be used once and expires in %s. If you did not ask for a password reset, you can ignore this email.\n", link, expire),
+	}
+}
+
+func newEmailChangeCodeMail(to, code string, expire time.Duration) mailer.Message {
+	return mailer.Message{
+		To:      []string{to},
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Your verification code is %s.\n\n"+
+			"Enter it to make this your new sign in address. The code expires in %s. If you did not ask for this, you can ignore this email.\n", code, expire),
+	}
+}
+
+func newReauthCodeMail(to, code string, expire time.Duration) mailer.Message {
+	return mailer.Message{
+		To:      []string{to},
+		Subject: "Confirm the change to your account",
+		Body: fmt.Sprintf("Your verification code is %s.\n\n"+
+			"Enter it to confirm the change to your account. The code expires in %s. If you did not ask for this, someone may be signed in to your account, sign out all sessions.\n", code, expire),
+	}
+}
+
+func newEmailChangedMail(to, newEmail, link string, expire time.Duration) mailer.Message {
+	return mailer.Message{
+		To:      []string{to},
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf("The sign in address of your account was changed to %s and all sessions were signed out.\n\n"+
+			"If you did not do this, open the link below to switch back to this address, then reset your password:\n\n%s\n\n"+
+			"The link expires in %s.\n", newEmail, link, expire),
+	}
+}
+
+func newDataExportReadyMail(to, link string, expireAt time.Time) mailer.Message {
+	return mailer.Message{
+		To:      []string{to},
+		Subject: "Your data export is ready",
+		Body: fmt.Sprintf("The copy of your data you asked for is ready, download it here:\n\n%s\n\n"+
+			"The link works until %s.\n", link, expireAt.Format(time.RFC1123)),
+	}
+}
+
+func newAccountDeletionMail(to, link string, purgeAt time.Time) mailer.Message {
+	return mailer.Message{
+		To:      []string{to},
+		Subject: "Your account will be deleted",
+		Body: fmt.Sprintf("Your account was deleted and all sessions were signed out. "+
+			"Your personal data will be erased on %s.\n\n"+
+			"Changed your mind? Open the link below before then to restore the account:\n\n%s\n", purgeAt.Format(time.RFC1123), link),
+	}
+}
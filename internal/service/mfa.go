@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"super-web-server/internal/config"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/mailer"
+	"super-web-server/pkg/password"
+	"super-web-server/pkg/totp"
+	"super-web-server/pkg/utils"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type MFAService interface {
+	EnrollTOTP(ctx context.Context, userUniqueID int64) (*dto.UserTOTPEnrollResDTO, *exception.Exception)
+	ConfirmTOTP(ctx context.Context, userUniqueID int64, data dto.UserTOTPConfirmReqDTO) (*dto.UserTOTPConfirmResDTO, *exception.Exception)
+	DisableTOTP(ctx context.Context, userUniqueID int64, sessionID string, data dto.UserTOTPDisableReqDTO) *exception.Exception
+	CreateChallenge(ctx context.Context, userUniqueID int64) (*dto.UserLoginResDTO, *exception.Exception)
+	LoginByMFA(ctx context.Context, data dto.UserMFALoginReqDTO, meta dto.ClientMeta) (*dto.UserTokenResDTO, *exception.Exception)
+}
+
+// setIfGreaterScript stores a TOTP step only if it is newer than the last
+// accepted one, so a code cannot be replayed inside its validity window.
+var setIfGreaterScript = redis.NewScript(`
+local last = tonumber(redis.call("GET", KEYS[1]) or "-1")
+if tonumber(ARGV[1]) <= last then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return 1
+`)
+
+type mfaService struct {
+	userRepo             repo.UserRepo
+	userRecoveryCodeRepo repo.UserRecoveryCodeRepo
+	tokenService         TokenService
+	hasher               *password.Hasher
+	logger               *logger.Logger
+	redis                *redis.Client
+	config               config.MFAConfig
+	reauth               *reauthenticator
+}
+
+func NewMFAService(userRepo repo.UserRepo, userSessionRepo repo.UserSessionRepo, userRecoveryCodeRepo repo.UserRecoveryCodeRepo, tokenService TokenService, hasher *password.Hasher, logger *logger.Logger, redis *redis.Client, mailer mailer.Mailer, config *config.Config) MFAService {
+	logger.Info("NewMFAService initialized successfully")
+	return &mfaService{
+		userRepo:             userRepo,
+		userRecoveryCodeRepo: userRecoveryCodeRepo,
+		tokenService:         tokenService,
+		hasher:               hasher,
+		logger:               logger,
+		redis:                redis,
+		config:               config.MFA,
+		reauth:               newReauthenticator(userSessionRepo, newVerifyCodeStore(redis, config.VerifyCode), hasher, mailer, logger, config.Reauth.MaxAge, config.VerifyCode.Expire, config.VerifyCode.ResendInterval),
+	}
+}
+
+func (s *mfaService) pendingSecretKey(userUniqueID int64) string {
+	return fmt.Sprintf("user:mfa:totp:pending:%d", userUniqueID)
+}
+
+func (s *mfaService) lastStepKey(userUniqueID int64) string {
+	return fmt.Sprintf("user:mfa:totp:last_step:%d", userUniqueID)
+}
+
+func (s *mfaService) challengeKey(token string) string {
+	return fmt.Sprintf("auth:mfa:challenge:%s", utils.SHA256Hex(token))
+}
+
+func (s *mfaService) EnrollTOTP(ctx context.Context, userUniqueID int64) (*dto.UserTOTPEnrollResDTO, *exception.Exception) {
+	user, err := s.userRepo.FindByUniqueID(ctx, userUniqueID)
+	if err != nil {
+		return nil, exception.ExceptionUserNotFound.AppendDetails(err.Error())
+	}
+	if user.IsTOTPEnabled() {
+		return nil, exception.ExceptionMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+
+	// the secret only becomes active once the user proves to have stored it
+	if err := s.redis.Set(ctx, s.pendingSecretKey(userUniqueID), secret, s.config.EnrollExpire).Err(); err != nil {
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+
+	return &dto.UserTOTPEnrollResDTO{
+		Secret:   secret,
+		URL:      totp.URL(s.config.Issuer, user.Email, secret),
+		ExpireAt: time.Now().Add(s.config.EnrollExpire).UnixMilli(),
+	}, nil
+}
+
+func (s *mfaService) ConfirmTOTP(ctx context.Context, userUniqueID int64, data dto.UserTOTPConfirmReqDTO) (*dto.UserTOTPConfirmResDTO, *exception.Exception) {
+	user, err := s.userRepo.FindByUniqueID(ctx, userUniqueID)
+	if err != nil {
+		return nil, exception.ExceptionUserNotFound.AppendDetails(err.Error())
+	}
+	if user.IsTOTPEnabled() {
+		return nil, exception.ExceptionMFAAlreadyEnabled
+	}
+
+	secret, err := s.redis.Get(ctx, s.pendingSecretKey(userUniqueID)).Result()
+	if err == redis.Nil {
+		return nil, exception.ExceptionMFAEnrollExpired
+	} else if err != nil {
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+
+	step, ok := totp.Validate(secret, data.Code, time.Now(), s.config.Skew)
+	if !ok {
+		return nil, exception.ExceptionMFACodeInvalid
+	}
+	if ex := s.markStepUsed(ctx, userUniqueID, step); ex != nil {
+		return nil, ex
+	}
+
+	codes, ex := s.regenerateRecoveryCodes(ctx, userUniqueID)
+	if ex != nil {
+		return nil, ex
+	}
+
+	if err := s.userRepo.UpdateByMap(ctx, user.ID, map[string]any{
+		"totp_secret":     secret,
+		"totp_enabled_at": time.Now(),
+	}); err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	s.redis.Del(ctx, s.pendingSecretKey(userUniqueID))
+
+	return &dto.UserTOTPConfirmResDTO{RecoveryCodes: codes}, nil
+}
+
+// DisableTOTP needs a current TOTP or recovery code, and the password or for
+// an account without one the reauth code.
+func (s *mfaService) DisableTOTP(ctx context.Context, userUniqueID int64, sessionID string, data dto.UserTOTPDisableReqDTO) *exception.Exception {
+	user, err := s.userRepo.FindByUniqueID(ctx, userUniqueID)
+	if err != nil {
+		return exception.ExceptionUserNotFound.AppendDetails(err.Error())
+	}
+	if !user.IsTOTPEnabled() {
+		return exception.ExceptionMFANotEnabled
+	}
+	if ex := s.reauth.Check(ctx, user, sessionID, data.Password, data.ReauthCode); ex != nil {
+		return ex
+	}
+	if ex := s.verifyCode(ctx, userUniqueID, user.TOTPSecret, data.Code); ex != nil {
+		return ex
+	}
+
+	if err := s.userRepo.UpdateByMap(ctx, user.ID, map[string]any{
+		"totp_secret":     "",
+		"totp_enabled_at": nil,
+	}); err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	if err := s.userRecoveryCodeRepo.DeleteByUserUniqueID(ctx, userUniqueID); err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	return nil
+}
+
+// CreateChallenge is returned by a first factor login instead of tokens when
+// the user has TOTP enabled. The mfa token is exchanged by LoginByMFA.
+func (s *mfaService) CreateChallenge(ctx context.Context, userUniqueID int64) (*dto.UserLoginResDTO, *exception.Exception) {
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+
+	key := s.challengeKey(token)
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, key, "user", userUniqueID, "attempts", 0)
+	pipe.Expire(ctx, key, s.config.ChallengeExpire)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+
+	return &dto.UserLoginResDTO{
+		MFARequired: true,
+		MFAToken:    token,
+		MFAExpireAt: time.Now().Add(s.config.ChallengeExpire).UnixMilli(),
+	}, nil
+}
+
+func (s *mfaService) LoginByMFA(ctx context.Context, data dto.UserMFALoginReqDTO, meta dto.ClientMeta) (*dto.UserTokenResDTO, *exception.Exception) {
+	key := s.challengeKey(data.MFAToken)
+
+	attempts, err := incrAttemptsScript.Run(ctx, s.redis, []string{key}).Int64()
+	if err != nil {
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	if attempts < 0 {
+		return nil, exception.ExceptionMFAChallengeInvalid
+	}
+	if attempts > int64(s.config.MaxAttempts) {
+		s.redis.Del(ctx, key)
+		return nil, exception.ExceptionMFAChallengeInvalid.AppendDetails("too many attempts")
+	}
+
+	value, err := s.redis.HGet(ctx, key, "user").Result()
+	if err == redis.Nil {
+		return nil, exception.ExceptionMFAChallengeInvalid
+	} else if err != nil {
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	userUniqueID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, exception.ExceptionMFAChallengeInvalid
+	}
+
+	user, err := s.userRepo.FindByUniqueID(ctx, userUniqueID)
+	if err != nil {
+		return nil, exception.ExceptionUserNotFound.AppendDetails(err.Error())
+	}
+	if !user.IsTOTPEnabled() {
+		return nil, exception.ExceptionMFAChallengeInvalid
+	}
+	// the account may have been disabled since the challenge was issued
+	if ex := userStatusException(user, time.Now()); ex != nil {
+		return nil, ex
+	}
+
+	if ex := s.verifyCode(ctx, userUniqueID, user.TOTPSecret, data.Code); ex != nil {
+		return nil, ex
+	}
+
+	// the challenge is single-use, a concurrent request may have consumed it
+	if deleted, err := s.redis.Del(ctx, key).Result(); err != nil || deleted == 0 {
+		return nil, exception.ExceptionMFAChallengeInvalid
+	}
+
+	return s.tokenService.IssueTokenPair(ctx, userUniqueID, meta)
+}
+
+// verifyCode accepts a current TOTP code or an unused recovery code.
+func (s *mfaService) verifyCode(ctx context.Context, userUniqueID int64, secret, code string) *exception.Exception {
+	code = strings.TrimSpace(code)
+	if step, ok := totp.Validate(secret, code, time.Now(), s.config.Skew); ok {
+		return s.markStepUsed(ctx, userUniqueID, step)
+	}
+
+	used, err := s.userRecoveryCodeRepo.UseCode(ctx, userUniqueID, s.hashRecoveryCode(userUniqueID, code))
+	if err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	if !used {
+		return exception.ExceptionMFACodeInvalid
+	}
+	s.logger.Info("Recovery code used", zap.Int64("userUniqueID", userUniqueID))
+	return nil
+}
+
+// markStepUsed records step as the last accepted one. A step not newer than
+// that was used before. It fails closed, a code is refused when the step
+// cannot be recorded since it could be replayed otherwise.
+func (s *mfaService) markStepUsed(ctx context.Context, userUniqueID int64, step int64) *exception.Exception {
+	ttl := time.Duration(2*s.config.Skew+2) * totp.Period * time.Second
+	ok, err := setIfGreaterScript.Run(ctx, s.redis, []string{s.lastStepKey(userUniqueID)}, step, ttl.Milliseconds()).Int()
+	if err != nil {
+		s.logger.Error("Failed to record totp step", zap.Int64("userUniqueID", userUniqueID), zap.Error(err))
+		return exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	if ok != 1 {
+		return exception.ExceptionMFACodeInvalid.AppendDetails("code already used")
+	}
+	return nil
+}
+
+func (s *mfaService) hashRecoveryCode(userUniqueID int64, code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return utils.SHA256Hex(fmt.Sprintf("%d:%s", userUniqueID, normalized))
+}
+
+func (s *mfaService) regenerateRecoveryCodes(ctx context.Context, userUniqueID int64) ([]string, *exception.Exception) {
+	const chars = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+	codes := make([]string, 0, s.config.RecoveryCodes)
+	hashes := make([]string, 0, s.config.RecoveryCodes)
+	for range s.config.RecoveryCodes {
+		raw := make([]byte, 10)
+		for i := range raw {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+			if err != nil {
+				return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+			}
+			raw[i] = chars[n.Int64()]
+		}
+		code := string(raw[:5]) + "-" + string(raw[5:])
+		codes = append(codes, code)
+		hashes = append(hashes, s.hashRecoveryCode(userUniqueID, code))
+	}
+
+	if err := s.userRecoveryCodeRepo.ReplaceByUserUniqueID(ctx, userUniqueID, hashes); err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	return codes, nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"super-web-server/internal/config"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/totp"
+	"testing"
+	"time"
+)
+
+type fakeUserRecoveryCodeRepo struct {
+	repo.UserRecoveryCodeRepo
+	deleted []int64
+}
+
+func (r *fakeUserRecoveryCodeRepo) DeleteByUserUniqueID(ctx context.Context, userUniqueID int64) error {
+	r.deleted = append(r.deleted, userUniqueID)
+	return nil
+}
+
+func newTestMFAService(t *testing.T, users ...*model.User) (*mfaService, func()) {
+	t.Helper()
+	client, server := newTestRedis(t)
+	s := &mfaService{
+		userRepo:             &fakeUserRepo{users: users},
+		userRecoveryCodeRepo: &fakeUserRecoveryCodeRepo{},
+		logger:               newTestLogger(),
+		redis:                client,
+		config:               config.MFAConfig{EnrollExpire: 10 * time.Minute, Skew: 1, MaxAttempts: 5, RecoveryCodes: 10},
+		reauth: newReauthenticator(&fakeUserSessionRepo{}, newVerifyCodeStore(client, config.VerifyCodeConfig{Length: 6, Expire: time.Minute, MaxAttempts: 3, ResendInterval: time.Minute}),
+			nil, &fakeMailer{}, newTestLogger(), 10*time.Minute, time.Minute, time.Minute),
+	}
+	return s, server.Close
+}
+
+func TestVerifyCodeRejectsReplay(t *testing.T) {
+	s, _ := newTestMFAService(t)
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := totp.Code(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if ex := s.verifyCode(ctx, 1, secret, code); ex != nil {
+		t.Fatalf("first verifyCode() = %v, want success", ex)
+	}
+	if ex := s.verifyCode(ctx, 1, secret, code); !ex.Is(exception.ExceptionMFACodeInvalid) {
+		t.Errorf("replayed verifyCode() = %v, want code invalid", ex)
+	}
+	// the steps are tracked per user
+	if ex := s.verifyCode(ctx, 2, secret, code); ex != nil {
+		t.Errorf("verifyCode() of another user = %v, want success", ex)
+	}
+}
+
+func TestVerifyCodeFailsClosed(t *testing.T) {
+	s, stopRedis := newTestMFAService(t)
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := totp.Code(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stopRedis()
+	if ex := s.verifyCode(context.Background(), 1, secret, code); !ex.Is(exception.ExceptionInternalServerError) {
+		t.Errorf("verifyCode() without redis = %v, want internal server error", ex)
+	}
+}
+
+func TestConfirmTOTPEnrollmentExpired(t *testing.T) {
+	s, _ := newTestMFAService(t, &model.User{UniqueID: 1})
+	_, ex := s.ConfirmTOTP(context.Background(), 1, dto.UserTOTPConfirmReqDTO{Code: "123456"})
+	if !ex.Is(exception.ExceptionMFAEnrollExpired) {
+		t.Errorf("ConfirmTOTP() = %v, want enrollment expired", ex)
+	}
+}
+
+func TestDisableTOTPPasswordless(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enabledAt := time.Now()
+	// created through OAuth, the account never had a password
+	user := &model.User{BaseModel: model.BaseModel{ID: 1}, UniqueID: 1, Email: "user@example.com", TOTPSecret: secret, TOTPEnabledAt: &enabledAt}
+	s, _ := newTestMFAService(t, user)
+	code, err := totp.Code(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ex := s.DisableTOTP(context.Background(), 1, "", dto.UserTOTPDisableReqDTO{Code: code})
+	if ex == nil || !ex.Is(exception.ExceptionReauthRequired) {
+		t.Fatalf("DisableTOTP() without a reauth code = %v, want %v", ex, exception.ExceptionReauthRequired)
+	}
+
+	if _, ex := s.reauth.SendCode(context.Background(), user); ex != nil {
+		t.Fatalf("SendCode: %v", ex)
+	}
+	reauthCode := codeFromMail(t, s.reauth.mailer.(*fakeMailer).sent[0].Body)
+	if ex := s.DisableTOTP(context.Background(), 1, "", dto.UserTOTPDisableReqDTO{Code: code, ReauthCode: reauthCode}); ex != nil {
+		t.Fatalf("DisableTOTP: %v", ex)
+	}
+	updates := s.userRepo.(*fakeUserRepo).updates
+	if len(updates) != 1 || updates[0]["totp_secret"] != "" {
+		t.Errorf("updates = %v, want the secret cleared", updates)
+	}
+	if deleted := s.userRecoveryCodeRepo.(*fakeUserRecoveryCodeRepo).deleted; len(deleted) != 1 || deleted[0] != 1 {
+		t.Errorf("deleted recovery codes of %v, want [1]", deleted)
+	}
+}
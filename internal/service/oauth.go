@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"super-web-server/internal/config"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/oidc"
+	"super-web-server/pkg/snowflake"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type OAuthService interface {
+	ListProviders(ctx context.Context) []*dto.UserOAuthProviderResDTO
+	Authorize(ctx context.Context, provider string, linkUserUniqueID int64) (*dto.UserOAuthAuthorizeResDTO, *exception.Exception)
+	Callback(ctx context.Context, provider, browserState string, data dto.UserOAuthCallbackReqDTO, meta dto.ClientMeta) (*dto.UserLoginResDTO, *exception.Exception)
+	Link(ctx context.Context, userUniqueID int64, provider, browserState string, data dto.UserOAuthCallbackReqDTO) *exception.Exception
+}
+
+// oauthState is kept in Redis between the redirect to the provider and the
+// callback. LinkUserUniqueID is set when a signed in user links the provider
+// instead of signing in with it.
+type oauthState struct {
+	Provider         string `json:"provider"`
+	CodeVerifier     string `json:"codeVerifier"`
+	Nonce            string `json:"nonce"`
+	LinkUserUniqueID int64  `json:"linkUserUniqueId,omitempty"`
+}
+
+type oauthService struct {
+	userRepo         repo.UserRepo
+	userRoleRepo     repo.UserRoleRepo
+	userIdentityRepo repo.UserIdentityRepo
+	tokenService     TokenService
+	mfaService       MFAService
+	providers        map[string]*oidc.Provider
+	providerNames    []string
+	logger           *logger.Logger
+	redis            *redis.Client
+	snowflake        *snowflake.Snowflake
+	config           config.OIDCConfig
+}
+
+func NewOAuthService(userRepo repo.UserRepo, userRoleRepo repo.UserRoleRepo, userIdentityRepo repo.UserIdentityRepo, tokenService TokenService, mfaService MFAService, providers []*oidc.Provider, logger *logger.Logger, redis *redis.Client, snowflake *snowflake.Snowflake, config config.OIDCConfig) OAuthService {
+	logger.Info("NewOAuthService initialized successfully")
+	s := &oauthService{
+		userRepo:         userRepo,
+		userRoleRepo:     userRoleRepo,
+		userIdentityRepo: userIdentityRepo,
+		tokenService:     tokenService,
+		mfaService:       mfaService,
+		providers:        make(map[string]*oidc.Provider, len(providers)),
+		logger:           logger,
+		redis:            redis,
+		snowflake:        snowflake,
+		config:           config,
+	}
+	for _, provider := range providers {
+		s.providers[provider.Name()] = provider
+		s.providerNames = append(s.providerNames, provider.Name())
+	}
+	return s
+}
+
+func (s *oauthService) stateKey(state string) string {
+	return fmt.Sprintf("auth:oauth:state:%s", state)
+}
+
+func (s *oauthService) ListProviders(ctx context.Context) []*dto.UserOAuthProviderResDTO {
+	res := make([]*dto.UserOAuthProviderResDTO, 0, len(s.providerNames))
+	for _, name := range s.providerNames {
+		res = append(res, &dto.UserOAuthProviderResDTO{Name: name})
+	}
+	return res
+}
+
+// Authorize starts a flow at the provider. The state is returned to be set
+// as a cookie, the callback only accepts it from the browser that started
+// the flow. linkUserUniqueID is 0 to sign in, or the user linking the
+// provider to their account.
+func (s *oauthService) Authorize(ctx context.Context, providerName string, linkUserUniqueID int64) (*dto.UserOAuthAuthorizeResDTO, *exception.Exception) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, exception.ExceptionOAuthProviderNotFound
+	}
+
+	var values [3]string
+	for i := range values {
+		value, err := oidc.RandomString()
+		if err != nil {
+			return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+		}
+		values[i] = value
+	}
+	state, codeVerifier, nonce := values[0], values[1], values[2]
+
+	url, err := provider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallenge(codeVerifier))
+	if err != nil {
+		s.logger.Error("oauth authorize failed", zap.String("provider", providerName), zap.Error(err))
+		return nil, exception.ExceptionBadGateway.AppendDetails("identity provider unavailable")
+	}
+
+	payload, err := json.Marshal(oauthState{
+		Provider:         providerName,
+		CodeVerifier:     codeVerifier,
+		Nonce:            nonce,
+		LinkUserUniqueID: linkUserUniqueID,
+	})
+	if err != nil {
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	if err := s.redis.Set(ctx, s.stateKey(state), payload, s.config.StateExpire).Err(); err != nil {
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+
+	return &dto.UserOAuthAuthorizeResDTO{
+		URL:      url,
+		State:    state,
+		ExpireAt: time.Now().Add(s.config.StateExpire).UnixMilli(),
+	}, nil
+}
+
+// Callback signs in with the code the provider redirected back with.
+// browserState is the state cookie set by Authorize.
+func (s *oauthService) Callback(ctx context.Context, providerName, browserState string, data dto.UserOAuthCallbackReqDTO, meta dto.ClientMeta) (*dto.UserLoginResDTO, *exception.Exception) {
+	state, claims, ex := s.exchange(ctx, providerName, browserState, data)
+	if ex != nil {
+		return nil, ex
+	}
+	// a link flow must not turn into a sign in
+	if state.LinkUserUniqueID != 0 {
+		return nil, exception.ExceptionOAuthStateInvalid
+	}
+
+	user, ex := s.resolveUser(ctx, providerName, claims)
+	if ex != nil {
+		return nil, ex
+	}
+
+	return completeLogin(ctx, s.tokenService, s.mfaService, user, meta)
+}
+
+// Link adds the identity the code signs in to the signed in user. It is the
+// only way an identity joins an existing account, the user has proved to own
+// both.
+func (s *oauthService) Link(ctx context.Context, userUniqueID int64, providerName, browserState string, data dto.UserOAuthCallbackReqDTO) *exception.Exception {
+	state, claims, ex := s.exchange(ctx, providerName, browserState, data)
+	if ex != nil {
+		return ex
+	}
+	if state.LinkUserUniqueID != userUniqueID {
+		return exception.ExceptionOAuthStateInvalid
+	}
+
+	identity, err := s.userIdentityRepo.FindByProviderSubject(ctx, providerName, claims.Subject)
+	if err == nil {
+		if identity.UserUniqueID == userUniqueID {
+			return nil
+		}
+		return exception.ExceptionOAuthIdentityLinked
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	identity = &model.UserIdentity{
+		UserUniqueID: userUniqueID,
+		Provider:     providerName,
+		Subject:      claims.Subject,
+		Email:        claims.Email,
+	}
+	if err := s.userIdentityRepo.Create(ctx, identity); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return exception.ExceptionOAuthIdentityLinked
+		}
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	s.logger.Info("oauth identity linked", zap.String("provider", providerName), zap.Int64("userUniqueId", userUniqueID))
+	return nil
+}
+
+// exchange redeems the code and verifies the ID token. The state has to be
+// the one of the browser presenting it, so nobody can complete a flow they
+// started in someone else's browser.
+func (s *oauthService) exchange(ctx context.Context, providerName, browserState string, data dto.UserOAuthCallbackReqDTO) (*oauthState, *oidc.IDTokenClaims, *exception.Exception) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, nil, exception.ExceptionOAuthProviderNotFound
+	}
+	if browserState == "" || subtle.ConstantTimeCompare([]byte(browserState), []byte(data.State)) != 1 {
+		return nil, nil, exception.ExceptionOAuthStateInvalid
+	}
+
+	// GETDEL makes the state single use
+	payload, err := s.redis.GetDel(ctx, s.stateKey(data.State)).Bytes()
+	if err == redis.Nil {
+		return nil, nil, exception.ExceptionOAuthStateInvalid
+	} else if err != nil {
+		return nil, nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	var state oauthState
+	if err := json.Unmarshal(payload, &state); err != nil || state.Provider != providerName {
+		return nil, nil, exception.ExceptionOAuthStateInvalid
+	}
+
+	token, err := provider.Exchange(ctx, data.Code, state.CodeVerifier)
+	if err != nil {
+		s.logger.Warn("oauth code exchange failed", zap.String("provider", providerName), zap.Error(err))
+		return nil, nil, exception.ExceptionOAuthLoginFailed
+	}
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, state.Nonce)
+	if err != nil {
+		s.logger.Warn("oauth id token rejected", zap.String("provider", providerName), zap.Error(err))
+		return nil, nil, exception.ExceptionOAuthLoginFailed
+	}
+	return &state, claims, nil
+}
+
+// resolveUser finds the user of an external identity. An unknown identity
+// gets a new user when the provider has verified the email. It is never
+// joined to an existing user with the same email, whoever controls the email
+// at the provider would take the account over; the owner links it from their
+// settings instead.
+func (s *oauthService) resolveUser(ctx context.Context, providerName string, claims *oidc.IDTokenClaims) (*model.User, *exception.Exception) {
+	identity, err := s.userIdentityRepo.FindByProviderSubject(ctx, providerName, claims.Subject)
+	if err == nil {
+		user, err := s.userRepo.FindByUniqueID(ctx, identity.UserUniqueID)
+		if err != nil {
+			return nil, exception.ExceptionUserNotFound.AppendDetails(err.Error())
+		}
+		return user, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	if claims.Email == "" || !claims.IsEmailVerified() {
+		return nil, exception.ExceptionOAuthEmailNotVerified
+	}
+
+	_, err = s.userRepo.FindByEmail(ctx, claims.Email)
+	if err == nil {
+		return nil, exception.ExceptionOAuthLinkRequired
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	return s.createUser(ctx, claims, &model.UserIdentity{
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+}
+
+func (s *oauthService) createUser(ctx context.Context, claims *oidc.IDTokenClaims, identity *model.UserIdentity) (*model.User, *exception.Exception) {
+	userRole, err := s.userRoleRepo.FindByCode(ctx, model.UserRoleCodeUser)
+	if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	now := time.Now()
+	user := &model.User{
+		UniqueID:        s.snowflake.GenerateID(),
+		Email:           claims.Email,
+		Nickname:        claims.Name,
+		AvatarURL:       claims.Picture,
+		EmailVerifiedAt: &now,
+		Roles:           []*model.UserRole{userRole},
+	}
+
+	if err := s.userIdentityRepo.CreateWithUser(ctx, user, identity); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, exception.ExceptionOAuthLoginFailed.AppendDetails("account created concurrently, please retry")
+		}
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	s.logger.Info("oauth user created", zap.String("provider", identity.Provider), zap.Int64("userUniqueId", user.UniqueID))
+	return user, nil
+}
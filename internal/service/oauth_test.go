@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"super-web-server/internal/config"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/oidc"
+	"super-web-server/pkg/oidc/oidctest"
+	"super-web-server/pkg/snowflake"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const testProvider = "test"
+
+type fakeUserIdentityRepo struct {
+	repo.UserIdentityRepo
+	identities []*model.UserIdentity
+	userRepo   *fakeUserRepo
+}
+
+func (r *fakeUserIdentityRepo) FindByProviderSubject(ctx context.Context, provider, subject string) (*model.UserIdentity, error) {
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUserIdentityRepo) Create(ctx context.Context, entity *model.UserIdentity) error {
+	if _, err := r.FindByProviderSubject(ctx, entity.Provider, entity.Subject); err == nil {
+		return gorm.ErrDuplicatedKey
+	}
+	r.identities = append(r.identities, entity)
+	return nil
+}
+
+func (r *fakeUserIdentityRepo) CreateWithUser(ctx context.Context, user *model.User, identity *model.UserIdentity) error {
+	r.userRepo.users = append(r.userRepo.users, user)
+	identity.UserUniqueID = user.UniqueID
+	return r.Create(ctx, identity)
+}
+
+// fakeTokenService issues fixed tokens for the users it is asked to sign in.
+type fakeTokenService struct {
+	TokenService
+	issued []int64
+}
+
+func (s *fakeTokenService) IssueTokenPair(ctx context.Context, userUniqueID int64, meta dto.ClientMeta) (*dto.UserTokenResDTO, *exception.Exception) {
+	s.issued = append(s.issued, userUniqueID)
+	return &dto.UserTokenResDTO{Token: "access", RefreshToken: "refresh"}, nil
+}
+
+type oauthTest struct {
+	service    *oauthService
+	provider   *oidctest.Server
+	users      *fakeUserRepo
+	identities *fakeUserIdentityRepo
+	tokens     *fakeTokenService
+}
+
+func newOAuthTest(t *testing.T) *oauthTest {
+	t.Helper()
+	provider := oidctest.NewServer("client")
+	t.Cleanup(provider.Close)
+
+	rdb, _ := newTestRedis(t)
+	node, err := snowflake.NewSnowflake(1)
+	if err != nil {
+		t.Fatalf("NewSnowflake: %v", err)
+	}
+	users := &fakeUserRepo{}
+	identities := &fakeUserIdentityRepo{userRepo: users}
+	tokens := &fakeTokenService{}
+	service := NewOAuthService(users, &fakeUserRoleRepo{roles: []*model.UserRole{{Code: model.UserRoleCodeUser}}}, identities, tokens, nil, []*oidc.Provider{
+		oidc.NewProvider(oidc.Config{
+			Name:        testProvider,
+			Issuer:      provider.Issuer(),
+			ClientID:    provider.ClientID,
+			RedirectURL: "http://localhost/callback",
+		}, provider.Client()),
+	}, newTestLogger(), rdb, node, config.OIDCConfig{StateExpire: time.Minute}).(*oauthService)
+
+	return &oauthTest{service: service, provider: provider, users: users, identities: identities, tokens: tokens}
+}
+
+// authorize starts a flow and lets identity consent at the provider, it
+// returns the state and the code the browser would come back with.
+func (o *oauthTest) authorize(t *testing.T, identity oidctest.Identity, linkUserUniqueID int64) (string, string) {
+	t.Helper()
+	ctx := context.Background()
+	res, ex := o.service.Authorize(ctx, testProvider, linkUserUniqueID)
+	if ex != nil {
+		t.Fatalf("Authorize: %v", ex)
+	}
+	payload, err := o.service.redis.Get(ctx, o.service.stateKey(res.State)).Bytes()
+	if err != nil {
+		t.Fatalf("read state: %v", err)
+	}
+	var state oauthState
+	if err := json.Unmarshal(payload, &state); err != nil {
+		t.Fatalf("unmarshal state: %v", err)
+	}
+	return res.State, o.provider.Authorize(identity, state.Nonce, oidc.CodeChallenge(state.CodeVerifier))
+}
+
+func TestOAuthCallbackCreatesUser(t *testing.T) {
+	o := newOAuthTest(t)
+	state, code := o.authorize(t, oidctest.Identity{Subject: "sub-1", Email: "new@example.com", EmailVerified: true, Name: "New"}, 0)
+
+	res, ex := o.service.Callback(context.Background(), testProvider, state, dto.UserOAuthCallbackReqDTO{Code: code, State: state}, dto.ClientMeta{})
+	if ex != nil {
+		t.Fatalf("Callback: %v", ex)
+	}
+	if res.UserTokenResDTO == nil || res.Token != "access" {
+		t.Fatalf("Callback = %+v, want tokens", res)
+	}
+	if len(o.users.users) != 1 || o.users.users[0].Email != "new@example.com" {
+		t.Fatalf("users = %+v, want the new user", o.users.users)
+	}
+	if len(o.identities.identities) != 1 || o.identities.identities[0].UserUniqueID != o.users.users[0].UniqueID {
+		t.Fatalf("identities = %+v, want one of the new user", o.identities.identities)
+	}
+
+	// the known identity signs in to the same user
+	state, code = o.authorize(t, oidctest.Identity{Subject: "sub-1", Email: "new@example.com", EmailVerified: true}, 0)
+	if _, ex := o.service.Callback(context.Background(), testProvider, state, dto.UserOAuthCallbackReqDTO{Code: code, State: state}, dto.ClientMeta{}); ex != nil {
+		t.Fatalf("second Callback: %v", ex)
+	}
+	if len(o.users.users) != 1 || len(o.tokens.issued) != 2 || o.tokens.issued[1] != o.users.users[0].UniqueID {
+		t.Errorf("issued = %v for users %d, want the same user twice", o.tokens.issued, len(o.users.users))
+	}
+}
+
+func TestOAuthCallbackDoesNotLinkByEmail(t *testing.T) {
+	o := newOAuthTest(t)
+	local := &model.User{UniqueID: 7, Email: "taken@example.com", Password: "hash"}
+	o.users.users = append(o.users.users, local)
+	state, code := o.authorize(t, oidctest.Identity{Subject: "attacker", Email: "taken@example.com", EmailVerified: true}, 0)
+
+	_, ex := o.service.Callback(context.Background(), testProvider, state, dto.UserOAuthCallbackReqDTO{Code: code, State: state}, dto.ClientMeta{})
+	if ex == nil || !ex.Is(exception.ExceptionOAuthLinkRequired) {
+		t.Fatalf("Callback = %v, want %v", ex, exception.ExceptionOAuthLinkRequired)
+	}
+	if len(o.identities.identities) != 0 || len(o.tokens.issued) != 0 || len(o.users.updates) != 0 {
+		t.Errorf("identities %v, issued %v, updates %v, want the local user untouched", o.identities.identities, o.tokens.issued, o.users.updates)
+	}
+	if local.Password != "hash" {
+		t.Errorf("password = %q, want it kept", local.Password)
+	}
+}
+
+func TestOAuthCallbackRejectsState(t *testing.T) {
+	identity := oidctest.Identity{Subject: "sub-1", Email: "new@example.com", EmailVerified: true}
+	tests := []struct {
+		name         string
+		link         int64
+		browserState func(state string) string
+	}{
+		{"no cookie", 0, func(string) string { return "" }},
+		{"other browser", 0, func(string) string { return "someone-else" }},
+		{"link flow", 7, func(state string) string { return state }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newOAuthTest(t)
+			state, code := o.authorize(t, identity, tt.link)
+			_, ex := o.service.Callback(context.Background(), testProvider, tt.browserState(state), dto.UserOAuthCallbackReqDTO{Code: code, State: state}, dto.ClientMeta{})
+			if ex == nil || !ex.Is(exception.ExceptionOAuthStateInvalid) {
+				t.Fatalf("Callback = %v, want %v", ex, exception.ExceptionOAuthStateInvalid)
+			}
+			if len(o.users.users) != 0 || len(o.tokens.issued) != 0 {
+				t.Errorf("users %v, issued %v, want no sign in", o.users.users, o.tokens.issued)
+			}
+		})
+	}
+}
+
+func TestOAuthLink(t *testing.T) {
+	identity := oidctest.Identity{Subject: "sub-1", Email: "other@example.com", EmailVerified: true}
+	tests := []struct {
+		name     string
+		existing *model.UserIdentity
+		flowUser int64
+		want     *exception.Exception
+	}{
+		{"links", nil, 7, nil},
+		{"already linked to the user", &model.UserIdentity{UserUniqueID: 7, Provider: testProvider, Subject: "sub-1"}, 7, nil},
+		{"linked to another user", &model.UserIdentity{UserUniqueID: 8, Provider: testProvider, Subject: "sub-1"}, 7, exception.ExceptionOAuthIdentityLinked},
+		{"flow of another user", nil, 8, exception.ExceptionOAuthStateInvalid},
+		{"sign in flow", nil, 0, exception.ExceptionOAuthStateInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newOAuthTest(t)
+			if tt.existing != nil {
+				o.identities.identities = append(o.identities.identities, tt.existing)
+			}
+			state, code := o.authorize(t, identity, tt.flowUser)
+
+			ex := o.service.Link(context.Background(), 7, testProvider, state, dto.UserOAuthCallbackReqDTO{Code: code, State: state})
+			if tt.want == nil {
+				if ex != nil {
+					t.Fatalf("Link: %v", ex)
+				}
+				got, err := o.identities.FindByProviderSubject(context.Background(), testProvider, "sub-1")
+				if err != nil || got.UserUniqueID != 7 {
+					t.Fatalf("identity = %+v, %v, want linked to 7", got, err)
+				}
+				return
+			}
+			if ex == nil || !ex.Is(tt.want) {
+				t.Fatalf("Link = %v, want %v", ex, tt.want)
+			}
+			if tt.existing == nil && len(o.identities.identities) != 0 {
+				t.Errorf("identities = %+v, want none", o.identities.identities)
+			}
+		})
+	}
+}
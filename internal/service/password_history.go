@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/password"
+
+	"go.uber.org/zap"
+)
+
+// passwordHistory keeps the last limit password hashes of every user and
+// refuses a new password that matches one of them. A limit of 0 turns it off.
+type passwordHistory struct {
+	repo   repo.UserPasswordHistoryRepo
+	hasher *password.Hasher
+	logger *logger.Logger
+	limit  int
+}
+
+func newPasswordHistory(repo repo.UserPasswordHistoryRepo, hasher *password.Hasher, logger *logger.Logger, limit int) *passwordHistory {
+	return &passwordHistory{
+		repo:   repo,
+		hasher: hasher,
+		logger: logger,
+		limit:  limit,
+	}
+}
+
+// CheckReuse compares pw with the current password of the user and the
+// recent ones. The current password is checked on its own because users
+// created before the history existed have no entries yet.
+func (h *passwordHistory) CheckReuse(ctx context.Context, user *model.User, pw string) *exception.Exception {
+	if h.limit <= 0 {
+		return nil
+	}
+
+	// an empty hash, an account without password, never matches
+	hashes := []string{user.Password}
+
+	entries, err := h.repo.FindRecentByUserUniqueID(ctx, user.UniqueID, h.limit)
+	if err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	for _, entry := range entries {
+		if entry.PasswordHash != user.Password {
+			hashes = append(hashes, entry.PasswordHash)
+		}
+	}
+
+	for _, hash := range hashes {
+		if ok, _, _ := h.hasher.Verify(pw, hash); ok {
+			return exception.ExceptionPasswordReused
+		}
+	}
+	return nil
+}
+
+// Record adds hash to the history of the user and drops the entries past the
+// limit. A failure only weakens the reuse check, so it is logged and ignored.
+func (h *passwordHistory) Record(ctx context.Context, userUniqueID int64, hash string) {
+	if h.limit <= 0 {
+		return
+	}
+
+	if err := h.repo.Create(ctx, &model.UserPasswordHistory{UserUniqueID: userUniqueID, PasswordHash: hash}); err != nil {
+		h.logger.Error("Failed to record password history", zap.Int64("uniqueID", userUniqueID), zap.Error(err))
+		return
+	}
+	if err := h.repo.PruneByUserUniqueID(ctx, userUniqueID, h.limit); err != nil {
+		h.logger.Error("Failed to prune password history", zap.Int64("uniqueID", userUniqueID), zap.Error(err))
+	}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	appctx "super-web-server/internal/ctx"
+	"super-web-server/internal/exception"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/policy"
+
+	"go.uber.org/zap"
+)
+
+// PolicyService answers whether the user may act on a record, where role
+// checks only tell what the user is. The rules come from policy.file.
+type PolicyService interface {
+	// Authorize checks the user the request was authenticated as, ctx has to
+	// be the request context.
+	Authorize(ctx context.Context, action policy.Action, resource policy.Resource) *exception.Exception
+}
+
+type policyService struct {
+	engine    *policy.Engine
+	roleCache *userRoleCache
+	logger    *logger.Logger
+}
+
+func NewPolicyService(engine *policy.Engine, roleCache *userRoleCache, logger *logger.Logger) PolicyService {
+	logger.Info("NewPolicyService initialized successfully")
+	return &policyService{
+		engine:    engine,
+		roleCache: roleCache,
+		logger:    logger,
+	}
+}
+
+func (s *policyService) Authorize(ctx context.Context, action policy.Action, resource policy.Resource) *exception.Exception {
+	userUniqueID, _ := ctx.Value(appctx.USER_UNIQUE_ID_KEY).(int64)
+	if userUniqueID == 0 {
+		return exception.ExceptionUnauthorized
+	}
+	return s.authorize(ctx, userUniqueID, action, resource)
+}
+
+// authorize returns ExceptionForbidden with the reason when the policy of the
+// resource denies the action to the user.
+func (s *policyService) authorize(ctx context.Context, userUniqueID int64, action policy.Action, resource policy.Resource) *exception.Exception {
+	roles, ex := s.roleCache.Roles(ctx, userUniqueID)
+	if ex != nil {
+		return ex
+	}
+	subject := policy.Subject{UniqueID: userUniqueID, Roles: make([]string, 0, len(roles))}
+	for _, role := range roles {
+		subject.Roles = append(subject.Roles, string(role.Code))
+	}
+
+	decision := s.engine.Evaluate(ctx, subject, action, resource)
+	if !decision.Allowed {
+		s.logger.Debug("policy denied",
+			zap.Int64("userUniqueId", userUniqueID),
+			zap.String("action", string(action)),
+			zap.String("resource", resource.Type),
+			zap.String("reason", decision.Reason),
+		)
+		return exception.ExceptionForbidden.AppendDetails(decision.Reason)
+	}
+	return nil
+}
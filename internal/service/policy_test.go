@@ -0,0 +1,66 @@
+package service
+
+import (
+	"net/http/httptest"
+	appctx "super-web-server/internal/ctx"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/pkg/policy"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPolicyAuthorize(t *testing.T) {
+	engine, err := policy.New(policy.Config{Resources: map[string]policy.ResourcePolicy{
+		"file": {Policy: policy.KindOwner, Rules: []policy.Rule{
+			{Effect: policy.EffectAllow, Actions: []string{"read"}, Roles: []string{string(model.UserRoleCodeAdmin)}},
+		}},
+	}})
+	if err != nil {
+		t.Fatalf("policy.New: %v", err)
+	}
+	user := &model.UserRole{BaseModel: model.BaseModel{ID: 2}, Code: model.UserRoleCodeUser}
+	// the rule names the admin role, held here through a parent
+	admin := &model.UserRole{BaseModel: model.BaseModel{ID: 1}, Code: model.UserRoleCodeAdmin, Parents: []*model.UserRole{user}}
+	users := &fakeUserRepo{users: []*model.User{
+		{UniqueID: testAdmin, Roles: []*model.UserRole{admin}},
+		{UniqueID: testUser, Roles: []*model.UserRole{user}},
+	}}
+	roleRepo := &fakeUserRoleRepo{roles: []*model.UserRole{admin, user}}
+	s := NewPolicyService(engine, newTestRoleCache(t, users, roleRepo, &fakePermissionRepo{}), newTestLogger())
+
+	owned := policy.Resource{Type: "file", OwnerUniqueID: testUser}
+	tests := []struct {
+		name     string
+		subject  int64
+		action   policy.Action
+		resource policy.Resource
+		want     *exception.Exception
+	}{
+		{"owner", testUser, policy.ActionDelete, owned, nil},
+		{"role rule", testAdmin, policy.ActionRead, owned, nil},
+		{"role rule does not cover delete", testAdmin, policy.ActionDelete, owned, exception.ExceptionForbidden},
+		{"another user", testUser, policy.ActionRead, policy.Resource{Type: "file", OwnerUniqueID: testAdmin}, exception.ExceptionForbidden},
+		{"not signed in", 0, policy.ActionRead, owned, exception.ExceptionUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the subject comes from the request, as set by the auth middleware
+			gtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			if tt.subject != 0 {
+				appctx.NewAppCtx(gtx).SetUserUniqueID(tt.subject)
+			}
+			ex := s.Authorize(gtx, tt.action, tt.resource)
+			if tt.want == nil {
+				if ex != nil {
+					t.Fatalf("Authorize: %v", ex)
+				}
+				return
+			}
+			if ex == nil || !ex.Is(tt.want) {
+				t.Fatalf("Authorize = %v, want %v", ex, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"super-web-server/internal/config"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/imaging"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/mailer"
+	"super-web-server/pkg/password"
+	"super-web-server/pkg/sms"
+	"super-web-server/pkg/utils"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ProfileService interface {
+	UpdateProfile(ctx context.Context, userUniqueID int64, data dto.UserProfileUpdateReqDTO) (*model.User, *exception.Exception)
+	SendMobileCode(ctx context.Context, userUniqueID int64, data dto.UserProfileMobileCodeReqDTO) (*dto.UserVerifyCodeResDTO, *exception.Exception)
+	ChangePassword(ctx context.Context, userUniqueID int64, sessionID string, data dto.UserPasswordChangeReqDTO) *exception.Exception
+	UpdateAvatar(ctx context.Context, userUniqueID int64, file io.Reader) (*model.User, *exception.Exception)
+	SendReauthCode(ctx context.Context, userUniqueID int64) (*dto.UserVerifyCodeResDTO, *exception.Exception)
+	RequestEmailChange(ctx context.Context, userUniqueID int64, sessionID string, data dto.UserEmailChangeReqDTO) (*dto.UserVerifyCodeResDTO, *exception.Exception)
+	ConfirmEmailChange(ctx context.Context, userUniqueID int64, data dto.UserEmailChangeConfirmReqDTO) (*model.User, *exception.Exception)
+	RevertEmailChange(ctx context.Context, data dto.UserEmailRevertReqDTO) *exception.Exception
+}
+
+type profileService struct {
+	userRepo        repo.UserRepo
+	userSessionRepo repo.UserSessionRepo
+	tokenService    TokenService
+	logger          *logger.Logger
+	redis           *redis.Client
+	mailer          mailer.Mailer
+	sms             sms.Provider
+	hasher          *password.Hasher
+	config          *config.Config
+	verifyCode      *verifyCodeStore
+	history         *passwordHistory
+	reauth          *reauthenticator
+	statusCache     *userStatusCache
+}
+
+func NewProfileService(userRepo repo.UserRepo, userSessionRepo repo.UserSessionRepo, passwordHistoryRepo repo.UserPasswordHistoryRepo, tokenService TokenService, logger *logger.Logger, redis *redis.Client, mailer mailer.Mailer, sms sms.Provider, hasher *password.Hasher, config *config.Config) ProfileService {
+	logger.Info("NewProfileService initialized successfully")
+	verifyCode := newVerifyCodeStore(redis, config.VerifyCode)
+	return &profileService{
+		userRepo:        userRepo,
+		userSessionRepo: userSessionRepo,
+		tokenService:    tokenService,
+		logger:          logger,
+		redis:           redis,
+		mailer:          mailer,
+		sms:             sms,
+		hasher:          hasher,
+		config:          config,
+		verifyCode:      verifyCode,
+		history:         newPasswordHistory(passwordHistoryRepo, hasher, logger, config.PasswordPolicy.History),
+		reauth:          newReauthenticator(userSessionRepo, verifyCode, hasher, mailer, logger, config.Reauth.MaxAge, config.VerifyCode.Expire, config.VerifyCode.ResendInterval),
+		statusCache:     newUserStatusCache(userRepo, redis, logger),
+	}
+}
+
+func (s *profileService) findUser(ctx context.Context, userUniqueID int64) (*model.User, *exception.Exception) {
+	user, err := s.userRepo.FindByUniqueID(ctx, userUniqueID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, exception.ExceptionUserNotFound
+	} else if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	return user, nil
+}
+
+// mobileCodeTarget binds a mobile code to the user that requested it.
+func (s *profileService) mobileCodeTarget(userUniqueID int64, mobile string) string {
+	return fmt.Sprintf("%d:%s", userUniqueID, mobile)
+}
+
+// UpdateProfile applies the whitelisted fields. The mobile is a login
+// identifier, so a new one has to be confirmed with a code sent to it.
+func (s *profileService) UpdateProfile(ctx context.Context, userUniqueID int64, data dto.UserProfileUpdateReqDTO) (*model.User, *exception.Exception) {
+	user, ex := s.findUser(ctx, userUniqueID)
+	if ex != nil {
+		return nil, ex
+	}
+
+	updates := map[string]any{}
+	if data.Nickname != nil {
+		updates["nickname"] = *data.Nickname
+	}
+	if data.Mobile != nil && *data.Mobile != user.MobileNumber() {
+		if data.MobileCode == "" {
+			return nil, exception.ExceptionInvalidParam.AppendDetails("mobileCode is required to change the mobile")
+		}
+		if ex := s.checkMobileFree(ctx, *data.Mobile, userUniqueID); ex != nil {
+			return nil, ex
+		}
+		if ex := s.verifyCode.Verify(ctx, VerifyCodeSceneProfileMobile, s.mobileCodeTarget(userUniqueID, *data.Mobile), data.MobileCode); ex != nil {
+			return nil, ex
+		}
+		updates["mobile"] = model.NullableString(*data.Mobile)
+	}
+
+	if len(updates) > 0 {
+		// the unique index on mobile decides when two users bind it at once
+		if err := s.userRepo.UpdateByMap(ctx, user.ID, updates); errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, exception.ExceptionUserMobileExists
+		} else if err != nil {
+			return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+		}
+	}
+
+	return s.findUser(ctx, userUniqueID)
+}
+
+func (s *profileService) checkMobileFree(ctx context.Context, mobile string, userUniqueID int64) *exception.Exception {
+	user, err := s.userRepo.FindByMobile(ctx, mobile)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	if user.UniqueID != userUniqueID {
+		return exception.ExceptionUserMobileExists
+	}
+	return nil
+}
+
+func (s *profileService) SendMobileCode(ctx context.Context, userUniqueID int64, data dto.UserProfileMobileCodeReqDTO) (*dto.UserVerifyCodeResDTO, *exception.Exception) {
+	if ex := s.checkMobileFree(ctx, data.Mobile, userUniqueID); ex != nil {
+		return nil, ex
+	}
+
+	code, expireAt, ex := s.verifyCode.Issue(ctx, VerifyCodeSceneProfileMobile, s.mobileCodeTarget(userUniqueID, data.Mobile))
+	if ex != nil {
+		return nil, ex
+	}
+
+	message := fmt.Sprintf("Your verification code is %s, it expires in %s.", code, s.config.VerifyCode.Expire)
+	if err := s.sms.Send(ctx, data.Mobile, message); err != nil {
+		s.logger.Error("Failed to send mobile verification sms", zap.String("mobile", data.Mobile), zap.Error(err))
+		return nil, exception.ExceptionServiceError.AppendDetails("send sms failed")
+	}
+
+	return &dto.UserVerifyCodeResDTO{
+		CodeExpireAt:    expireAt.UnixMilli(),
+		CodeResendAfter: time.Now().Add(s.config.VerifyCode.ResendInterval).UnixMilli(),
+	}, nil
+}
+
+// SendReauthCode mails the code an account without password confirms
+// sensitive changes with.
+func (s *profileService) SendReauthCode(ctx context.Context, userUniqueID int64) (*dto.UserVerifyCodeResDTO, *exception.Exception) {
+	user, ex := s.findUser(ctx, userUniqueID)
+	if ex != nil {
+		return nil, ex
+	}
+	return s.reauth.SendCode(ctx, user)
+}
+
+// ChangePassword needs the current password, or the step-up of an account
+// that never had one (e.g. created through OAuth). Every other session is
+// ended afterwards, the one making the change stays signed in.
+func (s *profileService) ChangePassword(ctx context.Context, userUniqueID int64, sessionID string, data dto.UserPasswordChangeReqDTO) *exception.Exception {
+	user, ex := s.findUser(ctx, userUniqueID)
+	if ex != nil {
+		return ex
+	}
+
+	if ex := s.reauth.Check(ctx, user, sessionID, data.CurrentPassword, data.Code); ex != nil {
+		return ex
+	}
+
+	if ex := s.history.CheckReuse(ctx, user, data.NewPassword); ex != nil {
+		return ex
+	}
+
+	hashedPassword, err := s.hasher.Hash(data.NewPassword)
+	if err != nil {
+		return exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	if err := s.userRepo.UpdateByMap(ctx, user.ID, map[string]any{"password": hashedPassword}); err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	s.history.Record(ctx, user.UniqueID, hashedPassword)
+
+	sessions, err := s.userSessionRepo.FindActiveByUserUniqueID(ctx, userUniqueID)
+	if err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	for _, session := range sessions {
+		if session.SessionID == sessionID {
+			continue
+		}
+		if ex := s.tokenService.RevokeRefreshTokenFamily(ctx, session.SessionID); ex != nil {
+			return ex
+		}
+	}
+
+	s.logger.Info("password changed", zap.Int64("userUniqueId", userUniqueID))
+	return nil
+}
+
+// UpdateAvatar decodes the uploaded image, crops and scales it to a square
+// and stores it re-encoded as JPEG, so nothing of the original file (metadata,
+// polyglot payloads) is ever served. The file name is random, a new avatar
+// gets a new URL and caches never show the old one.
+func (s *profileService) UpdateAvatar(ctx context.Context, userUniqueID int64, file io.Reader) (*model.User, *exception.Exception) {
+	avatarConfig := s.config.Avatar
+
+	user, ex := s.findUser(ctx, userUniqueID)
+	if ex != nil {
+		return nil, ex
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, avatarConfig.MaxSize+1))
+	if err != nil {
+		return nil, exception.ExceptionBadRequest.AppendDetails(err.Error())
+	}
+	if int64(len(data)) > avatarConfig.MaxSize {
+		return nil, exception.ExceptionFileTooLarge.AppendDetails(fmt.Sprintf("max %d bytes", avatarConfig.MaxSize))
+	}
+
+	img, _, err := imaging.Decode(data, avatarConfig.MaxPixels)
+	if errors.Is(err, imaging.ErrTooManyPixels) {
+		return nil, exception.ExceptionFileTooLarge.AppendDetails(fmt.Sprintf("max %d pixels", avatarConfig.MaxPixels))
+	} else if err != nil {
+		return nil, exception.ExceptionImageInvalid
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, imaging.Thumbnail(img, avatarConfig.Size), &jpeg.Options{Quality: 85}); err != nil {
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+
+	token, err := utils.GenerateSecureToken(12)
+	if err != nil {
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	name := fmt.Sprintf("%d-%s.jpg", userUniqueID, token)
+	if err := s.writeAvatar(name, buf.Bytes()); err != nil {
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+
+	avatarURL := strings.TrimSuffix(avatarConfig.URL, "/") + "/" + name
+	if err := s.userRepo.UpdateByMap(ctx, user.ID, map[string]any{"avatar_url": avatarURL}); err != nil {
+		os.Remove(filepath.Join(avatarConfig.Dir, name))
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	s.removeAvatar(user.AvatarURL)
+
+	user.AvatarURL = avatarURL
+	return user, nil
+}
+
+// writeAvatar writes through a temporary file, a request for the URL never
+// sees a half written image.
+func (s *profileService) writeAvatar(name string, data []byte) error {
+	dir := s.config.Avatar.Dir
+	tmp, err := os.CreateTemp(dir, ".avatar-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
+
+func (s *profileService) removeAvatar(avatarURL string) {
+	removeAvatar(s.config.Avatar, s.logger, avatarURL)
+}
+
+// removeAvatar deletes an avatar, as long as it is one of ours and not e.g. a
+// picture URL taken over from an OAuth provider.
+func removeAvatar(avatarConfig config.AvatarConfig, logger *logger.Logger, avatarURL string) {
+	prefix := strings.TrimSuffix(avatarConfig.URL, "/") + "/"
+	name, ok := strings.CutPrefix(avatarURL, prefix)
+	if !ok || name == "" || strings.ContainsAny(name, `/\`) {
+		return
+	}
+	if err := os.Remove(filepath.Join(avatarConfig.Dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Warn("Failed to remove avatar", zap.String("file", name), zap.Error(err))
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/pkg/utils"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// emailRevert is what a revert link sent to the previous address restores.
+type emailRevert struct {
+	UserUniqueID int64  `json:"userUniqueId"`
+	OldEmail     string `json:"oldEmail"`
+	NewEmail     string `json:"newEmail"`
+}
+
+func (s *profileService) emailRevertKey(token string) string {
+	return fmt.Sprintf("user:email:revert:%s", utils.SHA256Hex(token))
+}
+
+// emailCodeTarget binds an email change code to the user that requested it.
+func (s *profileService) emailCodeTarget(userUniqueID int64, email string) string {
+	return fmt.Sprintf("%d:%s", userUniqueID, strings.ToLower(email))
+}
+
+func (s *profileService) checkEmailFree(ctx context.Context, email string, userUniqueID int64) *exception.Exception {
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	if user.UniqueID != userUniqueID {
+		return exception.ExceptionUserEmailAlreadyExists
+	}
+	return exception.ExceptionInvalidParam.AppendDetails("newEmail is the current email")
+}
+
+// RequestEmailChange mails a code to the new address, nothing changes until
+// it is confirmed. It needs the current password, or the step-up of an
+// account that never had one (e.g. created through OAuth).
+func (s *profileService) RequestEmailChange(ctx context.Context, userUniqueID int64, sessionID string, data dto.UserEmailChangeReqDTO) (*dto.UserVerifyCodeResDTO, *exception.Exception) {
+	user, ex := s.findUser(ctx, userUniqueID)
+	if ex != nil {
+		return nil, ex
+	}
+
+	if ex := s.reauth.Check(ctx, user, sessionID, data.Password, data.Code); ex != nil {
+		return nil, ex
+	}
+
+	if ex := s.checkEmailFree(ctx, data.NewEmail, userUniqueID); ex != nil {
+		return nil, ex
+	}
+
+	code, expireAt, ex := s.verifyCode.Issue(ctx, VerifyCodeSceneEmailChange, s.emailCodeTarget(userUniqueID, data.NewEmail))
+	if ex != nil {
+		return nil, ex
+	}
+
+	if err := s.mailer.Send(ctx, newEmailChangeCodeMail(data.NewEmail, code, s.config.VerifyCode.Expire)); err != nil {
+		s.logger.Error("Failed to send email change code mail", zap.String("email", data.NewEmail), zap.Error(err))
+		return nil, exception.ExceptionServiceError.AppendDetails("send mail failed")
+	}
+
+	return &dto.UserVerifyCodeResDTO{
+		CodeExpireAt:    expireAt.UnixMilli(),
+		CodeResendAfter: time.Now().Add(s.config.VerifyCode.ResendInterval).UnixMilli(),
+	}, nil
+}
+
+// ConfirmEmailChange swaps the email once the code sent to the new address
+// is confirmed. The previous address is told about it and gets a link to
+// undo the change, every session of the user is ended.
+func (s *profileService) ConfirmEmailChange(ctx context.Context, userUniqueID int64, data dto.UserEmailChangeConfirmReqDTO) (*model.User, *exception.Exception) {
+	user, ex := s.findUser(ctx, userUniqueID)
+	if ex != nil {
+		return nil, ex
+	}
+	if ex := s.checkEmailFree(ctx, data.NewEmail, userUniqueID); ex != nil {
+		return nil, ex
+	}
+	if ex := s.verifyCode.Verify(ctx, VerifyCodeSceneEmailChange, s.emailCodeTarget(userUniqueID, data.NewEmail), data.Code); ex != nil {
+		return nil, ex
+	}
+
+	// the code proves ownership of the new mailbox
+	err := s.userRepo.ChangeEmail(ctx, user.ID, user.Email, data.NewEmail, emailVerifiedUpdates(user, time.Now()))
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, exception.ExceptionUserEmailAlreadyExists
+	} else if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, exception.ExceptionEmailChangeInvalid
+	} else if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	s.statusCache.Invalidate(ctx, userUniqueID)
+	s.logger.Info("email changed", zap.Int64("userUniqueId", userUniqueID), zap.String("oldEmail", user.Email), zap.String("newEmail", data.NewEmail))
+
+	s.notifyEmailChanged(ctx, emailRevert{UserUniqueID: userUniqueID, OldEmail: user.Email, NewEmail: data.NewEmail})
+
+	if ex := s.tokenService.RevokeUserTokens(ctx, userUniqueID); ex != nil {
+		return nil, ex
+	}
+	return s.findUser(ctx, userUniqueID)
+}
+
+// notifyEmailChanged mails the revert link to the previous address. The
+// change is already done, a failure is only logged.
+func (s *profileService) notifyEmailChanged(ctx context.Context, revert emailRevert) {
+	expire := s.config.EmailChange.RevertExpire
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		s.logger.Error("Failed to generate email revert token", zap.Error(err))
+		return
+	}
+	value, err := json.Marshal(revert)
+	if err != nil {
+		s.logger.Error("Failed to marshal email revert", zap.Error(err))
+		return
+	}
+	if err := s.redis.Set(ctx, s.emailRevertKey(token), value, expire).Err(); err != nil {
+		s.logger.Error("Failed to store email revert token", zap.Int64("userUniqueId", revert.UserUniqueID), zap.Error(err))
+		return
+	}
+
+	link := fmt.Sprintf(s.config.EmailChange.RevertURL, token)
+	if err := s.mailer.Send(ctx, newEmailChangedMail(revert.OldEmail, revert.NewEmail, link, expire)); err != nil {
+		s.logger.Error("Failed to send email changed mail", zap.String("email", revert.OldEmail), zap.Error(err))
+	}
+}
+
+// RevertEmailChange restores the address the link was sent to, whatever the
+// email was changed to since, and ends every session again: whoever changed
+// it is signed out.
+func (s *profileService) RevertEmailChange(ctx context.Context, data dto.UserEmailRevertReqDTO) *exception.Exception {
+	// GETDEL makes the link single-use even under concurrent requests
+	value, err := s.redis.GetDel(ctx, s.emailRevertKey(data.Token)).Bytes()
+	if err == redis.Nil {
+		return exception.ExceptionEmailRevertInvalid
+	} else if err != nil {
+		return exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	var revert emailRevert
+	if err := json.Unmarshal(value, &revert); err != nil {
+		return exception.ExceptionEmailRevertInvalid
+	}
+
+	user, ex := s.findUser(ctx, revert.UserUniqueID)
+	if ex != nil {
+		return ex
+	}
+	if user.Email != revert.OldEmail {
+		err := s.userRepo.ChangeEmail(ctx, user.ID, user.Email, revert.OldEmail, emailVerifiedUpdates(user, time.Now()))
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return exception.ExceptionUserEmailAlreadyExists
+		} else if errors.Is(err, gorm.ErrRecordNotFound) {
+			return exception.ExceptionEmailRevertInvalid
+		} else if err != nil {
+			return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+		}
+		s.statusCache.Invalidate(ctx, user.UniqueID)
+	}
+	s.logger.Info("email change reverted", zap.Int64("userUniqueId", user.UniqueID), zap.String("email", revert.OldEmail), zap.String("revertedEmail", user.Email))
+
+	return s.tokenService.RevokeUserTokens(ctx, user.UniqueID)
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"super-web-server/internal/config"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func newTestProfileService(t *testing.T, userRepo *fakeUserRepo) *profileService {
+	t.Helper()
+	client, _ := newTestRedis(t)
+	verifyCodeConfig := config.VerifyCodeConfig{Length: 6, Expire: 10 * time.Minute, MaxAttempts: 5, ResendInterval: time.Minute}
+	return &profileService{
+		userRepo:   userRepo,
+		logger:     newTestLogger(),
+		redis:      client,
+		config:     &config.Config{VerifyCode: verifyCodeConfig},
+		verifyCode: newVerifyCodeStore(client, verifyCodeConfig),
+	}
+}
+
+func TestUpdateProfileMobile(t *testing.T) {
+	const mobile = "13800000000"
+	tests := []struct {
+		name      string
+		owner     *model.User // another user holding the mobile
+		updateErr error
+		want      *exception.Exception
+	}{
+		{name: "free"},
+		{name: "taken", owner: &model.User{UniqueID: 2, Mobile: model.NullableString(mobile)}, want: exception.ExceptionUserMobileExists},
+		{name: "taken meanwhile", updateErr: gorm.ErrDuplicatedKey, want: exception.ExceptionUserMobileExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := &fakeUserRepo{users: []*model.User{{UniqueID: 1}}, updateErr: tt.updateErr}
+			if tt.owner != nil {
+				userRepo.users = append(userRepo.users, tt.owner)
+			}
+			s := newTestProfileService(t, userRepo)
+			ctx := context.Background()
+
+			code, _, ex := s.verifyCode.Issue(ctx, VerifyCodeSceneProfileMobile, s.mobileCodeTarget(1, mobile))
+			if ex != nil {
+				t.Fatalf("Issue() = %v", ex)
+			}
+			m := mobile
+			_, ex = s.UpdateProfile(ctx, 1, dto.UserProfileUpdateReqDTO{Mobile: &m, MobileCode: code})
+			if tt.want != nil {
+				if !ex.Is(tt.want) {
+					t.Fatalf("UpdateProfile() = %v, want %v", ex, tt.want)
+				}
+				return
+			}
+			if ex != nil {
+				t.Fatalf("UpdateProfile() = %v, want success", ex)
+			}
+			if len(userRepo.updates) != 1 || *userRepo.updates[0]["mobile"].(*string) != mobile {
+				t.Errorf("updates = %v, want mobile %s", userRepo.updates, mobile)
+			}
+		})
+	}
+}
+
+func TestNullableString(t *testing.T) {
+	if got := model.NullableString(""); got != nil {
+		t.Errorf("NullableString(\"\") = %q, want nil", *got)
+	}
+	if got := model.NullableString("1"); got == nil || *got != "1" {
+		t.Errorf("NullableString(\"1\") = %v, want \"1\"", got)
+	}
+	if got := (&model.User{}).MobileNumber(); got != "" {
+		t.Errorf("MobileNumber() = %q, want empty", got)
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/mailer"
+	"super-web-server/pkg/password"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// reauthenticator confirms it is the user asking for a sensitive change, a
+// stolen access token alone is not enough. Users prove it with their
+// password. Accounts without one (e.g. created through OAuth) either signed
+// in recently, at the provider or with a code, or enter a code mailed to
+// them.
+type reauthenticator struct {
+	userSessionRepo repo.UserSessionRepo
+	verifyCode      *verifyCodeStore
+	hasher          *password.Hasher
+	mailer          mailer.Mailer
+	logger          *logger.Logger
+	maxAge          time.Duration
+	codeExpire      time.Duration
+	resendInterval  time.Duration
+}
+
+func newReauthenticator(userSessionRepo repo.UserSessionRepo, verifyCode *verifyCodeStore, hasher *password.Hasher, mailer mailer.Mailer, logger *logger.Logger, maxAge, codeExpire, resendInterval time.Duration) *reauthenticator {
+	return &reauthenticator{
+		userSessionRepo: userSessionRepo,
+		verifyCode:      verifyCode,
+		hasher:          hasher,
+		mailer:          mailer,
+		logger:          logger,
+		maxAge:          maxAge,
+		codeExpire:      codeExpire,
+		resendInterval:  resendInterval,
+	}
+}
+
+func (r *reauthenticator) codeTarget(userUniqueID int64) string {
+	return fmt.Sprintf("%d", userUniqueID)
+}
+
+// Check accepts the password of the user, or for an account without one a
+// code from SendCode or a session signed in less than maxAge ago.
+func (r *reauthenticator) Check(ctx context.Context, user *model.User, sessionID, pw, code string) *exception.Exception {
+	if user.Password != "" {
+		ok, _, err := r.hasher.Verify(pw, user.Password)
+		if err != nil {
+			r.logger.Error("verify password failed", zap.Int64("userUniqueId", user.UniqueID), zap.Error(err))
+		}
+		if !ok {
+			return exception.ExceptionUserPasswordIncorrect
+		}
+		return nil
+	}
+
+	if code != "" {
+		return r.verifyCode.Verify(ctx, VerifyCodeSceneReauth, r.codeTarget(user.UniqueID), code)
+	}
+
+	if sessionID != "" {
+		session, err := r.userSessionRepo.FindBySessionID(ctx, sessionID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+		}
+		// a refresh keeps the session, so its creation is the sign in
+		if err == nil && session.UserUniqueID == user.UniqueID && session.CreatedAt != nil && time.Since(*session.CreatedAt) <= r.maxAge {
+			return nil
+		}
+	}
+	return exception.ExceptionReauthRequired
+}
+
+// SendCode mails a code confirming a sensitive change to an account without
+// password.
+func (r *reauthenticator) SendCode(ctx context.Context, user *model.User) (*dto.UserVerifyCodeResDTO, *exception.Exception) {
+	if user.Password != "" {
+		return nil, exception.ExceptionInvalidParam.AppendDetails("confirm with your password")
+	}
+
+	code, expireAt, ex := r.verifyCode.Issue(ctx, VerifyCodeSceneReauth, r.codeTarget(user.UniqueID))
+	if ex != nil {
+		return nil, ex
+	}
+
+	if err := r.mailer.Send(ctx, newReauthCodeMail(user.Email, code, r.codeExpire)); err != nil {
+		r.logger.Error("Failed to send reauth code mail", zap.Int64("userUniqueId", user.UniqueID), zap.Error(err))
+		return nil, exception.ExceptionServiceError.AppendDetails("send mail failed")
+	}
+
+	return &dto.UserVerifyCodeResDTO{
+		CodeExpireAt:    expireAt.UnixMilli(),
+		CodeResendAfter: time.Now().Add(r.resendInterval).UnixMilli(),
+	}, nil
+}
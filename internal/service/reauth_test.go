@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"super-web-server/internal/config"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/password"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type fakeUserSessionRepo struct {
+	repo.UserSessionRepo
+	sessions []*model.UserSession
+}
+
+func (r *fakeUserSessionRepo) FindBySessionID(ctx context.Context, sessionID string) (*model.UserSession, error) {
+	for _, session := range r.sessions {
+		if session.SessionID == sessionID {
+			return session, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestReauthCheck(t *testing.T) {
+	hasher := password.NewHasher(password.Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	hash, err := hasher.Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	fresh := time.Now().Add(-time.Minute)
+	stale := time.Now().Add(-time.Hour)
+	sessions := &fakeUserSessionRepo{sessions: []*model.UserSession{
+		{SessionID: "fresh", UserUniqueID: 1, BaseModel: model.BaseModel{CreatedAt: &fresh}},
+		{SessionID: "stale", UserUniqueID: 1, BaseModel: model.BaseModel{CreatedAt: &stale}},
+		{SessionID: "other", UserUniqueID: 2, BaseModel: model.BaseModel{CreatedAt: &fresh}},
+	}}
+
+	withPassword := &model.User{UniqueID: 1, Email: "user@example.com", Password: hash}
+	oauthOnly := &model.User{UniqueID: 1, Email: "user@example.com"}
+	tests := []struct {
+		name      string
+		user      *model.User
+		sessionID string
+		password  string
+		code      bool
+		want      *exception.Exception
+	}{
+		{"password", withPassword, "stale", "secret", false, nil},
+		{"wrong password", withPassword, "stale", "guess", false, exception.ExceptionUserPasswordIncorrect},
+		// a fresh session never replaces the password of an account that has one
+		{"no password but fresh session", withPassword, "fresh", "", false, exception.ExceptionUserPasswordIncorrect},
+		{"passwordless fresh session", oauthOnly, "fresh", "", false, nil},
+		{"passwordless stale session", oauthOnly, "stale", "", false, exception.ExceptionReauthRequired},
+		{"passwordless session of another user", oauthOnly, "other", "", false, exception.ExceptionReauthRequired},
+		{"passwordless unknown session", oauthOnly, "unknown", "", false, exception.ExceptionReauthRequired},
+		{"passwordless without session", oauthOnly, "", "", false, exception.ExceptionReauthRequired},
+		{"passwordless code", oauthOnly, "stale", "", true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb, _ := newTestRedis(t)
+			mailer := &fakeMailer{}
+			r := newReauthenticator(sessions, newVerifyCodeStore(rdb, config.VerifyCodeConfig{Length: 6, Expire: time.Minute, MaxAttempts: 3, ResendInterval: time.Minute}),
+				hasher, mailer, newTestLogger(), 10*time.Minute, time.Minute, time.Minute)
+
+			code := ""
+			if tt.code {
+				if _, ex := r.SendCode(context.Background(), tt.user); ex != nil {
+					t.Fatalf("SendCode: %v", ex)
+				}
+				if len(mailer.sent) != 1 || mailer.sent[0].To[0] != tt.user.Email {
+					t.Fatalf("sent = %+v, want the code mailed to the user", mailer.sent)
+				}
+				code = codeFromMail(t, mailer.sent[0].Body)
+			}
+
+			ex := r.Check(context.Background(), tt.user, tt.sessionID, tt.password, code)
+			if tt.want == nil {
+				if ex != nil {
+					t.Fatalf("Check() = %v, want success", ex)
+				}
+				return
+			}
+			if ex == nil || !ex.Is(tt.want) {
+				t.Fatalf("Check() = %v, want %v", ex, tt.want)
+			}
+		})
+	}
+}
+
+func TestReauthCodeIsSingleUse(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	mailer := &fakeMailer{}
+	r := newReauthenticator(&fakeUserSessionRepo{}, newVerifyCodeStore(rdb, config.VerifyCodeConfig{Length: 6, Expire: time.Minute, MaxAttempts: 3, ResendInterval: time.Minute}),
+		nil, mailer, newTestLogger(), 10*time.Minute, time.Minute, time.Minute)
+	user := &model.User{UniqueID: 1, Email: "user@example.com"}
+
+	if _, ex := r.SendCode(context.Background(), user); ex != nil {
+		t.Fatalf("SendCode: %v", ex)
+	}
+	code := codeFromMail(t, mailer.sent[0].Body)
+	if ex := r.Check(context.Background(), user, "", "", code); ex != nil {
+		t.Fatalf("first Check() = %v, want success", ex)
+	}
+	if ex := r.Check(context.Background(), user, "", "", code); ex == nil || !ex.Is(exception.ExceptionVerifyCodeInvalid) {
+		t.Fatalf("second Check() = %v, want %v", ex, exception.ExceptionVerifyCodeInvalid)
+	}
+}
+
+func TestReauthSendCodeNeedsPasswordless(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	mailer := &fakeMailer{}
+	r := newReauthenticator(&fakeUserSessionRepo{}, newVerifyCodeStore(rdb, config.VerifyCodeConfig{Length: 6, Expire: time.Minute, ResendInterval: time.Minute}),
+		nil, mailer, newTestLogger(), 10*time.Minute, time.Minute, time.Minute)
+	if _, ex := r.SendCode(context.Background(), &model.User{UniqueID: 1, Password: "hash"}); ex == nil || !ex.Is(exception.ExceptionInvalidParam) {
+		t.Fatalf("SendCode() = %v, want %v", ex, exception.ExceptionInvalidParam)
+	}
+	if len(mailer.sent) != 0 {
+		t.Errorf("sent = %+v, want nothing", mailer.sent)
+	}
+}
+
+func codeFromMail(t *testing.T, body string) string {
+	t.Helper()
+	var code string
+	if _, err := fmt.Sscanf(body, "Your verification code is %6s.", &code); err != nil {
+		t.Fatalf("no code in %q: %v", body, err)
+	}
+	return code
+}
@@ -1,34 +1,141 @@
 package service
 
 import (
+	"super-web-server/internal/config"
 	"super-web-server/internal/repo"
 	"super-web-server/pkg/jwt"
 	"super-web-server/pkg/logger"
+	"super-web-server/pkg/mailer"
+	"super-web-server/pkg/oidc"
+	"super-web-server/pkg/password"
+	"super-web-server/pkg/policy"
+	"super-web-server/pkg/signurl"
+	"super-web-server/pkg/sms"
+	"super-web-server/pkg/snowflake"
+	"super-web-server/pkg/storage"
 
 	"github.com/redis/go-redis/v9"
 )
 
 type Service interface {
 	User() UserService
+	Token() TokenService
+	MFA() MFAService
+	OAuth() OAuthService
+	APIKey() APIKeyService
+	Session() SessionService
+	AdminUser() AdminUserService
+	AdminRole() AdminRoleService
+	Profile() ProfileService
+	File() FileService
+	Account() AccountService
+	Policy() PolicyService
+
+	// Start and Stop run the background work of the services
+	Start()
+	Stop()
 }
 
 type service struct {
-	userService UserService
-	logger      *logger.Logger
-	redis       *redis.Client
-	jwt         *jwt.JWT
+	userService      UserService
+	tokenService     TokenService
+	mfaService       MFAService
+	oauthService     OAuthService
+	apiKeyService    APIKeyService
+	sessionService   SessionService
+	adminUserService AdminUserService
+	adminRoleService AdminRoleService
+	profileService   ProfileService
+	fileService      FileService
+	accountService   AccountService
+	policyService    PolicyService
+	roleCache        *userRoleCache
+	logger           *logger.Logger
+	redis            *redis.Client
+	jwt              *jwt.JWT
 }
 
-func NewService(repo repo.Repo, logger *logger.Logger, redis *redis.Client, jwt *jwt.JWT) Service {
+func NewService(repo repo.Repo, logger *logger.Logger, redis *redis.Client, jwt *jwt.JWT, snowflake *snowflake.Snowflake, mailer mailer.Mailer, sms sms.Provider, oidcProviders []*oidc.Provider, hasher *password.Hasher, storage storage.Storage, signer *signurl.Signer, policy *policy.Engine, config *config.Config) Service {
 	logger.Info("NewService initialized successfully")
+	tokenService := NewTokenService(repo.UserSession(), logger, redis, jwt)
+	mfaService := NewMFAService(repo.User(), repo.UserSession(), repo.UserRecoveryCode(), tokenService, hasher, logger, redis, mailer, config)
+	roleCache := newUserRoleCache(repo.User(), repo.UserRole(), repo.Permission(), redis, logger, config.RoleCache)
+	policyService := NewPolicyService(policy, roleCache, logger)
 	return &service{
-		userService: NewUserService(repo.User(), logger, redis, jwt),
-		logger:      logger,
-		redis:       redis,
-		jwt:         jwt,
+		userService:      NewUserService(repo.User(), repo.UserRole(), repo.UserPasswordHistory(), tokenService, mfaService, roleCache, logger, redis, jwt, snowflake, mailer, sms, hasher, config),
+		tokenService:     tokenService,
+		mfaService:       mfaService,
+		oauthService:     NewOAuthService(repo.User(), repo.UserRole(), repo.UserIdentity(), tokenService, mfaService, oidcProviders, logger, redis, snowflake, config.OIDC),
+		apiKeyService:    NewAPIKeyService(repo.APIKey(), repo.User(), roleCache, logger, redis),
+		sessionService:   NewSessionService(repo.UserSession(), tokenService, logger, redis),
+		profileService:   NewProfileService(repo.User(), repo.UserSession(), repo.UserPasswordHistory(), tokenService, logger, redis, mailer, sms, hasher, config),
+		adminUserService: NewAdminUserService(repo.User(), repo.UserRole(), repo.UserPasswordHistory(), tokenService, roleCache, logger, redis, jwt, snowflake, hasher, config),
+		adminRoleService: NewAdminRoleService(repo.User(), repo.UserRole(), repo.Permission(), roleCache, logger),
+		fileService:      NewFileService(repo.File(), storage, signer, policyService, logger, snowflake, config),
+		accountService:   NewAccountService(repo.User(), repo.UserSession(), repo.UserIdentity(), repo.APIKey(), repo.File(), repo.UserDataExport(), tokenService, roleCache, logger, redis, snowflake, mailer, hasher, storage, signer, config),
+		policyService:    policyService,
+		roleCache:        roleCache,
+		logger:           logger,
+		redis:            redis,
+		jwt:              jwt,
 	}
 }
 
 func (s *service) User() UserService {
 	return s.userService
 }
+
+func (s *service) Token() TokenService {
+	return s.tokenService
+}
+
+func (s *service) MFA() MFAService {
+	return s.mfaService
+}
+
+func (s *service) OAuth() OAuthService {
+	return s.oauthService
+}
+
+func (s *service) APIKey() APIKeyService {
+	return s.apiKeyService
+}
+
+func (s *service) Session() SessionService {
+	return s.sessionService
+}
+
+func (s *service) AdminUser() AdminUserService {
+	return s.adminUserService
+}
+
+func (s *service) AdminRole() AdminRoleService {
+	return s.adminRoleService
+}
+
+func (s *service) Profile() ProfileService {
+	return s.profileService
+}
+
+func (s *service) File() FileService {
+	return s.fileService
+}
+
+func (s *service) Account() AccountService {
+	return s.accountService
+}
+
+func (s *service) Policy() PolicyService {
+	return s.policyService
+}
+
+// Start subscribes to the invalidations of cached roles, Stop also waits for
+// the data exports being built.
+func (s *service) Start() {
+	s.roleCache.Start()
+}
+
+func (s *service) Stop() {
+	s.roleCache.Stop()
+	s.accountService.Stop()
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"super-web-server/internal/config"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/mailer"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func newTestLogger() *logger.Logger {
+	return &logger.Logger{Logger: zap.NewNop()}
+}
+
+// newTestRedis starts an in-memory redis that is closed with the test.
+func newTestRedis(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client, server
+}
+
+// fakeUserRepo keeps users in memory, methods the tests do not need panic
+// through the nil embedded interface.
+type fakeUserRepo struct {
+	repo.UserRepo
+	users     []*model.User
+	updates   []map[string]any
+	updateErr error
+}
+
+func (r *fakeUserRepo) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUserRepo) FindByUniqueID(ctx context.Context, uniqueID int64) (*model.User, error) {
+	for _, user := range r.users {
+		if user.UniqueID == uniqueID {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUserRepo) FindByMobile(ctx context.Context, mobile string) (*model.User, error) {
+	for _, user := range r.users {
+		if user.Mobile != nil && *user.Mobile == mobile {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUserRepo) UpdateByMap(ctx context.Context, id uint64, data map[string]any) error {
+	if r.updateErr != nil {
+		return r.updateErr
+	}
+	r.updates = append(r.updates, data)
+	return nil
+}
+
+// fakeUserRoleRepo holds every role with its Parents. FindOne cannot read
+// the query, it returns found, the role a test works on.
+type fakeUserRoleRepo struct {
+	repo.UserRoleRepo
+	roles   []*model.UserRole
+	found   *model.UserRole
+	updates int
+}
+
+func (r *fakeUserRoleRepo) FindOne(ctx context.Context, opts ...repo.QueryOption) (*model.UserRole, error) {
+	if r.found == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.found, nil
+}
+
+func (r *fakeUserRoleRepo) FindByCodes(ctx context.Context, codes []model.UserRoleEnum) ([]*model.UserRole, error) {
+	var roles []*model.UserRole
+	for _, role := range r.roles {
+		if slices.Contains(codes, role.Code) {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+func (r *fakeUserRoleRepo) Create(ctx context.Context, entity *model.UserRole) error {
+	entity.ID = uint64(len(r.roles) + 1)
+	r.roles = append(r.roles, entity)
+	r.found = entity
+	return nil
+}
+
+func (r *fakeUserRoleRepo) UpdateWithRefs(ctx context.Context, role *model.UserRole, data map[string]any, parents []*model.UserRole, permissions []*model.Permission) error {
+	r.updates++
+	return nil
+}
+
+func (r *fakeUserRoleRepo) FindByCode(ctx context.Context, code model.UserRoleEnum) (*model.UserRole, error) {
+	for _, role := range r.roles {
+		if role.Code == code {
+			return role, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUserRoleRepo) FindMany(ctx context.Context, opts ...repo.QueryOption) ([]*model.UserRole, error) {
+	return r.roles, nil
+}
+
+// fakePermissionRepo grants the permissions listed per role id.
+type fakePermissionRepo struct {
+	repo.PermissionRepo
+	granted map[uint64][]model.PermissionEnum
+}
+
+// FindByCodes knows every code.
+func (r *fakePermissionRepo) FindByCodes(ctx context.Context, codes []model.PermissionEnum) ([]*model.Permission, error) {
+	permissions := make([]*model.Permission, 0, len(codes))
+	for _, code := range codes {
+		permissions = append(permissions, &model.Permission{Code: code})
+	}
+	return permissions, nil
+}
+
+func (r *fakePermissionRepo) FindCodesByRoleIDs(ctx context.Context, roleIDs []uint64) ([]model.PermissionEnum, error) {
+	var permissions []model.PermissionEnum
+	for _, id := range roleIDs {
+		for _, permission := range r.granted[id] {
+			if !slices.Contains(permissions, permission) {
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	return permissions, nil
+}
+
+// newTestRoleCache resolves roles and permissions from the fakes without
+// keeping anything in process.
+func newTestRoleCache(t *testing.T, users *fakeUserRepo, roles *fakeUserRoleRepo, permissions *fakePermissionRepo) *userRoleCache {
+	t.Helper()
+	rdb, _ := newTestRedis(t)
+	return newUserRoleCache(users, roles, permissions, rdb, newTestLogger(), config.RoleCacheConfig{TTL: time.Minute})
+}
+
+// fakeMailer records the messages it is asked to send and fails with err.
+type fakeMailer struct {
+	sent []mailer.Message
+	err  error
+}
+
+func (m *fakeMailer) Send(ctx context.Context, msg mailer.Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/jwt"
+	"super-web-server/pkg/logger"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type SessionService interface {
+	List(ctx context.Context, userUniqueID int64, currentSessionID string) ([]*dto.UserSessionResDTO, *exception.Exception)
+	Revoke(ctx context.Context, userUniqueID int64, sessionID string) *exception.Exception
+	TouchSession(ctx context.Context, claims *jwt.JWTClaims) *exception.Exception
+}
+
+// sessionTouchInterval is the precision of UserSession.LastSeenAt, a Redis
+// marker keeps requests in between from writing to the database.
+const sessionTouchInterval = 1 * time.Minute
+
+type sessionService struct {
+	userSessionRepo repo.UserSessionRepo
+	tokenService    TokenService
+	logger          *logger.Logger
+	redis           *redis.Client
+}
+
+func NewSessionService(userSessionRepo repo.UserSessionRepo, tokenService TokenService, logger *logger.Logger, redis *redis.Client) SessionService {
+	logger.Info("NewSessionService initialized successfully")
+	return &sessionService{
+		userSessionRepo: userSessionRepo,
+		tokenService:    tokenService,
+		logger:          logger,
+		redis:           redis,
+	}
+}
+
+func (s *sessionService) touchedKey(sessionID string) string {
+	return fmt.Sprintf("auth:session:touched:%s", sessionID)
+}
+
+func (s *sessionService) List(ctx context.Context, userUniqueID int64, currentSessionID string) ([]*dto.UserSessionResDTO, *exception.Exception) {
+	sessions, err := s.userSessionRepo.FindActiveByUserUniqueID(ctx, userUniqueID)
+	if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	res := make([]*dto.UserSessionResDTO, 0, len(sessions))
+	for _, session := range sessions {
+		item := &dto.UserSessionResDTO{
+			SessionID:  session.SessionID,
+			DeviceName: session.DeviceName,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			Current:    session.SessionID == currentSessionID,
+		}
+		if session.CreatedAt != nil {
+			item.CreatedAt = session.CreatedAt.UnixMilli()
+		}
+		if session.LastSeenAt != nil {
+			item.LastSeenAt = session.LastSeenAt.UnixMilli()
+		}
+		res = append(res, item)
+	}
+	return res, nil
+}
+
+func (s *sessionService) Revoke(ctx context.Context, userUniqueID int64, sessionID string) *exception.Exception {
+	session, err := s.userSessionRepo.FindBySessionID(ctx, sessionID)
+	// another user's session is reported as missing, not as forbidden
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && session.UserUniqueID != userUniqueID) {
+		return exception.ExceptionSessionNotFound
+	} else if err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	if session.RevokedAt != nil {
+		return nil
+	}
+
+	if ex := s.tokenService.RevokeRefreshTokenFamily(ctx, sessionID); ex != nil {
+		return ex
+	}
+
+	s.logger.Info("session revoked", zap.String("sessionId", sessionID), zap.Int64("userUniqueId", userUniqueID))
+	return nil
+}
+
+// TouchSession is a jwt.TokenValidator recording the last time a session was used.
+func (s *sessionService) TouchSession(ctx context.Context, claims *jwt.JWTClaims) *exception.Exception {
+	if claims.SessionID == "" {
+		return nil
+	}
+
+	ok, err := s.redis.SetNX(ctx, s.touchedKey(claims.SessionID), 1, sessionTouchInterval).Result()
+	if err != nil || !ok {
+		return nil
+	}
+	if err := s.userSessionRepo.UpdateBySessionID(ctx, claims.SessionID, map[string]any{"last_seen_at": time.Now()}); err != nil {
+		s.logger.Warn("session touch failed", zap.String("sessionId", claims.SessionID), zap.Error(err))
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/jwt"
+	"super-web-server/pkg/logger"
+	"super-web-server/pkg/utils"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type TokenService interface {
+	IssueTokenPair(ctx context.Context, userUniqueID int64, meta dto.ClientMeta) (*dto.UserTokenResDTO, *exception.Exception)
+	RefreshToken(ctx context.Context, data dto.UserRefreshTokenReqDTO) (*dto.UserTokenResDTO, *exception.Exception)
+	RevokeRefreshTokenFamily(ctx context.Context, family string) *exception.Exception
+	RevokeAccessToken(ctx context.Context, tokenID string, expireAt time.Time) *exception.Exception
+	RevokeUserTokens(ctx context.Context, userUniqueID int64) *exception.Exception
+	Logout(ctx context.Context, tokenID string, expireAt time.Time, sessionID string, data dto.UserLogoutReqDTO) *exception.Exception
+	CheckAccessToken(ctx context.Context, claims *jwt.JWTClaims) *exception.Exception
+}
+
+// Refresh tokens are opaque strings "<family>.<secret>". Every login starts a
+// new family, every refresh rotates the token inside its family. Redis keeps
+//
+//	auth:refresh:family:<family>  -> user unique id, removed when the family is revoked
+//	auth:refresh:token:<sha256>   -> {family, user, used}, kept after use to detect reuse
+//	auth:refresh:user:<user>      -> set of families of the user
+//
+// Access tokens are revoked by their jti (auth:denylist:jti:<jti>), by their
+// session (auth:session:revoked:<sid>) or for a whole user by a "tokens
+// issued before" timestamp (auth:revoked_before:<user>).
+//
+// Every family is also a model.UserSession row, the family is the session id.
+type tokenService struct {
+	userSessionRepo repo.UserSessionRepo
+	logger          *logger.Logger
+	redis           *redis.Client
+	jwt             *jwt.JWT
+}
+
+func NewTokenService(userSessionRepo repo.UserSessionRepo, logger *logger.Logger, redis *redis.Client, jwt *jwt.JWT) TokenService {
+	logger.Info("NewTokenService initialized successfully")
+	return &tokenService{
+		userSessionRepo: userSessionRepo,
+		logger:          logger,
+		redis:           redis,
+		jwt:             jwt,
+	}
+}
+
+func (s *tokenService) familyKey(family string) string {
+	return fmt.Sprintf("auth:refresh:family:%s", family)
+}
+
+func (s *tokenService) tokenKey(refreshToken string) string {
+	return fmt.Sprintf("auth:refresh:token:%s", utils.SHA256Hex(refreshToken))
+}
+
+func (s *tokenService) userFamiliesKey(userUniqueID int64) string {
+	return fmt.Sprintf("auth:refresh:user:%d", userUniqueID)
+}
+
+func (s *tokenService) denylistKey(tokenID string) string {
+	return fmt.Sprintf("auth:denylist:jti:%s", tokenID)
+}
+
+func (s *tokenService) revokedBeforeKey(userUniqueID int64) string {
+	return fmt.Sprintf("auth:revoked_before:%d", userUniqueID)
+}
+
+func (s *tokenService) sessionRevokedKey(sessionID string) string {
+	return fmt.Sprintf("auth:session:revoked:%s", sessionID)
+}
+
+// IssueTokenPair starts a new session for a successful login.
+func (s *tokenService) IssueTokenPair(ctx context.Context, userUniqueID int64, meta dto.ClientMeta) (*dto.UserTokenResDTO, *exception.Exception) {
+	family, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		return nil, exception.ExceptionTokenGenerateFailed.AppendDetails(err.Error())
+	}
+
+	now := time.Now()
+	expiresAt := s.jwt.RefreshExpireAt()
+	session := &model.UserSession{
+		SessionID:    family,
+		UserUniqueID: userUniqueID,
+		DeviceName:   meta.DeviceName,
+		UserAgent:    meta.UserAgent,
+		IP:           meta.IP,
+		LastSeenAt:   &now,
+		ExpiresAt:    &expiresAt,
+	}
+	if err := s.userSessionRepo.Create(ctx, session); err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	return s.issueTokenPair(ctx, userUniqueID, family)
+}
+
+func (s *tokenService) issueTokenPair(ctx context.Context, userUniqueID int64, family string) (*dto.UserTokenResDTO, *exception.Exception) {
+	token, err := s.jwt.GenerateToken(userUniqueID, family)
+	if err != nil {
+		return nil, exception.ExceptionTokenGenerateFailed.AppendDetails(err.Error())
+	}
+
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, exception.ExceptionTokenGenerateFailed.AppendDetails(err.Error())
+	}
+	refreshToken := family + "." + secret
+	refreshExpire := s.jwt.GetRefreshExpire()
+
+	pipe := s.redis.TxPipeline()
+	pipe.Set(ctx, s.familyKey(family), userUniqueID, refreshExpire)
+	pipe.HSet(ctx, s.tokenKey(refreshToken), "family", family, "user", userUniqueID, "used", 0)
+	pipe.Expire(ctx, s.tokenKey(refreshToken), refreshExpire)
+	pipe.SAdd(ctx, s.userFamiliesKey(userUniqueID), family)
+	pipe.Expire(ctx, s.userFamiliesKey(userUniqueID), refreshExpire)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, exception.ExceptionTokenGenerateFailed.AppendDetails(err.Error())
+	}
+
+	return &dto.UserTokenResDTO{
+		Token:           token,
+		ExpireAt:        s.jwt.ExpireAt().UnixMilli(),
+		RefreshAt:       s.jwt.RefreshAt().UnixMilli(),
+		RefreshToken:    refreshToken,
+		RefreshExpireAt: s.jwt.RefreshExpireAt().UnixMilli(),
+	}, nil
+}
+
+func (s *tokenService) RefreshToken(ctx context.Context, data dto.UserRefreshTokenReqDTO) (*dto.UserTokenResDTO, *exception.Exception) {
+	family, _, ok := strings.Cut(data.RefreshToken, ".")
+	if !ok || family == "" {
+		return nil, exception.ExceptionRefreshTokenInvalid
+	}
+
+	tokenKey := s.tokenKey(data.RefreshToken)
+	record, err := s.redis.HGetAll(ctx, tokenKey).Result()
+	if err != nil {
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	if len(record) == 0 || record["family"] != family {
+		return nil, exception.ExceptionRefreshTokenInvalid
+	}
+
+	// HINCRBY is atomic, so of two concurrent refreshes with the same token
+	// exactly one sees used == 1
+	used, err := s.redis.HIncrBy(ctx, tokenKey, "used", 1).Result()
+	if err != nil {
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	if used > 1 {
+		s.logger.Warn("Refresh token reused, revoking family", zap.String("family", family), zap.String("user", record["user"]))
+		if ex := s.RevokeRefreshTokenFamily(ctx, family); ex != nil {
+			return nil, ex
+		}
+		return nil, exception.ExceptionRefreshTokenReused
+	}
+
+	owner, err := s.redis.Get(ctx, s.familyKey(family)).Result()
+	if err == redis.Nil {
+		return nil, exception.ExceptionRefreshTokenInvalid
+	} else if err != nil {
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+
+	userUniqueID, err := strconv.ParseInt(owner, 10, 64)
+	if err != nil || strconv.FormatInt(userUniqueID, 10) != record["user"] {
+		return nil, exception.ExceptionRefreshTokenInvalid
+	}
+
+	res, ex := s.issueTokenPair(ctx, userUniqueID, family)
+	if ex != nil {
+		return nil, ex
+	}
+
+	// a refresh extends the session like it extends the family
+	if err := s.userSessionRepo.UpdateBySessionID(ctx, family, map[string]any{
+		"last_seen_at": time.Now(),
+		"expires_at":   time.UnixMilli(res.RefreshExpireAt),
+	}); err != nil {
+		s.logger.Warn("Update session on refresh failed", zap.String("family", family), zap.Error(err))
+	}
+
+	return res, nil
+}
+
+// RevokeRefreshTokenFamily ends a session, its refresh tokens stop working
+// and so do the access tokens already issued to it.
+func (s *tokenService) RevokeRefreshTokenFamily(ctx context.Context, family string) *exception.Exception {
+	pipe := s.redis.TxPipeline()
+	pipe.Del(ctx, s.familyKey(family))
+	pipe.Set(ctx, s.sessionRevokedKey(family), 1, s.jwt.GetExpire())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	if err := s.userSessionRepo.RevokeBySessionID(ctx, family); err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	return nil
+}
+
+func (s *tokenService) RevokeAccessToken(ctx context.Context, tokenID string, expireAt time.Time) *exception.Exception {
+	ttl := time.Until(expireAt)
+	if tokenID == "" || ttl <= 0 {
+		return nil
+	}
+	if err := s.redis.Set(ctx, s.denylistKey(tokenID), 1, ttl).Err(); err != nil {
+		return exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	return nil
+}
+
+// RevokeUserTokens invalidates every access and refresh token issued to the
+// user so far, i.e. logs the user out of all sessions.
+func (s *tokenService) RevokeUserTokens(ctx context.Context, userUniqueID int64) *exception.Exception {
+	familiesKey := s.userFamiliesKey(userUniqueID)
+	families, err := s.redis.SMembers(ctx, familiesKey).Result()
+	if err != nil {
+		return exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+
+	pipe := s.redis.TxPipeline()
+	// access tokens live at most jwt expire, so the marker can go after that
+	pipe.Set(ctx, s.revokedBeforeKey(userUniqueID), time.Now().UnixMilli(), s.jwt.GetExpire())
+	for _, family := range families {
+		pipe.Del(ctx, s.familyKey(family))
+	}
+	pipe.Del(ctx, familiesKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	if err := s.userSessionRepo.RevokeByUserUniqueID(ctx, userUniqueID); err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	return nil
+}
+
+func (s *tokenService) Logout(ctx context.Context, tokenID string, expireAt time.Time, sessionID string, data dto.UserLogoutReqDTO) *exception.Exception {
+	if ex := s.RevokeAccessToken(ctx, tokenID, expireAt); ex != nil {
+		return ex
+	}
+	// tokens issued with a session name their refresh token family directly
+	if sessionID != "" {
+		return s.RevokeRefreshTokenFamily(ctx, sessionID)
+	}
+	if data.RefreshToken == "" {
+		return nil
+	}
+	family, _, ok := strings.Cut(data.RefreshToken, ".")
+	if !ok || family == "" {
+		return nil
+	}
+	// only the holder of a refresh token can name its family
+	exists, err := s.redis.Exists(ctx, s.tokenKey(data.RefreshToken)).Result()
+	if err != nil {
+		return exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	if exists == 0 {
+		return nil
+	}
+	return s.RevokeRefreshTokenFamily(ctx, family)
+}
+
+// CheckAccessToken is a jwt.TokenValidator rejecting revoked access tokens.
+// An impersonation token is also revoked with the tokens of its actor.
+func (s *tokenService) CheckAccessToken(ctx context.Context, claims *jwt.JWTClaims) *exception.Exception {
+	keys := []string{
+		s.denylistKey(claims.ID),
+		s.sessionRevokedKey(claims.SessionID),
+		s.revokedBeforeKey(claims.UserUniqueID),
+	}
+	if claims.IsImpersonation() {
+		keys = append(keys, s.revokedBeforeKey(claims.ActorUniqueID))
+	}
+	values, err := s.redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		return exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	if values[0] != nil || (claims.SessionID != "" && values[1] != nil) {
+		return exception.ExceptionTokenRevoked
+	}
+	for _, value := range values[2:] {
+		if value == nil {
+			continue
+		}
+		revokedBefore, err := strconv.ParseInt(fmt.Sprint(value), 10, 64)
+		if err == nil && claims.IssuedAtMilli() < revokedBefore {
+			return exception.ExceptionTokenRevoked
+		}
+	}
+	return nil
+}
@@ -2,42 +2,94 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"super-web-server/internal/config"
 	"super-web-server/internal/dto"
 	"super-web-server/internal/exception"
 	"super-web-server/internal/model"
 	"super-web-server/internal/repo"
 	"super-web-server/pkg/jwt"
 	"super-web-server/pkg/logger"
+	"super-web-server/pkg/mailer"
+	"super-web-server/pkg/password"
+	"super-web-server/pkg/sms"
+	"super-web-server/pkg/snowflake"
 	"super-web-server/pkg/utils"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type UserService interface {
 	GetUserByID(ctx context.Context, id uint64) (*model.User, *exception.Exception)
 	GetUserByUniqueID(ctx context.Context, uniqueID int64) (*model.User, *exception.Exception)
 	GetUserCachedRolesByUniqueID(ctx context.Context, uniqueID int64) ([]*model.UserRole, *exception.Exception)
-	LoginByEmail(ctx context.Context, data dto.UserLoginByEmailReqDTO) (*dto.UserLoginByEmailResDTO, *exception.Exception)
+	GetUserCachedPermissionsByUniqueID(ctx context.Context, uniqueID int64) ([]model.PermissionEnum, *exception.Exception)
+	GetUserInfo(ctx context.Context, uniqueID int64) (*dto.UserInfoResDTO, *exception.Exception)
+	LoginByEmail(ctx context.Context, data dto.UserLoginByEmailReqDTO, meta dto.ClientMeta) (*dto.UserLoginResDTO, *exception.Exception)
+	Register(ctx context.Context, data dto.UserRegisterReqDTO) (*dto.UserRegisterResDTO, *exception.Exception)
+	RegisterVerify(ctx context.Context, data dto.UserRegisterVerifyReqDTO) *exception.Exception
+	RegisterResendCode(ctx context.Context, data dto.UserRegisterResendCodeReqDTO) (*dto.UserVerifyCodeResDTO, *exception.Exception)
+	ForgotPassword(ctx context.Context, data dto.UserPasswordForgotReqDTO) *exception.Exception
+	ResetPassword(ctx context.Context, data dto.UserPasswordResetReqDTO) *exception.Exception
+	SendLoginByMobileCode(ctx context.Context, data dto.UserLoginByMobileSendCodeReqDTO) (*dto.UserVerifyCodeResDTO, *exception.Exception)
+	LoginByMobile(ctx context.Context, data dto.UserLoginByMobileReqDTO, meta dto.ClientMeta) (*dto.UserLoginResDTO, *exception.Exception)
+	CheckUserStatus(ctx context.Context, claims *jwt.JWTClaims) *exception.Exception
+	PurgeUnverifiedUsers(ctx context.Context) error
 }
 
 type userService struct {
-	userRepo repo.UserRepo
-	logger   *logger.Logger
-	redis    *redis.Client
-	jwt      *jwt.JWT
+	userRepo     repo.UserRepo
+	userRoleRepo repo.UserRoleRepo
+	tokenService TokenService
+	mfaService   MFAService
+	logger       *logger.Logger
+	redis        *redis.Client
+	jwt          *jwt.JWT
+	snowflake    *snowflake.Snowflake
+	mailer       mailer.Mailer
+	sms          sms.Provider
+	config       *config.Config
+	verifyCode   *verifyCodeStore
+	loginLimiter *loginLimiter
+	hasher       *password.Hasher
+	history      *passwordHistory
+	statusCache  *userStatusCache
+	roleCache    *userRoleCache
+	// dummyPasswordHash is verified against when the email is unknown, so
+	// both failure cases take about the same time
+	dummyPasswordHash func() string
 }
 
-func NewUserService(userRepo repo.UserRepo, logger *logger.Logger, redis *redis.Client, jwt *jwt.JWT) UserService {
+func NewUserService(userRepo repo.UserRepo, userRoleRepo repo.UserRoleRepo, passwordHistoryRepo repo.UserPasswordHistoryRepo, tokenService TokenService, mfaService MFAService, roleCache *userRoleCache, logger *logger.Logger, redis *redis.Client, jwt *jwt.JWT, snowflake *snowflake.Snowflake, mailer mailer.Mailer, sms sms.Provider, hasher *password.Hasher, config *config.Config) UserService {
 	logger.Info("NewUserService initialized successfully")
 	return &userService{
-		userRepo: userRepo,
-		logger:   logger,
-		redis:    redis,
-		jwt:      jwt,
+		userRepo:     userRepo,
+		userRoleRepo: userRoleRepo,
+		tokenService: tokenService,
+		mfaService:   mfaService,
+		logger:       logger,
+		redis:        redis,
+		jwt:          jwt,
+		snowflake:    snowflake,
+		mailer:       mailer,
+		sms:          sms,
+		config:       config,
+		verifyCode:   newVerifyCodeStore(redis, config.VerifyCode),
+		loginLimiter: newLoginLimiter(redis, logger, config.LoginLimit),
+		hasher:       hasher,
+		history:      newPasswordHistory(passwordHistoryRepo, hasher, logger, config.PasswordPolicy.History),
+		statusCache:  newUserStatusCache(userRepo, redis, logger),
+		roleCache:    roleCache,
+		dummyPasswordHash: sync.OnceValue(func() string {
+			hash, _ := hasher.Hash("dummy-password")
+			return hash
+		}),
 	}
 }
 
@@ -57,57 +109,406 @@ func (s *userService) GetUserByUniqueID(ctx context.Context, uniqueID int64) (*m
 	return user, nil
 }
 
+// GetUserCachedRolesByUniqueID returns the effective roles of the user, the
+// inherited ones included.
 func (s *userService) GetUserCachedRolesByUniqueID(ctx context.Context, uniqueID int64) ([]*model.UserRole, *exception.Exception) {
-	cacheKey := fmt.Sprintf("user:roles:%d", uniqueID)
-
-	cache, err := s.redis.Get(ctx, cacheKey).Result()
-	if err == nil && cache != "" {
-		var roles []*model.UserRole
-		if err := json.Unmarshal([]byte(cache), &roles); err != nil {
-			s.logger.Warn("Failed to unmarshal cached roles", zap.Int64("uniqueID", uniqueID), zap.Error(err))
-		} else {
-			return roles, nil
+	return s.roleCache.Roles(ctx, uniqueID)
+}
+
+func (s *userService) GetUserCachedPermissionsByUniqueID(ctx context.Context, uniqueID int64) ([]model.PermissionEnum, *exception.Exception) {
+	return s.roleCache.Permissions(ctx, uniqueID)
+}
+
+// GetUserInfo is the user with their effective permissions, frontends use
+// them to hide what the user cannot do.
+func (s *userService) GetUserInfo(ctx context.Context, uniqueID int64) (*dto.UserInfoResDTO, *exception.Exception) {
+	user, ex := s.GetUserByUniqueID(ctx, uniqueID)
+	if ex != nil {
+		return nil, ex
+	}
+	permissions, ex := s.roleCache.Permissions(ctx, uniqueID)
+	if ex != nil {
+		return nil, ex
+	}
+	return &dto.UserInfoResDTO{User: user, Permissions: permissions}, nil
+}
+
+func (s *userService) LoginByEmail(ctx context.Context, data dto.UserLoginByEmailReqDTO, meta dto.ClientMeta) (*dto.UserLoginResDTO, *exception.Exception) {
+	if ex := s.loginLimiter.Check(ctx, data.Email, meta.IP); ex != nil {
+		return nil, ex
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, data.Email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	// unknown email and wrong password get the same answer
+	if user == nil {
+		s.hasher.Verify(data.Password, s.dummyPasswordHash())
+		s.loginLimiter.Fail(ctx, data.Email, meta.IP)
+		return nil, exception.ExceptionUserInvalidCredentials
+	}
+	ok, needsRehash, err := s.hasher.Verify(data.Password, user.Password)
+	if err != nil {
+		s.logger.Error("verify password failed", zap.Int64("userUniqueId", user.UniqueID), zap.Error(err))
+	}
+	if !ok {
+		s.loginLimiter.Fail(ctx, data.Email, meta.IP)
+		return nil, exception.ExceptionUserInvalidCredentials
+	}
+
+	s.loginLimiter.Succeed(ctx, data.Email)
+
+	if needsRehash {
+		s.rehashPassword(ctx, user, data.Password)
+	}
+
+	return completeLogin(ctx, s.tokenService, s.mfaService, user, meta)
+}
+
+// completeLogin finishes a successful first factor login, users with TOTP
+// enabled get a MFA challenge instead of tokens. Accounts that are not active
+// are refused.
+func completeLogin(ctx context.Context, tokenService TokenService, mfaService MFAService, user *model.User, meta dto.ClientMeta) (*dto.UserLoginResDTO, *exception.Exception) {
+	if ex := userStatusException(user, time.Now()); ex != nil {
+		return nil, ex
+	}
+
+	if user.IsTOTPEnabled() {
+		return mfaService.CreateChallenge(ctx, user.UniqueID)
+	}
+
+	tokens, ex := tokenService.IssueTokenPair(ctx, user.UniqueID, meta)
+	if ex != nil {
+		return nil, ex
+	}
+	return &dto.UserLoginResDTO{UserTokenResDTO: tokens}, nil
+}
+
+// rehashPassword upgrades a hash of an old scheme or with old parameters while
+// the plain password is at hand. Failing only postpones it to the next login.
+func (s *userService) rehashPassword(ctx context.Context, user *model.User, password string) {
+	hashedPassword, err := s.hasher.Hash(password)
+	if err != nil {
+		s.logger.Error("rehash password failed", zap.Int64("userUniqueId", user.UniqueID), zap.Error(err))
+		return
+	}
+	if err := s.userRepo.UpdateByMap(ctx, user.ID, map[string]any{"password": hashedPassword}); err != nil {
+		s.logger.Error("store rehashed password failed", zap.Int64("userUniqueId", user.UniqueID), zap.Error(err))
+		return
+	}
+	user.Password = hashedPassword
+}
+
+// unverifiedBefore is the creation time before which a registration that
+// was never verified has expired.
+func (s *userService) unverifiedBefore(now time.Time) time.Time {
+	return now.Add(-s.config.Register.UnverifiedExpire)
+}
+
+// Register creates a pending user and mails them a code. An email held by a
+// registration that was never verified and has expired is taken over, so
+// nobody can squat an address they do not own.
+func (s *userService) Register(ctx context.Context, data dto.UserRegisterReqDTO) (*dto.UserRegisterResDTO, *exception.Exception) {
+	existing, err := s.userRepo.FindByEmail(ctx, data.Email)
+	if err == nil {
+		if ex := s.reclaimUnverified(ctx, existing); ex != nil {
+			return nil, ex
 		}
-	} else if err != nil && err != redis.Nil {
-		s.logger.Warn("Failed to get cached roles", zap.Int64("uniqueID", uniqueID), zap.Error(err))
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
 	}
 
-	user, err := s.userRepo.FindByUniqueID(ctx, uniqueID)
+	hashedPassword, err := s.hasher.Hash(data.Password)
 	if err != nil {
-		return nil, exception.ExceptionUserNotFound.AppendDetails(err.Error())
+		return nil, exception.ExceptionInternalServerError.AppendDetails(err.Error())
 	}
 
-	roles := user.Roles
+	userRole, err := s.userRoleRepo.FindByCode(ctx, model.UserRoleCodeUser)
+	if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	user := &model.User{
+		UniqueID: s.snowflake.GenerateID(),
+		Email:    data.Email,
+		Password: hashedPassword,
+		Nickname: data.Nickname,
+		Status:   model.UserStatusPending,
+		Roles:    []*model.UserRole{userRole},
+	}
 
-	if rolesBytes, err := json.Marshal(roles); err != nil {
-		s.logger.Warn("Failed to marshal roles for caching", zap.Error(err))
-	} else {
-		if err := s.redis.Set(ctx, cacheKey, rolesBytes, 5*time.Minute).Err(); err != nil {
-			s.logger.Warn("Failed to set cache for user roles", zap.Int64("uniqueID", uniqueID), zap.Error(err))
+	// the unique index on email is the source of truth, the lookup above only
+	// saves a round trip for the common case
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, exception.ExceptionUserEmailAlreadyExists
 		}
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	s.history.Record(ctx, user.UniqueID, hashedPassword)
+
+	codeRes, ex := s.sendRegisterCode(ctx, user.Email)
+	if ex != nil {
+		return nil, ex
+	}
+
+	return &dto.UserRegisterResDTO{
+		UniqueID:        user.UniqueID,
+		Email:           user.Email,
+		CodeExpireAt:    codeRes.CodeExpireAt,
+		CodeResendAfter: codeRes.CodeResendAfter,
+	}, nil
+}
+
+// reclaimUnverified deletes the expired registration holding the email of a
+// new one, any other user keeps it.
+func (s *userService) reclaimUnverified(ctx context.Context, user *model.User) *exception.Exception {
+	before := s.unverifiedBefore(time.Now())
+	if user.IsEmailVerified() || user.Status != model.UserStatusPending || user.CreatedAt == nil || !user.CreatedAt.Before(before) {
+		return exception.ExceptionUserEmailAlreadyExists
+	}
+	if err := s.userRepo.DeleteUnverified(ctx, user, before); errors.Is(err, gorm.ErrRecordNotFound) {
+		return exception.ExceptionUserEmailAlreadyExists
+	} else if err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
 	}
+	s.logger.Info("expired registration reclaimed", zap.Int64("userUniqueId", user.UniqueID))
+	return nil
+}
 
-	return roles, nil
+// PurgeUnverifiedUsers deletes the registrations whose email was not verified
+// in time.
+func (s *userService) PurgeUnverifiedUsers(ctx context.Context) error {
+	before := s.unverifiedBefore(time.Now())
+	users, err := s.userRepo.FindUnverifiedBefore(ctx, before, accountBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if err := s.userRepo.DeleteUnverified(ctx, user, before); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("delete unverified user %d: %w", user.UniqueID, err)
+		}
+		s.logger.Info("expired registration deleted", zap.Int64("userUniqueId", user.UniqueID))
+	}
+	return nil
 }
 
-func (s *userService) LoginByEmail(ctx context.Context, data dto.UserLoginByEmailReqDTO) (*dto.UserLoginByEmailResDTO, *exception.Exception) {
+func (s *userService) RegisterVerify(ctx context.Context, data dto.UserRegisterVerifyReqDTO) *exception.Exception {
 	user, err := s.userRepo.FindByEmail(ctx, data.Email)
 	if err != nil {
-		return nil, exception.ExceptionUserNotFound.AppendDetails(err.Error())
+		return exception.ExceptionUserNotFound.AppendDetails(err.Error())
+	}
+	if user.IsEmailVerified() {
+		return exception.ExceptionUserEmailVerified
+	}
+
+	if ex := s.verifyCode.Verify(ctx, VerifyCodeSceneRegister, user.Email, data.Code); ex != nil {
+		return ex
 	}
 
-	if !utils.CryptHashCompare(data.Password, user.Salt, user.Password) {
-		return nil, exception.ExceptionUserPasswordIncorrect
+	if err := s.userRepo.UpdateByMap(ctx, user.ID, emailVerifiedUpdates(user, time.Now())); err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
 	}
+	s.statusCache.Invalidate(ctx, user.UniqueID)
 
-	token, err := s.jwt.GenerateToken(user.UniqueID)
+	return nil
+}
+
+func (s *userService) RegisterResendCode(ctx context.Context, data dto.UserRegisterResendCodeReqDTO) (*dto.UserVerifyCodeResDTO, *exception.Exception) {
+	user, err := s.userRepo.FindByEmail(ctx, data.Email)
 	if err != nil {
-		return nil, exception.ExceptionTokenGenerateFailed.AppendDetails(err.Error())
+		return nil, exception.ExceptionUserNotFound.AppendDetails(err.Error())
+	}
+	if user.IsEmailVerified() {
+		return nil, exception.ExceptionUserEmailVerified
+	}
+	return s.sendRegisterCode(ctx, user.Email)
+}
+
+func (s *userService) sendRegisterCode(ctx context.Context, email string) (*dto.UserVerifyCodeResDTO, *exception.Exception) {
+	code, expireAt, ex := s.verifyCode.Issue(ctx, VerifyCodeSceneRegister, email)
+	if ex != nil {
+		return nil, ex
 	}
 
-	return &dto.UserLoginByEmailResDTO{
-		Token:     token,
-		ExpireAt:  s.jwt.ExpireAt().UnixMilli(),
-		RefreshAt: s.jwt.RefreshAt().UnixMilli(),
+	if err := s.mailer.Send(ctx, newRegisterCodeMail(email, code, s.config.VerifyCode.Expire)); err != nil {
+		s.logger.Error("Failed to send register code mail", zap.String("email", email), zap.Error(err))
+		return nil, exception.ExceptionServiceError.AppendDetails("send mail failed")
+	}
+
+	return &dto.UserVerifyCodeResDTO{
+		CodeExpireAt:    expireAt.UnixMilli(),
+		CodeResendAfter: time.Now().Add(s.config.VerifyCode.ResendInterval).UnixMilli(),
 	}, nil
 }
+
+func (s *userService) passwordResetKey(token string) string {
+	return fmt.Sprintf("user:password:reset:%s", utils.SHA256Hex(token))
+}
+
+func (s *userService) passwordResetUserKey(uniqueID int64) string {
+	return fmt.Sprintf("user:password:reset:user:%d", uniqueID)
+}
+
+// ForgotPassword mails a single-use reset link. It succeeds whether or not
+// the email belongs to a user, so it cannot be used to probe for accounts.
+// Failing to issue or mail the link is only logged for the same reason.
+func (s *userService) ForgotPassword(ctx context.Context, data dto.UserPasswordForgotReqDTO) *exception.Exception {
+	cooldownKey := fmt.Sprintf("user:password:reset:cooldown:%s", data.Email)
+	ok, err := s.redis.SetNX(ctx, cooldownKey, 1, s.config.VerifyCode.ResendInterval).Result()
+	if err != nil {
+		return exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	if !ok {
+		return exception.ExceptionTooManyRequests
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, data.Email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.logger.Info("Password reset requested for unknown email", zap.String("email", data.Email))
+		return nil
+	} else if err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	if err := s.sendPasswordReset(ctx, user); err != nil {
+		s.logger.Error("Failed to send password reset", zap.Int64("userUniqueId", user.UniqueID), zap.Error(err))
+	}
+	return nil
+}
+
+func (s *userService) sendPasswordReset(ctx context.Context, user *model.User) error {
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return err
+	}
+
+	expire := s.config.PasswordReset.Expire
+	userKey := s.passwordResetUserKey(user.UniqueID)
+
+	// a new link replaces the previous one
+	if previous, err := s.redis.Get(ctx, userKey).Result(); err == nil {
+		s.redis.Del(ctx, previous)
+	}
+
+	pipe := s.redis.TxPipeline()
+	pipe.Set(ctx, s.passwordResetKey(token), user.UniqueID, expire)
+	pipe.Set(ctx, userKey, s.passwordResetKey(token), expire)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf(s.config.PasswordReset.URL, token)
+	if err := s.mailer.Send(ctx, newPasswordResetMail(user.Email, link, expire)); err != nil {
+		return fmt.Errorf("send mail: %w", err)
+	}
+	return nil
+}
+
+func (s *userService) ResetPassword(ctx context.Context, data dto.UserPasswordResetReqDTO) *exception.Exception {
+	value, err := s.redis.Get(ctx, s.passwordResetKey(data.Token)).Result()
+	if err == redis.Nil {
+		return exception.ExceptionPasswordResetInvalid
+	} else if err != nil {
+		return exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+
+	uniqueID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return exception.ExceptionPasswordResetInvalid
+	}
+
+	user, err := s.userRepo.FindByUniqueID(ctx, uniqueID)
+	if err != nil {
+		return exception.ExceptionUserNotFound.AppendDetails(err.Error())
+	}
+
+	// a reused password keeps the token, the user can try another one
+	if ex := s.history.CheckReuse(ctx, user, data.Password); ex != nil {
+		return ex
+	}
+
+	hashedPassword, err := s.hasher.Hash(data.Password)
+	if err != nil {
+		return exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+
+	// GETDEL makes the token single-use even under concurrent requests
+	if err := s.redis.GetDel(ctx, s.passwordResetKey(data.Token)).Err(); err == redis.Nil {
+		return exception.ExceptionPasswordResetInvalid
+	} else if err != nil {
+		return exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	s.redis.Del(ctx, s.passwordResetUserKey(uniqueID))
+
+	updates := map[string]any{}
+	// the reset link proves ownership of the mailbox
+	if !user.IsEmailVerified() {
+		updates = emailVerifiedUpdates(user, time.Now())
+	}
+	updates["password"] = hashedPassword
+	if err := s.userRepo.UpdateByMap(ctx, user.ID, updates); err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	s.statusCache.Invalidate(ctx, user.UniqueID)
+	s.history.Record(ctx, user.UniqueID, hashedPassword)
+
+	return s.tokenService.RevokeUserTokens(ctx, user.UniqueID)
+}
+
+// SendLoginByMobileCode answers the same way whether or not the mobile
+// belongs to a user, the code is only sent to registered numbers.
+func (s *userService) SendLoginByMobileCode(ctx context.Context, data dto.UserLoginByMobileSendCodeReqDTO) (*dto.UserVerifyCodeResDTO, *exception.Exception) {
+	code, expireAt, ex := s.verifyCode.Issue(ctx, VerifyCodeSceneLoginMobile, data.Mobile)
+	if ex != nil {
+		return nil, ex
+	}
+
+	res := &dto.UserVerifyCodeResDTO{
+		CodeExpireAt:    expireAt.UnixMilli(),
+		CodeResendAfter: time.Now().Add(s.config.VerifyCode.ResendInterval).UnixMilli(),
+	}
+
+	_, err := s.userRepo.FindByMobile(ctx, data.Mobile)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.logger.Info("Login code requested for unknown mobile", zap.String("mobile", data.Mobile))
+		return res, nil
+	} else if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	message := fmt.Sprintf("Your login code is %s, it expires in %s.", code, s.config.VerifyCode.Expire)
+	if err := s.sms.Send(ctx, data.Mobile, message); err != nil {
+		s.logger.Error("Failed to send login code sms", zap.String("mobile", data.Mobile), zap.Error(err))
+		return nil, exception.ExceptionServiceError.AppendDetails("send sms failed")
+	}
+
+	return res, nil
+}
+
+func (s *userService) LoginByMobile(ctx context.Context, data dto.UserLoginByMobileReqDTO, meta dto.ClientMeta) (*dto.UserLoginResDTO, *exception.Exception) {
+	if ex := s.verifyCode.Verify(ctx, VerifyCodeSceneLoginMobile, data.Mobile, data.Code); ex != nil {
+		return nil, ex
+	}
+
+	user, err := s.userRepo.FindByMobile(ctx, data.Mobile)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, exception.ExceptionVerifyCodeInvalid
+	} else if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	if !user.IsEmailVerified() {
+		return nil, exception.ExceptionUserEmailNotVerified
+	}
+
+	return completeLogin(ctx, s.tokenService, s.mfaService, user, meta)
+}
+
+// CheckUserStatus is a jwt.TokenValidator rejecting tokens of users that were
+// disabled or banned after the token was issued.
+func (s *userService) CheckUserStatus(ctx context.Context, claims *jwt.JWTClaims) *exception.Exception {
+	return s.statusCache.Check(ctx, claims.UserUniqueID)
+}
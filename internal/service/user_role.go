@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+)
+
+// userRoleResolver follows role inheritance: a user holds their roles and
+// every role those inherit from, transitively.
+type userRoleResolver struct {
+	userRoleRepo repo.UserRoleRepo
+}
+
+func newUserRoleResolver(userRoleRepo repo.UserRoleRepo) *userRoleResolver {
+	return &userRoleResolver{
+		userRoleRepo: userRoleRepo,
+	}
+}
+
+// Expand returns roles followed by the roles they inherit from. There are few
+// roles, all of them are loaded at once.
+func (r *userRoleResolver) Expand(ctx context.Context, roles []*model.UserRole) ([]*model.UserRole, error) {
+	if len(roles) == 0 {
+		return roles, nil
+	}
+	all, err := r.userRoleRepo.FindMany(ctx, repo.Preload("Parents"))
+	if err != nil {
+		return nil, err
+	}
+	return expandUserRoles(roles, all), nil
+}
+
+// expandUserRoles walks the parents breadth first, all holds every role with
+// its Parents. A role is visited once, so a cycle cannot loop.
+func expandUserRoles(roles []*model.UserRole, all []*model.UserRole) []*model.UserRole {
+	byID := make(map[uint64]*model.UserRole, len(all))
+	for _, role := range all {
+		byID[role.ID] = role
+	}
+
+	var expanded []*model.UserRole
+	visited := make(map[uint64]bool)
+	queue := make([]uint64, 0, len(roles))
+	for _, role := range roles {
+		queue = append(queue, role.ID)
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		role, ok := byID[id]
+		if !ok || visited[id] {
+			continue
+		}
+		visited[id] = true
+		expanded = append(expanded, role)
+		for _, parent := range role.Parents {
+			queue = append(queue, parent.ID)
+		}
+	}
+	return expanded
+}
+
+func hasUserRole(roles []*model.UserRole, code model.UserRoleEnum) bool {
+	for _, role := range roles {
+		if role.Code == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"super-web-server/internal/config"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/logger"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// userRolesVersionKey is bumped when a role itself changes, which may
+	// affect any user
+	userRolesVersionKey = "user:roles:version"
+	// userRoleLocalCacheSize bounds the in-process copies, beyond it the
+	// expired ones are dropped
+	userRoleLocalCacheSize = 10000
+	// userRoleInvalidateAll is the message evicting every in-process copy
+	userRoleInvalidateAll = "*"
+)
+
+// userRolesUserVersionKey is bumped when the roles of the user change.
+func userRolesUserVersionKey(uniqueID int64) string {
+	return fmt.Sprintf("user:roles:version:%d", uniqueID)
+}
+
+// userRolesCacheKey names the versions the entry was read at, an entry
+// written after a version was bumped lands under a key nobody reads anymore.
+func userRolesCacheKey(uniqueID int64, version, userVersion int64) string {
+	return fmt.Sprintf("user:roles:%d:%d:%d", uniqueID, version, userVersion)
+}
+
+// userRoleCacheEntry holds the effective roles of a user, the inherited ones
+// included, and the permissions they grant.
+type userRoleCacheEntry struct {
+	Roles       []*model.UserRole      `json:"roles"`
+	Permissions []model.PermissionEnum `json:"permissions"`
+}
+
+type userRoleLocalEntry struct {
+	entry     *userRoleCacheEntry
+	expiresAt time.Time
+}
+
+// userRoleCache resolves the roles and permissions of a user on every request
+// that checks one. Entries are kept in process for a short while and in Redis
+// under versioned keys. Changes bump the versions and are published on a
+// channel every instance listens to, so the in-process copies are evicted
+// everywhere. A message lost while reconnecting delays a change by LocalTTL at
+// most. There is one cache per process, shared by the services.
+type userRoleCache struct {
+	userRepo       repo.UserRepo
+	permissionRepo repo.PermissionRepo
+	roles          *userRoleResolver
+	redis          *redis.Client
+	logger         *logger.Logger
+	config         config.RoleCacheConfig
+
+	mu         sync.Mutex
+	local      map[int64]userRoleLocalEntry
+	generation uint64 // bumped on every eviction, a load that raced one is not kept
+	pubsub     *redis.PubSub
+	done       chan struct{}
+}
+
+func newUserRoleCache(userRepo repo.UserRepo, userRoleRepo repo.UserRoleRepo, permissionRepo repo.PermissionRepo, redis *redis.Client, logger *logger.Logger, config config.RoleCacheConfig) *userRoleCache {
+	return &userRoleCache{
+		userRepo:       userRepo,
+		permissionRepo: permissionRepo,
+		roles:          newUserRoleResolver(userRoleRepo),
+		redis:          redis,
+		logger:         logger,
+		config:         config,
+		local:          make(map[int64]userRoleLocalEntry),
+	}
+}
+
+func (c *userRoleCache) Roles(ctx context.Context, uniqueID int64) ([]*model.UserRole, *exception.Exception) {
+	entry, ex := c.get(ctx, uniqueID)
+	if ex != nil {
+		return nil, ex
+	}
+	return entry.Roles, nil
+}
+
+func (c *userRoleCache) Permissions(ctx context.Context, uniqueID int64) ([]model.PermissionEnum, *exception.Exception) {
+	entry, ex := c.get(ctx, uniqueID)
+	if ex != nil {
+		return nil, ex
+	}
+	return entry.Permissions, nil
+}
+
+// HasPermissions reports whether the user holds all of permissions.
+func (c *userRoleCache) HasPermissions(ctx context.Context, uniqueID int64, permissions ...model.PermissionEnum) (bool, *exception.Exception) {
+	granted, ex := c.Permissions(ctx, uniqueID)
+	if ex != nil {
+		return false, ex
+	}
+	for _, permission := range permissions {
+		if !slices.Contains(granted, permission) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (c *userRoleCache) get(ctx context.Context, uniqueID int64) (*userRoleCacheEntry, *exception.Exception) {
+	if c.config.LocalTTL <= 0 {
+		return c.getShared(ctx, uniqueID)
+	}
+
+	c.mu.Lock()
+	local, ok := c.local[uniqueID]
+	generation := c.generation
+	c.mu.Unlock()
+	if ok && time.Now().Before(local.expiresAt) {
+		return local.entry, nil
+	}
+
+	entry, ex := c.getShared(ctx, uniqueID)
+	if ex != nil {
+		return nil, ex
+	}
+
+	c.mu.Lock()
+	if c.generation == generation {
+		if len(c.local) >= userRoleLocalCacheSize {
+			c.dropExpiredLocked()
+		}
+		c.local[uniqueID] = userRoleLocalEntry{entry: entry, expiresAt: time.Now().Add(c.config.LocalTTL)}
+	}
+	c.mu.Unlock()
+	return entry, nil
+}
+
+func (c *userRoleCache) dropExpiredLocked() {
+	now := time.Now()
+	for uniqueID, local := range c.local {
+		if !now.Before(local.expiresAt) {
+			delete(c.local, uniqueID)
+		}
+	}
+	if len(c.local) >= userRoleLocalCacheSize {
+		clear(c.local)
+	}
+}
+
+// getShared reads the entry from Redis, or from the database when missing.
+// Without the versions the entry is not cached at all.
+func (c *userRoleCache) getShared(ctx context.Context, uniqueID int64) (*userRoleCacheEntry, *exception.Exception) {
+	versions, err := c.redis.MGet(ctx, userRolesVersionKey, userRolesUserVersionKey(uniqueID)).Result()
+	if err != nil {
+		c.logger.Warn("Failed to get user role cache versions", zap.Int64("uniqueID", uniqueID), zap.Error(err))
+		return c.load(ctx, uniqueID)
+	}
+	cacheKey := userRolesCacheKey(uniqueID, parseCacheVersion(versions[0]), parseCacheVersion(versions[1]))
+
+	cache, err := c.redis.Get(ctx, cacheKey).Bytes()
+	if err == nil {
+		var entry userRoleCacheEntry
+		if err := json.Unmarshal(cache, &entry); err == nil {
+			return &entry, nil
+		}
+		c.logger.Warn("Failed to unmarshal cached user roles", zap.Int64("uniqueID", uniqueID), zap.Error(err))
+	} else if err != redis.Nil {
+		c.logger.Warn("Failed to get cached user roles", zap.Int64("uniqueID", uniqueID), zap.Error(err))
+	}
+
+	entry, ex := c.load(ctx, uniqueID)
+	if ex != nil {
+		return nil, ex
+	}
+	if value, err := json.Marshal(entry); err != nil {
+		c.logger.Warn("Failed to marshal user roles for caching", zap.Error(err))
+	} else if err := c.redis.Set(ctx, cacheKey, value, c.config.TTL).Err(); err != nil {
+		c.logger.Warn("Failed to set cache for user roles", zap.Int64("uniqueID", uniqueID), zap.Error(err))
+	}
+	return entry, nil
+}
+
+func parseCacheVersion(value any) int64 {
+	version, _ := value.(string)
+	parsed, _ := strconv.ParseInt(version, 10, 64)
+	return parsed
+}
+
+func (c *userRoleCache) load(ctx context.Context, uniqueID int64) (*userRoleCacheEntry, *exception.Exception) {
+	user, err := c.userRepo.FindByUniqueID(ctx, uniqueID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, exception.ExceptionUserNotFound
+	} else if err != nil {
+		return nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	roles, permissions, ex := c.grants(ctx, user.Roles)
+	if ex != nil {
+		return nil, ex
+	}
+	return &userRoleCacheEntry{
+		Roles:       roles,
+		Permissions: permissions,
+	}, nil
+}
+
+// grants returns roles with the roles they inherit from and the permissions
+// all of them grant.
+func (c *userRoleCache) grants(ctx context.Context, roles []*model.UserRole) ([]*model.UserRole, []model.PermissionEnum, *exception.Exception) {
+	roles, err := c.roles.Expand(ctx, roles)
+	if err != nil {
+		return nil, nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	if len(roles) == 0 {
+		return roles, []model.PermissionEnum{}, nil
+	}
+
+	roleIDs := make([]uint64, 0, len(roles))
+	for _, role := range roles {
+		roleIDs = append(roleIDs, role.ID)
+	}
+	permissions, err := c.permissionRepo.FindCodesByRoleIDs(ctx, roleIDs)
+	if err != nil {
+		return nil, nil, exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+	return roles, append([]model.PermissionEnum{}, permissions...), nil
+}
+
+// CheckGrants refuses to let the actor hand out what they do not hold: the
+// permissions and those granted by the roles, inheritance followed, have to
+// be among the effective permissions of the actor, or admins could raise
+// their own rights through a role.
+func (c *userRoleCache) CheckGrants(ctx context.Context, actorUniqueID int64, roles []*model.UserRole, permissions []model.PermissionEnum) *exception.Exception {
+	_, granted, ex := c.grants(ctx, roles)
+	if ex != nil {
+		return ex
+	}
+	granted = append(granted, permissions...)
+
+	held, ex := c.Permissions(ctx, actorUniqueID)
+	if ex != nil {
+		return ex
+	}
+	for _, permission := range granted {
+		if !slices.Contains(held, permission) {
+			return exception.ExceptionForbidden.AppendDetails("cannot grant " + string(permission) + " without holding it")
+		}
+	}
+	return nil
+}
+
+// InvalidateUser has to be called after the roles of the user changed.
+func (c *userRoleCache) InvalidateUser(ctx context.Context, uniqueID int64) {
+	pipe := c.redis.TxPipeline()
+	// a timestamp instead of a counter, so a version is never reused once the
+	// key expired. It outlives the entries written at the version it replaced,
+	// when it expires no entry at version 0 is left
+	pipe.Set(ctx, userRolesUserVersionKey(uniqueID), time.Now().UnixNano(), 2*c.config.TTL)
+	c.invalidate(ctx, pipe, strconv.FormatInt(uniqueID, 10))
+}
+
+// InvalidateAll has to be called after a role changed, it may affect any
+// user.
+func (c *userRoleCache) InvalidateAll(ctx context.Context) {
+	pipe := c.redis.TxPipeline()
+	pipe.Incr(ctx, userRolesVersionKey)
+	c.invalidate(ctx, pipe, userRoleInvalidateAll)
+}
+
+func (c *userRoleCache) invalidate(ctx context.Context, pipe redis.Pipeliner, message string) {
+	c.evict(message)
+	pipe.Publish(ctx, c.config.Channel, message)
+	if _, err := pipe.Exec(ctx); err != nil {
+		c.logger.Error("Failed to invalidate cached user roles", zap.String("target", message), zap.Error(err))
+	}
+}
+
+// evict drops in-process copies, of every user for userRoleInvalidateAll.
+func (c *userRoleCache) evict(message string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	if message == userRoleInvalidateAll {
+		clear(c.local)
+		return
+	}
+	uniqueID, err := strconv.ParseInt(message, 10, 64)
+	if err != nil {
+		clear(c.local)
+		return
+	}
+	delete(c.local, uniqueID)
+}
+
+// Start listens for the invalidations published by every instance, nothing
+// is kept in process without it.
+func (c *userRoleCache) Start() {
+	if c.config.LocalTTL <= 0 {
+		return
+	}
+	c.pubsub = c.redis.Subscribe(context.Background(), c.config.Channel)
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		for message := range c.pubsub.Channel() {
+			c.evict(message.Payload)
+		}
+	}()
+}
+
+func (c *userRoleCache) Stop() {
+	if c.pubsub == nil {
+		return
+	}
+	if err := c.pubsub.Close(); err != nil {
+		c.logger.Warn("Failed to close user role invalidation subscription", zap.Error(err))
+	}
+	<-c.done
+	c.evict(userRoleInvalidateAll)
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"super-web-server/internal/model"
+	"testing"
+)
+
+// testRoles links the roles by id, parents maps a role to the roles it
+// inherits from.
+func testRoles(parents map[uint64][]uint64, ids ...uint64) map[uint64]*model.UserRole {
+	roles := make(map[uint64]*model.UserRole, len(ids))
+	for _, id := range ids {
+		roles[id] = &model.UserRole{BaseModel: model.BaseModel{ID: id}, Code: model.UserRoleEnum("role:" + string(rune('a'+id-1)))}
+	}
+	for id, parentIDs := range parents {
+		for _, parentID := range parentIDs {
+			roles[id].Parents = append(roles[id].Parents, roles[parentID])
+		}
+	}
+	return roles
+}
+
+func roleIDs(roles []*model.UserRole) []uint64 {
+	ids := make([]uint64, 0, len(roles))
+	for _, role := range roles {
+		ids = append(ids, role.ID)
+	}
+	return ids
+}
+
+func TestExpandUserRoles(t *testing.T) {
+	tests := []struct {
+		name    string
+		parents map[uint64][]uint64
+		start   []uint64
+		want    []uint64
+	}{
+		{"no parents", nil, []uint64{1}, []uint64{1}},
+		{"chain", map[uint64][]uint64{1: {2}, 2: {3}}, []uint64{1}, []uint64{1, 2, 3}},
+		{"breadth first", map[uint64][]uint64{1: {2, 3}, 2: {4}}, []uint64{1}, []uint64{1, 2, 3, 4}},
+		{"diamond visits once", map[uint64][]uint64{1: {2, 3}, 2: {4}, 3: {4}}, []uint64{1}, []uint64{1, 2, 3, 4}},
+		{"cycle", map[uint64][]uint64{1: {2}, 2: {3}, 3: {1}}, []uint64{2}, []uint64{2, 3, 1}},
+		{"self parent", map[uint64][]uint64{1: {1}}, []uint64{1}, []uint64{1}},
+		{"several held", map[uint64][]uint64{1: {3}, 2: {3}}, []uint64{1, 2}, []uint64{1, 2, 3}},
+		{"held role inherited by another", map[uint64][]uint64{1: {2}}, []uint64{2, 1}, []uint64{2, 1}},
+		{"parents only", map[uint64][]uint64{1: {2}}, []uint64{2}, []uint64{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roles := testRoles(tt.parents, 1, 2, 3, 4)
+			all := []*model.UserRole{roles[1], roles[2], roles[3], roles[4]}
+			start := make([]*model.UserRole, 0, len(tt.start))
+			for _, id := range tt.start {
+				start = append(start, roles[id])
+			}
+
+			got := roleIDs(expandUserRoles(start, all))
+			if len(got) != len(tt.want) {
+				t.Fatalf("expandUserRoles() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expandUserRoles() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandUserRolesSkipsUnknownRoles(t *testing.T) {
+	roles := testRoles(map[uint64][]uint64{1: {2}}, 1, 2)
+	// role 2 was deleted, the held reference and the parent are dangling
+	got := roleIDs(expandUserRoles([]*model.UserRole{roles[1], {BaseModel: model.BaseModel{ID: 9}}}, []*model.UserRole{roles[1]}))
+	if len(got) != 1 || got[0] != 1 {
+		t.Fatalf("expandUserRoles() = %v, want [1]", got)
+	}
+}
+
+func TestUserRoleResolverExpand(t *testing.T) {
+	roles := testRoles(map[uint64][]uint64{1: {2}, 2: {3}, 3: {1}}, 1, 2, 3)
+	// the held role comes without Parents, as preloaded with a user
+	held := &model.UserRole{BaseModel: model.BaseModel{ID: 1}, Code: roles[1].Code}
+	resolver := newUserRoleResolver(&fakeUserRoleRepo{roles: []*model.UserRole{roles[1], roles[2], roles[3]}})
+
+	got, err := resolver.Expand(context.Background(), []*model.UserRole{held})
+	if err != nil {
+		t.Fatalf("Expand: %v", err)
+	}
+	if ids := roleIDs(got); len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 {
+		t.Errorf("Expand() = %v, want [1 2 3]", ids)
+	}
+	if !hasUserRole(got, roles[3].Code) {
+		t.Errorf("hasUserRole(%s) = false, want the inherited role", roles[3].Code)
+	}
+
+	got, err = resolver.Expand(context.Background(), nil)
+	if err != nil || len(got) != 0 {
+		t.Errorf("Expand(nil) = %v, %v, want no roles", got, err)
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/internal/repo"
+	"super-web-server/pkg/logger"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const userStatusCacheTTL = 5 * time.Minute
+
+func userStatusCacheKey(uniqueID int64) string {
+	return fmt.Sprintf("user:status:%d", uniqueID)
+}
+
+// userStatusException tells why a user may not sign in or use a token, nil
+// when the account is active.
+func userStatusException(user *model.User, now time.Time) *exception.Exception {
+	switch user.EffectiveStatus(now) {
+	case model.UserStatusActive:
+		return nil
+	case model.UserStatusPending:
+		return exception.ExceptionUserEmailNotVerified
+	case model.UserStatusBanned:
+		ex := exception.ExceptionUserBanned
+		if user.StatusReason != "" {
+			ex = ex.AppendDetails(user.StatusReason)
+		}
+		if user.BannedUntil != nil {
+			ex = ex.AppendDetails("until " + user.BannedUntil.UTC().Format(time.RFC3339))
+		}
+		return ex
+	default:
+		ex := exception.ExceptionUserDisabled
+		if user.StatusReason != "" {
+			ex = ex.AppendDetails(user.StatusReason)
+		}
+		return ex
+	}
+}
+
+// emailVerifiedUpdates marks the email of user as verified, which also
+// activates an account that was only pending verification.
+func emailVerifiedUpdates(user *model.User, now time.Time) map[string]any {
+	updates := map[string]any{"email_verified_at": now}
+	if user.Status == model.UserStatusPending {
+		updates["status"] = model.UserStatusActive
+	}
+	return updates
+}
+
+// userStatusCache answers "may this user still act" on every authenticated
+// request. The status fields are cached under user:status:<id>, every status
+// change has to call Invalidate.
+type userStatusCache struct {
+	userRepo repo.UserRepo
+	redis    *redis.Client
+	logger   *logger.Logger
+}
+
+type userStatusCacheEntry struct {
+	Status      model.UserStatus `json:"status"`
+	Reason      string           `json:"reason,omitempty"`
+	BannedUntil *time.Time       `json:"bannedUntil,omitempty"`
+}
+
+func newUserStatusCache(userRepo repo.UserRepo, redis *redis.Client, logger *logger.Logger) *userStatusCache {
+	return &userStatusCache{
+		userRepo: userRepo,
+		redis:    redis,
+		logger:   logger,
+	}
+}
+
+// Check returns the exception for a user that is not active (anymore).
+func (c *userStatusCache) Check(ctx context.Context, uniqueID int64) *exception.Exception {
+	cacheKey := userStatusCacheKey(uniqueID)
+	now := time.Now()
+
+	cache, err := c.redis.Get(ctx, cacheKey).Bytes()
+	if err == nil {
+		var entry userStatusCacheEntry
+		if err := json.Unmarshal(cache, &entry); err == nil {
+			return userStatusException(&model.User{
+				Status:       entry.Status,
+				StatusReason: entry.Reason,
+				BannedUntil:  entry.BannedUntil,
+			}, now)
+		}
+		c.logger.Warn("Failed to unmarshal cached user status", zap.Int64("uniqueID", uniqueID), zap.Error(err))
+	} else if err != redis.Nil {
+		c.logger.Warn("Failed to get cached user status", zap.Int64("uniqueID", uniqueID), zap.Error(err))
+	}
+
+	user, err := c.userRepo.FindByUniqueID(ctx, uniqueID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return exception.ExceptionUserNotFound
+	} else if err != nil {
+		return exception.ExceptionDatabaseError.AppendDetails(err.Error())
+	}
+
+	// a temporary ban must not outlive its end in the cache
+	ttl := userStatusCacheTTL
+	if user.Status == model.UserStatusBanned && user.BannedUntil != nil {
+		if remaining := time.Until(*user.BannedUntil); remaining < ttl {
+			ttl = remaining
+		}
+	}
+	if ttl > 0 {
+		entry, _ := json.Marshal(userStatusCacheEntry{
+			Status:      user.Status,
+			Reason:      user.StatusReason,
+			BannedUntil: user.BannedUntil,
+		})
+		if err := c.redis.Set(ctx, cacheKey, entry, ttl).Err(); err != nil {
+			c.logger.Warn("Failed to set cache for user status", zap.Int64("uniqueID", uniqueID), zap.Error(err))
+		}
+	}
+
+	return userStatusException(user, now)
+}
+
+func (c *userStatusCache) Invalidate(ctx context.Context, uniqueID int64) {
+	if err := c.redis.Del(ctx, userStatusCacheKey(uniqueID)).Err(); err != nil {
+		c.logger.Warn("Failed to invalidate cached user status", zap.Int64("uniqueID", uniqueID), zap.Error(err))
+	}
+}
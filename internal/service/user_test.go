@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"super-web-server/internal/config"
+	"super-web-server/internal/dto"
+	"super-web-server/internal/exception"
+	"super-web-server/internal/model"
+	"super-web-server/pkg/password"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestUserService(t *testing.T, users []*model.User, mailer *fakeMailer) *userService {
+	t.Helper()
+	client, _ := newTestRedis(t)
+	return &userService{
+		userRepo: &fakeUserRepo{users: users},
+		logger:   newTestLogger(),
+		redis:    client,
+		mailer:   mailer,
+		config: &config.Config{
+			VerifyCode:    config.VerifyCodeConfig{ResendInterval: time.Minute},
+			PasswordReset: config.PasswordResetConfig{Expire: 30 * time.Minute, URL: "http://localhost/reset?token=%s"},
+		},
+	}
+}
+
+func TestForgotPasswordAnswersTheSame(t *testing.T) {
+	users := []*model.User{{UniqueID: 1, Email: "known@example.com"}}
+	tests := []struct {
+		name     string
+		email    string
+		mailErr  error
+		wantSent int
+	}{
+		{"known email", "known@example.com", nil, 1},
+		{"unknown email", "unknown@example.com", nil, 0},
+		{"mail fails", "known@example.com", errors.New("smtp down"), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mailer := &fakeMailer{err: tt.mailErr}
+			s := newTestUserService(t, users, mailer)
+			if ex := s.ForgotPassword(context.Background(), dto.UserPasswordForgotReqDTO{Email: tt.email}); ex != nil {
+				t.Fatalf("ForgotPassword() = %v, want success", ex)
+			}
+			if len(mailer.sent) != tt.wantSent {
+				t.Errorf("sent %d mails, want %d", len(mailer.sent), tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestForgotPasswordCooldown(t *testing.T) {
+	s := newTestUserService(t, nil, &fakeMailer{})
+	data := dto.UserPasswordForgotReqDTO{Email: "unknown@example.com"}
+	if ex := s.ForgotPassword(context.Background(), data); ex != nil {
+		t.Fatalf("first ForgotPassword() = %v, want success", ex)
+	}
+	if ex := s.ForgotPassword(context.Background(), data); !ex.Is(exception.ExceptionTooManyRequests) {
+		t.Errorf("second ForgotPassword() = %v, want too many requests", ex)
+	}
+}
+
+func TestRehashPasswordUpgradesLegacyHash(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("secretpepper"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+	// the form migrateLegacyPasswordSalt leaves the former salt column in
+	encoded := "$bcrypt-salted$pepper$" + string(legacy)
+
+	for _, tt := range []struct {
+		name      string
+		updateErr error
+	}{
+		{"stored", nil},
+		{"store fails", errors.New("db down")},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestUserService(t, nil, &fakeMailer{})
+			s.hasher = password.NewHasher(password.Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+			users := s.userRepo.(*fakeUserRepo)
+			users.updateErr = tt.updateErr
+			user := &model.User{UniqueID: 1, Password: encoded}
+
+			ok, needsRehash, err := s.hasher.Verify("secret", user.Password)
+			if err != nil || !ok || !needsRehash {
+				t.Fatalf("Verify() = %v, %v, %v, want a match needing a rehash", ok, needsRehash, err)
+			}
+			s.rehashPassword(context.Background(), user, "secret")
+
+			if tt.updateErr != nil {
+				if user.Password != encoded {
+					t.Errorf("password = %q, want the legacy hash kept until it is stored", user.Password)
+				}
+				return
+			}
+			if len(users.updates) != 1 || users.updates[0]["password"] != user.Password {
+				t.Fatalf("updates = %v, want the new hash %q stored", users.updates, user.Password)
+			}
+			ok, needsRehash, err = s.hasher.Verify("secret", user.Password)
+			if err != nil || !ok || needsRehash {
+				t.Errorf("Verify(new hash) = %v, %v, %v, want a current match", ok, needsRehash, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"super-web-server/internal/config"
+	"super-web-server/internal/exception"
+	"super-web-server/pkg/utils"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// VerifyCodeScene identifies what a verify code is issued for, so a code
+// issued for one flow can never be used in another.
+type VerifyCodeScene string
+
+const (
+	VerifyCodeSceneRegister      VerifyCodeScene = "register"
+	VerifyCodeSceneLoginMobile   VerifyCodeScene = "login:mobile"
+	VerifyCodeSceneProfileMobile VerifyCodeScene = "profile:mobile"
+	VerifyCodeSceneEmailChange   VerifyCodeScene = "email:change"
+	VerifyCodeSceneReauth        VerifyCodeScene = "reauth"
+)
+
+// incrAttemptsScript increments the attempts of a code only while the code
+// still exists, so an expired code is never recreated without a ttl.
+var incrAttemptsScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return -1
+end
+return redis.call("HINCRBY", KEYS[1], "attempts", 1)
+`)
+
+// verifyCodeStore keeps numeric verify codes in redis. Only the hash of a code
+// is stored, every code has an expiry, a limited number of attempts and a
+// resend cooldown.
+type verifyCodeStore struct {
+	redis  *redis.Client
+	config config.VerifyCodeConfig
+}
+
+func newVerifyCodeStore(redis *redis.Client, config config.VerifyCodeConfig) *verifyCodeStore {
+	return &verifyCodeStore{
+		redis:  redis,
+		config: config,
+	}
+}
+
+func (s *verifyCodeStore) codeKey(scene VerifyCodeScene, target string) string {
+	return fmt.Sprintf("verify_code:%s:%s", scene, target)
+}
+
+func (s *verifyCodeStore) cooldownKey(scene VerifyCodeScene, target string) string {
+	return fmt.Sprintf("verify_code:cooldown:%s:%s", scene, target)
+}
+
+func (s *verifyCodeStore) hash(scene VerifyCodeScene, target, code string) string {
+	return utils.SHA256Hex(fmt.Sprintf("%s:%s:%s", scene, target, code))
+}
+
+// Issue generates a new code for target and returns it together with its expire time.
+// A previously issued code for the same scene and target is replaced.
+func (s *verifyCodeStore) Issue(ctx context.Context, scene VerifyCodeScene, target string) (string, time.Time, *exception.Exception) {
+	ok, err := s.redis.SetNX(ctx, s.cooldownKey(scene, target), 1, s.config.ResendInterval).Result()
+	if err != nil {
+		return "", time.Time{}, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	if !ok {
+		return "", time.Time{}, exception.ExceptionVerifyCodeTooFrequent
+	}
+
+	code, err := utils.GenerateSecureRandomCodeOnlyNumber(s.config.Length)
+	if err != nil {
+		return "", time.Time{}, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+
+	key := s.codeKey(scene, target)
+	pipe := s.redis.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, "hash", s.hash(scene, target, code), "attempts", 0)
+	pipe.Expire(ctx, key, s.config.Expire)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", time.Time{}, exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+
+	return code, time.Now().Add(s.config.Expire), nil
+}
+
+// Verify checks code for target. A matching code is consumed, a code that
+// has been tried too many times is dropped.
+func (s *verifyCodeStore) Verify(ctx context.Context, scene VerifyCodeScene, target, code string) *exception.Exception {
+	key := s.codeKey(scene, target)
+
+	hash, err := s.redis.HGet(ctx, key, "hash").Result()
+	if err == redis.Nil {
+		return exception.ExceptionVerifyCodeInvalid
+	} else if err != nil {
+		return exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+
+	attempts, err := incrAttemptsScript.Run(ctx, s.redis, []string{key}).Int64()
+	if err != nil {
+		return exception.ExceptionInternalServerError.AppendDetails(err.Error())
+	}
+	if attempts < 0 {
+		return exception.ExceptionVerifyCodeInvalid
+	}
+	if attempts > int64(s.config.MaxAttempts) {
+		s.redis.Del(ctx, key)
+		return exception.ExceptionVerifyCodeAttempts
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(s.hash(scene, target, code))) != 1 {
+		return exception.ExceptionVerifyCodeInvalid
+	}
+
+	s.redis.Del(ctx, key)
+	return nil
+}
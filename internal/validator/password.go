@@ -0,0 +1,30 @@
+package validator
+
+import (
+	"super-web-server/pkg/password"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// PasswordTag validates a new password against the configured policy.
+const PasswordTag = "password"
+
+var passwordPolicy = &password.Policy{}
+
+// RegisterPasswordPolicy installs policy behind the "password" binding tag.
+func RegisterPasswordPolicy(policy *password.Policy) {
+	passwordPolicy = policy
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		_ = v.RegisterValidation(PasswordTag, func(fl validator.FieldLevel) bool {
+			_, ok := passwordPolicy.Check(fl.Field().String())
+			return ok
+		})
+	}
+}
+
+// PasswordPolicy is the policy behind the "password" tag, translations use it
+// to tell which rule a password broke.
+func PasswordPolicy() *password.Policy {
+	return passwordPolicy
+}
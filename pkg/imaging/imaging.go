@@ -0,0 +1,90 @@
+// Package imaging decodes untrusted uploaded images and scales them down
+// with the standard library only.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"slices"
+
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("imaging: unsupported image format")
+	ErrTooManyPixels     = errors.New("imaging: image dimensions too large")
+)
+
+// Formats are the formats Decode accepts.
+var Formats = []string{"jpeg", "png", "gif"}
+
+// Decode decodes a JPEG, PNG or GIF image. The dimensions are read from the
+// header first, so an image with more than maxPixels pixels is refused before
+// any memory is allocated for it.
+func Decode(data []byte, maxPixels int) (image.Image, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupportedFormat
+	}
+	if !slices.Contains(Formats, format) {
+		return nil, "", ErrUnsupportedFormat
+	}
+	// divided rather than multiplied, forged dimensions must not overflow
+	if config.Width <= 0 || config.Height <= 0 || config.Width > maxPixels/config.Height {
+		return nil, "", ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	return img, format, nil
+}
+
+// Thumbnail crops the centered square of src and scales it down to size x
+// size by averaging the source pixels each target pixel covers. Smaller
+// images are not scaled up. Transparent areas become white, so the result
+// can be stored as JPEG.
+func Thumbnail(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	offset := image.Pt(bounds.Min.X+(bounds.Dx()-side)/2, bounds.Min.Y+(bounds.Dy()-side)/2)
+
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(square, square.Bounds(), src, offset, draw.Over)
+	if side <= size {
+		return square
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		y0, y1 := y*side/size, (y+1)*side/size
+		for x := 0; x < size; x++ {
+			x0, x1 := x*side/size, (x+1)*side/size
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := square.Pix[sy*square.Stride+x0*4 : sy*square.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += int(row[i])
+					g += int(row[i+1])
+					b += int(row[i+2])
+					a += int(row[i+3])
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,87 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// forgePNG rewrites the dimensions in the IHDR chunk of a valid PNG, the
+// pixel data no longer matches but the header decodes.
+func forgePNG(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+	data := encodePNG(t, 1, 1)
+	// signature (8), length (4), "IHDR" (4), then width and height
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      []byte
+		maxPixels int
+		wantErr   error
+	}{
+		{"within limit", encodePNG(t, 10, 10), 100, nil},
+		{"over limit", encodePNG(t, 10, 11), 100, ErrTooManyPixels},
+		{"forged huge header", forgePNG(t, 100_000, 100_000), 8_000_000, ErrTooManyPixels},
+		{"forged overflowing header", forgePNG(t, 1<<31, 1<<31), 8_000_000, ErrUnsupportedFormat},
+		{"not an image", []byte("not an image"), 100, ErrUnsupportedFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, _, err := Decode(tt.data, tt.maxPixels)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decode() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && img == nil {
+				t.Fatal("Decode() returned no image")
+			}
+		})
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			src.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	tests := []struct {
+		name string
+		size int
+		want int
+	}{
+		{"scaled down", 10, 10},
+		{"never scaled up", 64, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := Thumbnail(src, tt.size)
+			if dst.Bounds().Dx() != tt.want || dst.Bounds().Dy() != tt.want {
+				t.Fatalf("Thumbnail() = %v, want %dx%d", dst.Bounds(), tt.want, tt.want)
+			}
+			if got := dst.RGBAAt(tt.want/2, tt.want/2); got != (color.RGBA{R: 255, A: 255}) {
+				t.Errorf("center = %v, want opaque red", got)
+			}
+		})
+	}
+}
@@ -1,36 +1,162 @@
 package jwt
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net/http"
 	"strings"
 	"super-web-server/internal/ctx"
 	"super-web-server/internal/exception"
+	"super-web-server/pkg/jwk"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+// jwksMaxAge is how long verifiers may cache the JWKS.
+const jwksMaxAge = 5 * time.Minute
+
 type Config struct {
-	Secret string
-	Expire time.Duration
-	Issuer string
+	Secret        string
+	Expire        time.Duration
+	Issuer        string
+	RefreshExpire time.Duration
+
+	// Algorithm is one of HS256, RS256, ES256 or EdDSA and is required.
+	// Secret is only used by HS256, the asymmetric algorithms use rotated
+	// keys from KeyDir.
+	Algorithm      string
+	KeyDir         string
+	RotateInterval time.Duration
+	KeyRetention   time.Duration
+
+	// ImpersonationExpire is the lifetime of impersonation tokens, they
+	// cannot be refreshed.
+	ImpersonationExpire time.Duration
 }
 
 type JWTClaims struct {
 	jwt.RegisteredClaims
-	UserUniqueID int64 `json:"userUniqueId"`
-	RefreshAt    int64 `json:"refreshAt"`
+	UserUniqueID int64  `json:"userUniqueId"`
+	SessionID    string `json:"sid,omitempty"`
+	// ActorUniqueID is the user acting as UserUniqueID, only set on
+	// impersonation tokens.
+	ActorUniqueID int64 `json:"act,omitempty"`
+	// IssuedAtMs is iat in milliseconds, so a token issued right after a
+	// revocation is not mistaken for one issued before it. iat itself keeps
+	// the whole seconds other verifiers expect.
+	IssuedAtMs int64 `json:"iat_ms,omitempty"`
+}
+
+// IssuedAtMilli is the issue time in milliseconds, tokens without iat_ms fall
+// back to iat. It is 0 when neither is set.
+func (c *JWTClaims) IssuedAtMilli() int64 {
+	if c.IssuedAtMs != 0 {
+		return c.IssuedAtMs
+	}
+	if c.IssuedAt != nil {
+		return c.IssuedAt.UnixMilli()
+	}
+	return 0
 }
 
+// IsImpersonation reports whether the token was issued to somebody acting as
+// the user.
+func (c *JWTClaims) IsImpersonation() bool {
+	return c.ActorUniqueID != 0
+}
+
+// TokenValidator runs after the signature and expiry of a token have been
+// verified, e.g. to check a denylist. A non-nil exception rejects the request.
+type TokenValidator func(ctx context.Context, claims *JWTClaims) *exception.Exception
+
 type JWT struct {
-	Config Config
+	Config     Config
+	validators []TokenValidator
+	keys       *keySet
+	stop       context.CancelFunc
 }
 
-func NewJWT(config Config) *JWT {
+func NewJWT(config Config) (*JWT, error) {
+	keys, err := newKeySet(config)
+	if err != nil {
+		return nil, err
+	}
 	return &JWT{
 		Config: config,
+		keys:   keys,
+	}, nil
+}
+
+// Start rotates the signing key in the background until Stop is called.
+func (j *JWT) Start(onError func(err error)) {
+	if !j.keys.rotates() {
+		return
 	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j.stop = cancel
+
+	go func() {
+		ticker := time.NewTicker(min(j.Config.RotateInterval, time.Hour))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := j.keys.Rotate(false); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
+
+func (j *JWT) Stop() {
+	if j.stop != nil {
+		j.stop()
+	}
+}
+
+// RotateKey immediately replaces the signing key, e.g. after a key leaked.
+// The previous keys stay valid for verification. Unlike scheduled rotations
+// the key signs before verifiers caching the JWKS know it, they reject its
+// tokens until they refetch.
+func (j *JWT) RotateKey() error {
+	if !j.keys.rotates() {
+		return errors.New("HS256 keys cannot be rotated")
+	}
+	return j.keys.Rotate(true)
+}
+
+// JWKS serves the public verification keys as a JSON Web Key Set, so other
+// services can verify our tokens. HS256 secrets are never published.
+func (j *JWT) JWKS() gin.HandlerFunc {
+	return func(gtx *gin.Context) {
+		set := jwk.Set{Keys: []jwk.Key{}}
+		for _, key := range j.keys.Keys() {
+			public := key.PublicKey()
+			if public == nil {
+				continue
+			}
+			k, err := jwk.New(key.ID, key.Algorithm, public)
+			if err != nil {
+				continue
+			}
+			set.Keys = append(set.Keys, k)
+		}
+		gtx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwksMaxAge.Seconds())))
+		gtx.JSON(http.StatusOK, set)
+	}
+}
+
+// Use registers validators that are run by the JWT middleware for every token.
+func (j *JWT) Use(validators ...TokenValidator) {
+	j.validators = append(j.validators, validators...)
 }
 
 func (j *JWT) JWT() gin.HandlerFunc {
@@ -44,23 +170,28 @@ func (j *JWT) JWT() gin.HandlerFunc {
 			return
 		}
 
+		// access tokens are never re-signed here, clients renew them with
+		// their refresh token before ExpireAt
 		claims, err := j.ParseAndVerifyToken(token)
 		if err != nil {
 			appCtx.ToError(exception.ExceptionTokenExpired)
 			return
 		}
 
-		// if token is near to expire, generate a new token
-		if time.Now().Unix() > claims.RefreshAt {
-			newToken, err := j.GenerateToken(claims.UserUniqueID)
-			if err != nil {
-				appCtx.ToError(exception.ExceptionTokenGenerateFailed)
+		for _, validator := range j.validators {
+			if ex := validator(gtx, claims); ex != nil {
+				appCtx.ToError(ex)
 				return
 			}
-			appCtx.Header("New-Token", newToken)
 		}
 
 		appCtx.SetUserUniqueID(claims.UserUniqueID)
+		appCtx.SetTokenID(claims.ID)
+		appCtx.SetSessionID(claims.SessionID)
+		appCtx.SetTokenExpireAt(claims.ExpiresAt.Time)
+		if claims.IsImpersonation() {
+			appCtx.SetActorUniqueID(claims.ActorUniqueID)
+		}
 		appCtx.Next()
 	}
 }
@@ -84,10 +215,6 @@ func (j *JWT) GetTokenFromGinContext(c *gin.Context) (string, error) {
 	return token, nil
 }
 
-func (j *JWT) GetSecret() []byte {
-	return []byte(j.Config.Secret)
-}
-
 func (j *JWT) GetExpire() time.Duration {
 	return j.Config.Expire
 }
@@ -100,30 +227,78 @@ func (j *JWT) ExpireAt() time.Time {
 	return time.Now().Add(j.GetExpire())
 }
 
+// RefreshAt is the suggested time for clients to renew the access token.
 func (j *JWT) RefreshAt() time.Time {
 	return time.Now().Add(j.GetExpire() / 3 * 2)
 }
 
-func (j *JWT) GenerateClaims(userUniqueID int64) JWTClaims {
+func (j *JWT) GetRefreshExpire() time.Duration {
+	return j.Config.RefreshExpire
+}
+
+func (j *JWT) RefreshExpireAt() time.Time {
+	return time.Now().Add(j.GetRefreshExpire())
+}
+
+func (j *JWT) GenerateClaims(userUniqueID int64, sessionID string) JWTClaims {
+	now := time.Now()
 	return JWTClaims{
 		UserUniqueID: userUniqueID,
-		RefreshAt:    j.RefreshAt().Unix(),
+		SessionID:    sessionID,
+		IssuedAtMs:   now.UnixMilli(),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(j.ExpireAt()),
+			ID:        uuid.NewString(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.GetExpire())),
 			Issuer:    j.GetIssuer(),
 		},
 	}
 }
 
-func (j *JWT) GenerateToken(userUniqueID int64) (string, error) {
-	claims := j.GenerateClaims(userUniqueID)
-	tokenClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return tokenClaims.SignedString(j.GetSecret())
+func (j *JWT) GenerateToken(userUniqueID int64, sessionID string) (string, error) {
+	return j.sign(j.GenerateClaims(userUniqueID, sessionID))
 }
 
+// GenerateImpersonationToken issues a token that lets actorUniqueID act as
+// userUniqueID. It belongs to no session, so there is no refresh token and it
+// ends after ImpersonationExpire.
+func (j *JWT) GenerateImpersonationToken(actorUniqueID, userUniqueID int64) (string, *JWTClaims, error) {
+	claims := j.GenerateClaims(userUniqueID, "")
+	claims.ActorUniqueID = actorUniqueID
+	claims.ExpiresAt = jwt.NewNumericDate(time.UnixMilli(claims.IssuedAtMs).Add(j.Config.ImpersonationExpire))
+	token, err := j.sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, &claims, nil
+}
+
+func (j *JWT) sign(claims JWTClaims) (string, error) {
+	key := j.keys.SigningKey()
+	tokenClaims := jwt.NewWithClaims(key.method(), claims)
+	if key.ID != "" {
+		tokenClaims.Header["kid"] = key.ID
+	}
+	return tokenClaims.SignedString(key.private)
+}
+
+// ParseAndVerifyToken verifies the token with the key named by its kid header.
+// Only the configured algorithm is accepted, so a token cannot pick a weaker one.
 func (j *JWT) ParseAndVerifyToken(token string) (*JWTClaims, error) {
-	tokenClaims, err := jwt.ParseWithClaims(token, &JWTClaims{}, func(token *jwt.Token) (any, error) {
-		return j.GetSecret(), nil
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{j.keys.algorithm}),
+		jwt.WithIssuer(j.GetIssuer()),
+	)
+	tokenClaims, err := parser.ParseWithClaims(token, &JWTClaims{}, func(token *jwt.Token) (any, error) {
+		if !j.keys.rotates() {
+			return j.keys.SigningKey().verifyKey(), nil
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, ok := j.keys.Lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		return key.verifyKey(), nil
 	})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,94 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestJWT(t *testing.T) *JWT {
+	t.Helper()
+	j, err := NewJWT(Config{
+		Algorithm: AlgorithmHS256,
+		Secret:    "test-secret",
+		Issuer:    "test",
+		Expire:    time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewJWT: %v", err)
+	}
+	return j
+}
+
+func TestTokenKeepsMillisecondIssueTime(t *testing.T) {
+	j := newTestJWT(t)
+	claims := j.GenerateClaims(42, "session")
+	token, err := j.sign(claims)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	// iat stays an integer, verifiers that expect whole seconds still work
+	payload, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+	if err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	if strings.Contains(string(raw["iat"]), ".") {
+		t.Errorf("iat = %s, want whole seconds", raw["iat"])
+	}
+
+	parsed, err := j.ParseAndVerifyToken(token)
+	if err != nil {
+		t.Fatalf("ParseAndVerifyToken: %v", err)
+	}
+	if parsed.IssuedAtMilli() != claims.IssuedAtMs {
+		t.Errorf("IssuedAtMilli() = %d, want %d", parsed.IssuedAtMilli(), claims.IssuedAtMs)
+	}
+	if parsed.IssuedAt.Unix() != claims.IssuedAtMs/1000 {
+		t.Errorf("iat = %d, want %d", parsed.IssuedAt.Unix(), claims.IssuedAtMs/1000)
+	}
+}
+
+func TestIssuedAtMilli(t *testing.T) {
+	issuedAt := time.UnixMilli(1_700_000_000_123)
+	tests := []struct {
+		name   string
+		claims JWTClaims
+		want   int64
+	}{
+		{"iat_ms", JWTClaims{IssuedAtMs: issuedAt.UnixMilli()}, 1_700_000_000_123},
+		{"iat only", JWTClaims{RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(issuedAt)}}, 1_700_000_000_000},
+		{"none", JWTClaims{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.claims.IssuedAtMilli(); got != tt.want {
+				t.Errorf("IssuedAtMilli() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImpersonationTokenExpiresFromIssueTime(t *testing.T) {
+	j := newTestJWT(t)
+	j.Config.ImpersonationExpire = 15 * time.Minute
+	_, claims, err := j.GenerateImpersonationToken(1, 2)
+	if err != nil {
+		t.Fatalf("GenerateImpersonationToken: %v", err)
+	}
+	want := time.UnixMilli(claims.IssuedAtMs).Add(15 * time.Minute).Truncate(time.Second)
+	if !claims.ExpiresAt.Equal(want) {
+		t.Errorf("ExpiresAt = %v, want %v", claims.ExpiresAt.Time, want)
+	}
+	if claims.ActorUniqueID != 1 || claims.UserUniqueID != 2 {
+		t.Errorf("claims = %+v, want actor 1 acting as 2", claims)
+	}
+}
@@ -0,0 +1,165 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// pemCreatedHeader records when a key was generated, rotation is based on it
+// rather than on file times which do not survive copies. pemActiveHeader
+// records when it starts signing.
+const (
+	pemCreatedHeader = "Created"
+	pemActiveHeader  = "Active"
+)
+
+// Key is a signing key identified by its kid.
+type Key struct {
+	ID        string
+	Algorithm string
+	CreatedAt time.Time
+	// ActiveAt is when the key starts signing, it is published before so
+	// verifiers caching the key set know it by then
+	ActiveAt time.Time
+	// []byte for HS256, crypto.Signer otherwise
+	private any
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// verifyKey is the key handed to jwt for signature verification.
+func (k *Key) verifyKey() any {
+	if signer, ok := k.private.(crypto.Signer); ok {
+		return signer.Public()
+	}
+	return k.private
+}
+
+// PublicKey returns the public half of an asymmetric key, nil for HS256.
+func (k *Key) PublicKey() crypto.PublicKey {
+	if signer, ok := k.private.(crypto.Signer); ok {
+		return signer.Public()
+	}
+	return nil
+}
+
+func newSecretKey(secret string) *Key {
+	return &Key{Algorithm: AlgorithmHS256, private: []byte(secret)}
+}
+
+// GenerateKey creates a new asymmetric key for algorithm.
+func GenerateKey(algorithm string) (*Key, error) {
+	var (
+		private any
+		err     error
+	)
+	switch algorithm {
+	case AlgorithmRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgorithmES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &Key{
+		ID:        base64.RawURLEncoding.EncodeToString(id),
+		Algorithm: algorithm,
+		CreatedAt: now,
+		ActiveAt:  now,
+		private:   private,
+	}, nil
+}
+
+// MarshalPEM encodes the private key as PKCS #8 with the algorithm, creation
+// and activation time in the PEM headers.
+func (k *Key) MarshalPEM() ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.private)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{
+		Type: "PRIVATE KEY",
+		Headers: map[string]string{
+			"Algorithm":      k.Algorithm,
+			pemCreatedHeader: k.CreatedAt.UTC().Format(time.RFC3339),
+			pemActiveHeader:  k.ActiveAt.UTC().Format(time.RFC3339),
+		},
+		Bytes: der,
+	}), nil
+}
+
+// ParseKeyPEM decodes a key written by MarshalPEM.
+func ParseKeyPEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("no PKCS #8 private key found")
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm := block.Headers["Algorithm"]
+	switch private.(type) {
+	case *rsa.PrivateKey:
+		if algorithm == "" {
+			algorithm = AlgorithmRS256
+		}
+	case *ecdsa.PrivateKey:
+		if algorithm == "" {
+			algorithm = AlgorithmES256
+		}
+	case ed25519.PrivateKey:
+		algorithm = AlgorithmEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", private)
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, block.Headers[pemCreatedHeader])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s header: %w", pemCreatedHeader, err)
+	}
+	// keys written before activation existed sign right away
+	activeAt := createdAt
+	if value, ok := block.Headers[pemActiveHeader]; ok {
+		if activeAt, err = time.Parse(time.RFC3339, value); err != nil {
+			return nil, fmt.Errorf("invalid %s header: %w", pemActiveHeader, err)
+		}
+	}
+
+	return &Key{ID: id, Algorithm: algorithm, CreatedAt: createdAt, ActiveAt: activeAt, private: private}, nil
+}
@@ -0,0 +1,251 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reloadInterval limits how often an unknown kid or a JWKS request makes the
+// key set re-read the key directory, another instance sharing it may just
+// have rotated.
+const reloadInterval = 10 * time.Second
+
+// publishDelay is how long a rotated key is published before it signs, so
+// verifiers that cached the JWKS before the rotation have refetched it.
+const publishDelay = jwksMaxAge + reloadInterval
+
+// keySet holds the signing keys. The newest active key signs, a rotated key
+// is only published until publishDelay has passed. Older keys stay
+// available for verification until every token they signed has expired.
+//
+// With a key directory the keys are stored as <kid>.pem, instances sharing
+// the directory pick up keys rotated by each other. Without one the keys only
+// live in memory and every restart invalidates issued access tokens.
+type keySet struct {
+	algorithm      string
+	dir            string
+	rotateInterval time.Duration
+	retention      time.Duration
+
+	mu       sync.RWMutex
+	keys     []*Key // sorted by CreatedAt, newest last
+	loadedAt time.Time
+}
+
+func newKeySet(config Config) (*keySet, error) {
+	switch config.Algorithm {
+	case "":
+		return nil, errors.New("jwt algorithm is required")
+	case AlgorithmHS256:
+		if config.Secret == "" {
+			return nil, errors.New("jwt secret is required for HS256")
+		}
+		return &keySet{algorithm: AlgorithmHS256, keys: []*Key{newSecretKey(config.Secret)}}, nil
+	}
+
+	s := &keySet{
+		algorithm:      config.Algorithm,
+		dir:            config.KeyDir,
+		rotateInterval: config.RotateInterval,
+		// a retired key signs until the key replacing it is active and must
+		// outlive the tokens it signed
+		retention: max(config.KeyRetention, config.Expire) + publishDelay,
+	}
+	if s.dir != "" {
+		if err := os.MkdirAll(s.dir, 0o700); err != nil {
+			return nil, fmt.Errorf("create jwt key directory failed: %w", err)
+		}
+	}
+	if err := s.Rotate(false); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *keySet) rotates() bool {
+	return s.algorithm != AlgorithmHS256
+}
+
+// SigningKey returns the newest active key.
+func (s *keySet) SigningKey() *Key {
+	now := time.Now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := len(s.keys) - 1; i > 0; i-- {
+		if !s.keys[i].ActiveAt.After(now) {
+			return s.keys[i]
+		}
+	}
+	return s.keys[0]
+}
+
+// Lookup returns the key with the given kid.
+func (s *keySet) Lookup(kid string) (*Key, bool) {
+	if key, ok := s.find(kid); ok {
+		return key, true
+	}
+
+	if !s.stale() {
+		return nil, false
+	}
+	if err := s.load(); err != nil {
+		return nil, false
+	}
+	return s.find(kid)
+}
+
+func (s *keySet) stale() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.dir != "" && time.Since(s.loadedAt) > reloadInterval
+}
+
+func (s *keySet) find(kid string) (*Key, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, key := range s.keys {
+		if key.ID == kid {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// Keys returns every key usable for verification, including the ones not
+// active yet. They are re-read when stale, so a key rotated by another
+// instance is published well before it signs.
+func (s *keySet) Keys() []*Key {
+	if s.stale() {
+		// on failure the keys known so far are still valid
+		_ = s.load()
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.keys)
+}
+
+// Rotate reloads the keys, drops the expired ones and adds a new signing key
+// when the newest one is older than the rotation interval. The new key signs
+// after publishDelay, or right away when force is set or there is no key
+// yet; force also adds it regardless of the interval.
+func (s *keySet) Rotate(force bool) error {
+	now := time.Now()
+	keys, err := s.read(now)
+	if err != nil {
+		return err
+	}
+
+	if force || len(keys) == 0 || now.Sub(keys[len(keys)-1].CreatedAt) >= s.rotateInterval {
+		key, err := GenerateKey(s.algorithm)
+		if err != nil {
+			return fmt.Errorf("generate jwt key failed: %w", err)
+		}
+		if !force && len(keys) > 0 {
+			key.ActiveAt = key.CreatedAt.Add(publishDelay)
+		}
+		if err := s.store(key); err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.loadedAt = now
+	s.mu.Unlock()
+	return nil
+}
+
+// load picks up keys rotated by other instances.
+func (s *keySet) load() error {
+	now := time.Now()
+	keys, err := s.read(now)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadedAt = now
+	// the signing key is never dropped between two rotations
+	if len(keys) > 0 {
+		s.keys = keys
+	}
+	return nil
+}
+
+func (s *keySet) expired(key *Key, now time.Time) bool {
+	return now.Sub(key.CreatedAt) > s.rotateInterval+s.retention
+}
+
+// read returns the unexpired keys sorted by creation time, from the key
+// directory or from memory when there is none.
+func (s *keySet) read(now time.Time) ([]*Key, error) {
+	var keys []*Key
+	if s.dir == "" {
+		s.mu.RLock()
+		keys = slices.Clone(s.keys)
+		s.mu.RUnlock()
+	} else {
+		files, err := filepath.Glob(filepath.Join(s.dir, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("read jwt key %s failed: %w", file, err)
+			}
+			key, err := ParseKeyPEM(strings.TrimSuffix(filepath.Base(file), ".pem"), data)
+			if err != nil {
+				return nil, fmt.Errorf("parse jwt key %s failed: %w", file, err)
+			}
+			if s.expired(key, now) {
+				// another instance may have removed it already
+				_ = os.Remove(file)
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	keys = slices.DeleteFunc(keys, func(key *Key) bool {
+		return key.Algorithm != s.algorithm || s.expired(key, now)
+	})
+	slices.SortFunc(keys, func(a, b *Key) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return keys, nil
+}
+
+func (s *keySet) store(key *Key) error {
+	if s.dir == "" {
+		return nil
+	}
+
+	data, err := key.MarshalPEM()
+	if err != nil {
+		return err
+	}
+
+	// write and rename, so other instances never read a partial file
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, key.ID+".pem"))
+}
@@ -0,0 +1,104 @@
+package jwt
+
+import (
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func TestNewKeySetRequiresAlgorithm(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"no algorithm", Config{Secret: "secret"}, true},
+		{"HS256 without secret", Config{Algorithm: AlgorithmHS256}, true},
+		{"HS256", Config{Algorithm: AlgorithmHS256, Secret: "secret"}, false},
+		{"EdDSA", Config{Algorithm: AlgorithmEdDSA, RotateInterval: time.Hour}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := newKeySet(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newKeySet() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && s.algorithm != tt.config.Algorithm {
+				t.Errorf("algorithm = %s, want %s", s.algorithm, tt.config.Algorithm)
+			}
+		})
+	}
+}
+
+func TestRotatePublishesBeforeSigning(t *testing.T) {
+	for _, dir := range []string{"", t.TempDir()} {
+		t.Run("dir="+dir, func(t *testing.T) {
+			// every scheduled rotation is due right away
+			s, err := newKeySet(Config{Algorithm: AlgorithmEdDSA, KeyDir: dir, RotateInterval: time.Nanosecond})
+			if err != nil {
+				t.Fatalf("newKeySet: %v", err)
+			}
+			first := s.SigningKey()
+			if first.ActiveAt.After(time.Now()) {
+				t.Fatalf("first key active at %v, want right away", first.ActiveAt)
+			}
+
+			if err := s.Rotate(false); err != nil {
+				t.Fatalf("Rotate(false): %v", err)
+			}
+			keys := s.Keys()
+			if len(keys) != 2 {
+				t.Fatalf("Keys() = %d keys, want the rotated one published", len(keys))
+			}
+			pending := keys[1]
+			if got := pending.ActiveAt.Sub(pending.CreatedAt); got != publishDelay {
+				t.Errorf("rotated key activates after %v, want %v", got, publishDelay)
+			}
+			if s.SigningKey().ID != first.ID {
+				t.Errorf("SigningKey() = %s, want %s until the rotated key is active", s.SigningKey().ID, first.ID)
+			}
+
+			if err := s.Rotate(true); err != nil {
+				t.Fatalf("Rotate(true): %v", err)
+			}
+			keys = s.Keys()
+			if len(keys) != 3 {
+				t.Fatalf("Keys() = %d keys, want 3", len(keys))
+			}
+			if s.SigningKey().ID != keys[2].ID {
+				t.Errorf("SigningKey() = %s, want the forced key %s", s.SigningKey().ID, keys[2].ID)
+			}
+		})
+	}
+}
+
+func TestParseKeyPEMActivation(t *testing.T) {
+	key, err := GenerateKey(AlgorithmEdDSA)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	key.ActiveAt = key.CreatedAt.Add(time.Hour)
+	data, err := key.MarshalPEM()
+	if err != nil {
+		t.Fatalf("MarshalPEM: %v", err)
+	}
+
+	parsed, err := ParseKeyPEM(key.ID, data)
+	if err != nil {
+		t.Fatalf("ParseKeyPEM: %v", err)
+	}
+	if !parsed.ActiveAt.Equal(key.ActiveAt.Truncate(time.Second)) {
+		t.Errorf("ActiveAt = %v, want %v", parsed.ActiveAt, key.ActiveAt)
+	}
+
+	// keys written before activation existed sign from their creation
+	block, _ := pem.Decode(data)
+	delete(block.Headers, pemActiveHeader)
+	parsed, err = ParseKeyPEM(key.ID, pem.EncodeToMemory(block))
+	if err != nil {
+		t.Fatalf("ParseKeyPEM without %s: %v", pemActiveHeader, err)
+	}
+	if !parsed.ActiveAt.Equal(parsed.CreatedAt) {
+		t.Errorf("ActiveAt = %v, want CreatedAt %v", parsed.ActiveAt, parsed.CreatedAt)
+	}
+}
@@ -0,0 +1,31 @@
+package mailer
+
+import (
+	"context"
+	"super-web-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// consoleMailer only logs mails, it is the default for dev and test modes.
+type consoleMailer struct {
+	logger *logger.Logger
+	from   string
+}
+
+func NewConsoleMailer(logger *logger.Logger, from string) Mailer {
+	return &consoleMailer{logger: logger, from: from}
+}
+
+func (m *consoleMailer) Send(ctx context.Context, msg Message) error {
+	if err := validate(msg); err != nil {
+		return err
+	}
+	m.logger.Info("Mail sent to console",
+		zap.String("from", m.from),
+		zap.Strings("to", msg.To),
+		zap.String("subject", msg.Subject),
+		zap.String("body", msg.Body),
+	)
+	return nil
+}
@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fileMailer writes every mail as an .eml file into a directory, so mails
+// can be inspected in dev and test modes without a mail server.
+type fileMailer struct {
+	directory string
+	from      string
+}
+
+func NewFileMailer(directory, from string) (Mailer, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &fileMailer{directory: directory, from: from}, nil
+}
+
+func (m *fileMailer) Send(ctx context.Context, msg Message) error {
+	if err := validate(msg); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405"), uuid.NewString())
+	return os.WriteFile(filepath.Join(m.directory, name), msg.Bytes(m.from), 0644)
+}
@@ -0,0 +1,74 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+	HTML    bool
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Bytes renders msg as a RFC 5322 message sent by from.
+func (m Message) Bytes(from string) []byte {
+	contentType := "text/plain"
+	if m.HTML {
+		contentType = "text/html"
+	}
+
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.Trim(from[at+1:], "> ")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", uuid.NewString(), domain)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: %s; charset=UTF-8\r\n", contentType)
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+	buf.WriteString("\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(m.Body))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76])
+		buf.WriteString("\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded)
+	buf.WriteString("\r\n")
+
+	return buf.Bytes()
+}
+
+func validate(msg Message) error {
+	if len(msg.To) == 0 {
+		return fmt.Errorf("mail has no recipient")
+	}
+	for _, to := range msg.To {
+		if strings.ContainsAny(to, "\r\n") {
+			return fmt.Errorf("invalid recipient: %q", to)
+		}
+	}
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("invalid subject: %q", msg.Subject)
+	}
+	return nil
+}
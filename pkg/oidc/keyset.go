@@ -0,0 +1,84 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"super-web-server/pkg/jwk"
+	"sync"
+	"time"
+)
+
+// minRefreshInterval limits how often an unknown kid can trigger a JWKS
+// fetch, so forged tokens cannot be used to hammer the provider.
+const minRefreshInterval = 1 * time.Minute
+
+type keySet struct {
+	provider *Provider
+
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+func newKeySet(provider *Provider) *keySet {
+	return &keySet{provider: provider, keys: map[string]crypto.PublicKey{}}
+}
+
+// Key returns the key for kid, refetching the JWKS when the provider has
+// rotated its keys.
+func (s *keySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.RLock()
+	key, ok := s.keys[kid]
+	fresh := time.Since(s.fetchedAt) < minRefreshInterval
+	s.mu.RUnlock()
+
+	if ok {
+		return key, nil
+	}
+	if fresh {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func (s *keySet) refresh(ctx context.Context) error {
+	metadata, err := s.provider.Metadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	var set jwk.Set
+	if err := s.provider.getJSON(ctx, metadata.JWKSURI, &set); err != nil {
+		return fmt.Errorf("fetch jwks failed: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.PublicKey()
+		if err != nil {
+			// skip keys of unsupported types, the provider may publish several
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
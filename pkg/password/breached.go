@@ -0,0 +1,76 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// prefixLength is the number of hex characters of the SHA-1 a lookup is
+// bucketed by, the same split the Have I Been Pwned range API uses.
+const prefixLength = 5
+
+// BreachedList holds SHA-1 hashes of passwords known from breaches. The
+// hashes are bucketed by their first five hex characters and only the
+// suffixes are compared, so the list can be fed with HIBP range files.
+type BreachedList struct {
+	buckets map[string][]string
+}
+
+// LoadBreachedList reads a file with one upper or lower case SHA-1 hex digest
+// per line, optionally followed by ":<count>" as in the HIBP downloads.
+// Empty lines and lines starting with # are skipped.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list := &BreachedList{buckets: map[string][]string{}}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		digest, _, _ := strings.Cut(text, ":")
+		digest = strings.ToUpper(digest)
+		if len(digest) != sha1.Size*2 {
+			return nil, fmt.Errorf("%s:%d: not a sha1 digest", path, line)
+		}
+		if _, err := hex.DecodeString(digest); err != nil {
+			return nil, fmt.Errorf("%s:%d: not a sha1 digest", path, line)
+		}
+		prefix, suffix := digest[:prefixLength], digest[prefixLength:]
+		list.buckets[prefix] = append(list.buckets[prefix], suffix)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, suffixes := range list.buckets {
+		slices.Sort(suffixes)
+	}
+	return list, nil
+}
+
+func (l *BreachedList) Len() int {
+	n := 0
+	for _, suffixes := range l.buckets {
+		n += len(suffixes)
+	}
+	return n
+}
+
+// Contains reports whether password is in the list.
+func (l *BreachedList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	_, found := slices.BinarySearch(l.buckets[digest[:prefixLength]], digest[prefixLength:])
+	return found
+}
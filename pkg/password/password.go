@@ -0,0 +1,139 @@
+// Package password hashes passwords into PHC strings.
+//
+// New hashes use argon2id:
+//
+//	$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+//
+// Hashes of the former scheme, bcrypt over password + salt with the salt in
+// a separate column, are still verified in the folded form
+//
+//	$bcrypt-salted$<salt>$<bcrypt hash>
+//
+// and reported as needing a rehash.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	prefixArgon2id     = "$argon2id$"
+	prefixBcryptSalted = "$bcrypt-salted$"
+	prefixBcrypt       = "$2"
+)
+
+var ErrUnknownFormat = errors.New("unknown password hash format")
+
+// Params are the argon2id cost parameters, Memory is in KiB.
+type Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+type Hasher struct {
+	params Params
+}
+
+func NewHasher(params Params) *Hasher {
+	return &Hasher{params: params}
+}
+
+// Hash returns the argon2id PHC string of password.
+func (h *Hasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+	return encodeArgon2id(h.params, salt, key), nil
+}
+
+// Verify reports whether password matches encoded, and whether encoded should
+// be replaced by a fresh Hash because it uses an old scheme or old parameters.
+// An empty encoded hash, an account without password, never matches.
+func (h *Hasher) Verify(password, encoded string) (ok bool, needsRehash bool, err error) {
+	switch {
+	case encoded == "":
+		return false, false, nil
+	case strings.HasPrefix(encoded, prefixArgon2id):
+		params, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, false, err
+		}
+		actual := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(actual, key) != 1 {
+			return false, false, nil
+		}
+		return true, params != h.params, nil
+	case strings.HasPrefix(encoded, prefixBcryptSalted):
+		salt, hash, found := strings.Cut(strings.TrimPrefix(encoded, prefixBcryptSalted), "$")
+		if !found {
+			return false, false, ErrUnknownFormat
+		}
+		return compareBcrypt(password+salt, hash)
+	case strings.HasPrefix(encoded, prefixBcrypt):
+		return compareBcrypt(password, encoded)
+	}
+	return false, false, ErrUnknownFormat
+}
+
+func compareBcrypt(password, hash string) (bool, bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) || errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return false, false, nil
+	} else if err != nil {
+		return false, false, err
+	}
+	return true, true, nil
+}
+
+func encodeArgon2id(params Params, salt, key []byte) string {
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		prefixArgon2id, argon2.Version,
+		params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func decodeArgon2id(encoded string) (Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return Params{}, nil, nil, ErrUnknownFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Params{}, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+
+	var params Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return Params{}, nil, nil, fmt.Errorf("invalid argon2 parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Params{}, nil, nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Params{}, nil, nil, fmt.Errorf("invalid argon2 hash: %w", err)
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
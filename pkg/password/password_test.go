@@ -0,0 +1,100 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testParams = Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func bcryptHash(t *testing.T, password string) string {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+	return string(hash)
+}
+
+func TestHashRoundTrip(t *testing.T) {
+	h := NewHasher(testParams)
+	encoded, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("Hash() = %q, want an argon2id PHC string", encoded)
+	}
+	other, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if other == encoded {
+		t.Errorf("Hash() returned %q twice, want a fresh salt", encoded)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	h := NewHasher(testParams)
+	current, err := h.Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	weaker := testParams
+	weaker.Iterations = 2
+	outdated, err := NewHasher(weaker).Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	// the former scheme hashed password + salt, the migration folds the
+	// salt column into the hash
+	salted := prefixBcryptSalted + "pepper$" + bcryptHash(t, "secretpepper")
+
+	tests := []struct {
+		name            string
+		password        string
+		encoded         string
+		wantOK          bool
+		wantNeedsRehash bool
+		wantErr         error
+	}{
+		{"argon2id", "secret", current, true, false, nil},
+		{"argon2id wrong password", "guess", current, false, false, nil},
+		{"argon2id old parameters", "secret", outdated, true, true, nil},
+		{"bcrypt salted", "secret", salted, true, true, nil},
+		{"bcrypt salted wrong password", "secretpepper", salted, false, false, nil},
+		{"bcrypt", "secret", bcryptHash(t, "secret"), true, true, nil},
+		{"bcrypt wrong password", "guess", bcryptHash(t, "secret"), false, false, nil},
+		{"no password", "", "", false, false, nil},
+		{"bcrypt salted without hash", "secret", prefixBcryptSalted + "pepper", false, false, ErrUnknownFormat},
+		{"unknown", "secret", "md5$abc", false, false, ErrUnknownFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash, err := h.Verify(tt.password, tt.encoded)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOK || needsRehash != tt.wantNeedsRehash {
+				t.Errorf("Verify() = %v, %v, want %v, %v", ok, needsRehash, tt.wantOK, tt.wantNeedsRehash)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsMalformedArgon2id(t *testing.T) {
+	h := NewHasher(testParams)
+	for _, encoded := range []string{
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=x$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!$a2V5",
+	} {
+		if ok, _, err := h.Verify("secret", encoded); ok || err == nil {
+			t.Errorf("Verify(%q) = %v, %v, want an error", encoded, ok, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package password
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rule names the policy rule a password violates.
+type Rule string
+
+const (
+	RuleMinLength Rule = "min_length"
+	RuleMaxLength Rule = "max_length"
+	RuleLower     Rule = "lower"
+	RuleUpper     Rule = "upper"
+	RuleDigit     Rule = "digit"
+	RuleSymbol    Rule = "symbol"
+	RuleClasses   Rule = "classes"
+	RuleBreached  Rule = "breached"
+)
+
+// Policy checks the composition of new passwords. Lengths count characters,
+// not bytes. Reuse of previous passwords needs the user's history and is
+// checked by the caller.
+type Policy struct {
+	MinLength     int
+	MaxLength     int
+	RequireLower  bool
+	RequireUpper  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// MinClasses is the number of distinct character classes (lower, upper,
+	// digit, symbol) a password has to mix
+	MinClasses int
+	// Breached is optional, nil skips the check
+	Breached *BreachedList
+}
+
+// Check returns the first rule password violates, ok is true when there is none.
+func (p *Policy) Check(password string) (rule Rule, ok bool) {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return RuleMinLength, false
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return RuleMaxLength, false
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	switch {
+	case p.RequireLower && !lower:
+		return RuleLower, false
+	case p.RequireUpper && !upper:
+		return RuleUpper, false
+	case p.RequireDigit && !digit:
+		return RuleDigit, false
+	case p.RequireSymbol && !symbol:
+		return RuleSymbol, false
+	}
+
+	classes := 0
+	for _, has := range []bool{lower, upper, digit, symbol} {
+		if has {
+			classes++
+		}
+	}
+	if classes < p.MinClasses {
+		return RuleClasses, false
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		return RuleBreached, false
+	}
+
+	return "", true
+}
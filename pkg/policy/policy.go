@@ -0,0 +1,224 @@
+// Package policy decides whether a subject may perform an action on a
+// resource. Every resource type has a base policy: only its owner, only
+// subjects of its tenant, or only the roles named by the rules. Deny rules
+// are checked first, then allow rules, then the base policy. Evaluation works
+// on plain values, the caller looks up the subject and the resource.
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+type Kind string
+
+const (
+	KindOwner  Kind = "owner"  // the owner of the resource
+	KindTenant Kind = "tenant" // subjects of the tenant of the resource
+	KindRole   Kind = "role"   // nobody but the roles allowed by the rules
+)
+
+type Effect string
+
+const (
+	EffectAllow Effect = "allow"
+	EffectDeny  Effect = "deny"
+)
+
+type Action string
+
+const (
+	ActionRead   Action = "read"
+	ActionDelete Action = "delete"
+
+	// actionAny matches every action in a rule
+	actionAny = "*"
+)
+
+// actions are the actions rules may name, a typo would never match.
+var actions = []string{string(ActionRead), string(ActionDelete), actionAny}
+
+// Rule allows or denies actions to roles, a rule without roles applies to
+// every subject.
+type Rule struct {
+	Effect  Effect   `mapstructure:"effect"`
+	Actions []string `mapstructure:"actions"`
+	Roles   []string `mapstructure:"roles"`
+}
+
+func (r Rule) matches(subject Subject, action Action) bool {
+	if !slices.Contains(r.Actions, actionAny) && !slices.Contains(r.Actions, string(action)) {
+		return false
+	}
+	if len(r.Roles) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(r.Roles, subject.HasRole)
+}
+
+func (r Rule) String() string {
+	if len(r.Roles) == 0 {
+		return fmt.Sprintf("%s %s", r.Effect, strings.Join(r.Actions, ","))
+	}
+	return fmt.Sprintf("%s %s to %s", r.Effect, strings.Join(r.Actions, ","), strings.Join(r.Roles, ","))
+}
+
+type ResourcePolicy struct {
+	Policy Kind   `mapstructure:"policy"`
+	Rules  []Rule `mapstructure:"rules"`
+}
+
+type Config struct {
+	Resources map[string]ResourcePolicy `mapstructure:"resources"`
+}
+
+// Subject is who acts, Roles are the effective role codes, inherited ones
+// included.
+type Subject struct {
+	UniqueID int64
+	Tenant   string
+	Roles    []string
+}
+
+func (s Subject) HasRole(role string) bool {
+	return slices.Contains(s.Roles, role)
+}
+
+// Resource is what is acted on. Owner and Tenant are only compared by the
+// policies that need them, an empty tenant belongs to nobody.
+type Resource struct {
+	Type          string
+	OwnerUniqueID int64
+	Tenant        string
+}
+
+// TenantResolver tells the tenant of a subject that comes without one, an
+// empty tenant belongs to nobody.
+type TenantResolver func(ctx context.Context, subject Subject) (string, error)
+
+// Decision carries the reason in both cases, for logging and for the error
+// returned to the client.
+type Decision struct {
+	Allowed bool
+	Reason  string
+}
+
+func allow(format string, args ...any) Decision {
+	return Decision{Allowed: true, Reason: fmt.Sprintf(format, args...)}
+}
+
+func deny(format string, args ...any) Decision {
+	return Decision{Reason: fmt.Sprintf(format, args...)}
+}
+
+type Engine struct {
+	resources map[string]ResourcePolicy
+	tenants   TenantResolver
+}
+
+type Option func(*Engine)
+
+// WithTenantResolver looks up the tenant of subjects the caller built
+// without one. Only the tenant policy asks for it.
+func WithTenantResolver(resolver TenantResolver) Option {
+	return func(e *Engine) {
+		e.tenants = resolver
+	}
+}
+
+// New checks the config, a resource type without a policy denies everything.
+func New(config Config, opts ...Option) (*Engine, error) {
+	for resourceType, policy := range config.Resources {
+		switch policy.Policy {
+		case KindOwner, KindTenant, KindRole:
+		default:
+			return nil, fmt.Errorf("policy: %s: unknown policy %q", resourceType, policy.Policy)
+		}
+		for i, rule := range policy.Rules {
+			if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+				return nil, fmt.Errorf("policy: %s: rule %d: unknown effect %q", resourceType, i, rule.Effect)
+			}
+			if len(rule.Actions) == 0 {
+				return nil, fmt.Errorf("policy: %s: rule %d: no actions", resourceType, i)
+			}
+			for _, action := range rule.Actions {
+				if !slices.Contains(actions, action) {
+					return nil, fmt.Errorf("policy: %s: rule %d: unknown action %q", resourceType, i, action)
+				}
+			}
+		}
+	}
+	engine := &Engine{resources: config.Resources}
+	for _, opt := range opts {
+		opt(engine)
+	}
+	return engine, nil
+}
+
+// LoadFile reads the config from a YAML, JSON or TOML file, told apart by
+// the extension.
+func LoadFile(path string, opts ...Option) (*Engine, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, err
+	}
+	if len(config.Resources) == 0 {
+		return nil, errors.New("policy: no resources in " + path)
+	}
+	return New(config, opts...)
+}
+
+// Evaluate decides whether subject may perform action on resource.
+func (e *Engine) Evaluate(ctx context.Context, subject Subject, action Action, resource Resource) Decision {
+	policy, ok := e.resources[resource.Type]
+	if !ok {
+		return deny("no policy for %s", resource.Type)
+	}
+
+	for _, rule := range policy.Rules {
+		if rule.Effect == EffectDeny && rule.matches(subject, action) {
+			return deny("%s %s denied by rule %q", action, resource.Type, rule)
+		}
+	}
+	for _, rule := range policy.Rules {
+		if rule.Effect == EffectAllow && rule.matches(subject, action) {
+			return allow("%s %s allowed by rule %q", action, resource.Type, rule)
+		}
+	}
+
+	switch policy.Policy {
+	case KindOwner:
+		if subject.UniqueID != 0 && subject.UniqueID == resource.OwnerUniqueID {
+			return allow("owner of the %s", resource.Type)
+		}
+		return deny("only the owner can %s the %s", action, resource.Type)
+	case KindTenant:
+		tenant, err := e.tenant(ctx, subject)
+		if err != nil {
+			return deny("tenant of the subject unknown: %v", err)
+		}
+		if tenant != "" && tenant == resource.Tenant {
+			return allow("same tenant as the %s", resource.Type)
+		}
+		return deny("only the tenant of the %s can %s it", resource.Type, action)
+	default:
+		return deny("no role allowed to %s the %s", action, resource.Type)
+	}
+}
+
+func (e *Engine) tenant(ctx context.Context, subject Subject) (string, error) {
+	if subject.Tenant != "" || e.tenants == nil {
+		return subject.Tenant, nil
+	}
+	return e.tenants(ctx, subject)
+}
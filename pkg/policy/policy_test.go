@@ -0,0 +1,187 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  ResourcePolicy
+		wantErr bool
+	}{
+		{"owner", ResourcePolicy{Policy: KindOwner}, false},
+		{"tenant", ResourcePolicy{Policy: KindTenant}, false},
+		{"role with rules", ResourcePolicy{Policy: KindRole, Rules: []Rule{{Effect: EffectAllow, Actions: []string{"*"}}}}, false},
+		{"unknown policy", ResourcePolicy{Policy: "team"}, true},
+		{"unknown effect", ResourcePolicy{Policy: KindOwner, Rules: []Rule{{Effect: "maybe", Actions: []string{"read"}}}}, true},
+		{"no actions", ResourcePolicy{Policy: KindOwner, Rules: []Rule{{Effect: EffectAllow}}}, true},
+		{"unknown action", ResourcePolicy{Policy: KindOwner, Rules: []Rule{{Effect: EffectAllow, Actions: []string{"raed"}}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(Config{Resources: map[string]ResourcePolicy{"file": tt.policy}})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	engine, err := New(Config{Resources: map[string]ResourcePolicy{
+		"file": {Policy: KindOwner, Rules: []Rule{
+			{Effect: EffectAllow, Actions: []string{"read"}, Roles: []string{"role:admin"}},
+			{Effect: EffectDeny, Actions: []string{"delete"}, Roles: []string{"role:support"}},
+		}},
+		"report": {Policy: KindRole, Rules: []Rule{
+			{Effect: EffectAllow, Actions: []string{"*"}, Roles: []string{"role:admin"}},
+			{Effect: EffectDeny, Actions: []string{"*"}, Roles: []string{"role:banned"}},
+		}},
+		"notice": {Policy: KindRole, Rules: []Rule{
+			{Effect: EffectAllow, Actions: []string{"read"}},
+		}},
+		"project": {Policy: KindTenant, Rules: []Rule{
+			{Effect: EffectDeny, Actions: []string{"delete"}, Roles: []string{"role:support"}},
+		}},
+	}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	owner := Subject{UniqueID: 1, Roles: []string{"role:user"}}
+	other := Subject{UniqueID: 2, Roles: []string{"role:user"}}
+	admin := Subject{UniqueID: 3, Roles: []string{"role:user", "role:admin"}}
+	support := Subject{UniqueID: 1, Roles: []string{"role:user", "role:support"}}
+	bannedAdmin := Subject{UniqueID: 3, Roles: []string{"role:admin", "role:banned"}}
+	anonymous := Subject{}
+
+	file := Resource{Type: "file", OwnerUniqueID: 1}
+	orphan := Resource{Type: "file"}
+	report := Resource{Type: "report", OwnerUniqueID: 1}
+	notice := Resource{Type: "notice"}
+	project := Resource{Type: "project", OwnerUniqueID: 2, Tenant: "acme"}
+	untenanted := Resource{Type: "project", OwnerUniqueID: 2}
+	acme := Subject{UniqueID: 1, Tenant: "acme", Roles: []string{"role:user"}}
+	acmeSupport := Subject{UniqueID: 1, Tenant: "acme", Roles: []string{"role:support"}}
+	globex := Subject{UniqueID: 2, Tenant: "globex", Roles: []string{"role:user"}}
+
+	tests := []struct {
+		name     string
+		subject  Subject
+		action   Action
+		resource Resource
+		want     bool
+	}{
+		{"owner reads", owner, ActionRead, file, true},
+		{"owner deletes", owner, ActionDelete, file, true},
+		{"other user reads", other, ActionRead, file, false},
+		{"nobody owns an orphan", anonymous, ActionRead, orphan, false},
+		{"allow rule reads", admin, ActionRead, file, true},
+		{"allow rule does not cover delete", admin, ActionDelete, file, false},
+		{"deny rule beats ownership", support, ActionDelete, file, false},
+		{"deny rule leaves other actions", support, ActionRead, file, true},
+		{"role allowed", admin, ActionDelete, report, true},
+		{"role policy ignores the owner", owner, ActionRead, report, false},
+		{"deny beats allow", bannedAdmin, ActionRead, report, false},
+		{"rule without roles", anonymous, ActionRead, notice, true},
+		{"rule without roles, other action", admin, ActionDelete, notice, false},
+		{"unknown resource type", admin, ActionRead, Resource{Type: "invoice"}, false},
+		{"same tenant", acme, ActionDelete, project, true},
+		{"other tenant", globex, ActionRead, project, false},
+		{"tenant policy ignores the owner", globex, ActionRead, untenanted, false},
+		{"no tenant matches no tenant", other, ActionRead, untenanted, false},
+		{"deny rule beats the tenant", acmeSupport, ActionDelete, project, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := engine.Evaluate(context.Background(), tt.subject, tt.action, tt.resource)
+			if decision.Allowed != tt.want {
+				t.Errorf("Evaluate() = %+v, want allowed %v", decision, tt.want)
+			}
+			if decision.Reason == "" {
+				t.Error("Evaluate() gave no reason")
+			}
+		})
+	}
+}
+
+func TestEvaluateTenantResolver(t *testing.T) {
+	config := Config{Resources: map[string]ResourcePolicy{"project": {Policy: KindTenant}}}
+	tenants := map[int64]string{1: "acme", 2: "globex"}
+	resolved := 0
+	engine, err := New(config, WithTenantResolver(func(ctx context.Context, subject Subject) (string, error) {
+		resolved++
+		if subject.UniqueID == 9 {
+			return "", errors.New("lookup failed")
+		}
+		return tenants[subject.UniqueID], nil
+	}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	project := Resource{Type: "project", Tenant: "acme"}
+
+	tests := []struct {
+		name         string
+		subject      Subject
+		want         bool
+		wantResolved int
+	}{
+		{"resolved to the tenant", Subject{UniqueID: 1}, true, 1},
+		{"resolved to another tenant", Subject{UniqueID: 2}, false, 1},
+		{"resolved to no tenant", Subject{UniqueID: 3}, false, 1},
+		{"resolver failed", Subject{UniqueID: 9}, false, 1},
+		{"tenant given", Subject{UniqueID: 2, Tenant: "acme"}, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved = 0
+			decision := engine.Evaluate(context.Background(), tt.subject, ActionRead, project)
+			if decision.Allowed != tt.want {
+				t.Errorf("Evaluate() = %+v, want allowed %v", decision, tt.want)
+			}
+			if resolved != tt.wantResolved {
+				t.Errorf("resolver called %d times, want %d", resolved, tt.wantResolved)
+			}
+		})
+	}
+
+	// only the tenant policy asks for the tenant
+	owned := Config{Resources: map[string]ResourcePolicy{"file": {Policy: KindOwner}}}
+	engine, err = New(owned, WithTenantResolver(func(ctx context.Context, subject Subject) (string, error) {
+		t.Error("resolver called for the owner policy")
+		return "", nil
+	}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	engine.Evaluate(context.Background(), Subject{UniqueID: 1}, ActionRead, Resource{Type: "file", OwnerUniqueID: 1})
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "policy.yml")
+	if err := os.WriteFile(valid, []byte("resources:\n  file:\n    policy: owner\n    rules:\n      - effect: deny\n        actions: [delete]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	engine, err := LoadFile(valid)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if engine.Evaluate(context.Background(), Subject{UniqueID: 1}, ActionDelete, Resource{Type: "file", OwnerUniqueID: 1}).Allowed {
+		t.Error("Evaluate() allowed a delete the loaded rule denies")
+	}
+
+	empty := filepath.Join(dir, "empty.yml")
+	if err := os.WriteFile(empty, []byte("other: 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(empty); err == nil {
+		t.Error("LoadFile() of a file without resources succeeded")
+	}
+}
@@ -0,0 +1,147 @@
+// Package signurl signs URLs with HMAC-SHA256, so links to private resources
+// can be handed out without a session. Keys carry an id, the id of the
+// signing key is part of the URL so keys can be rotated: the first key signs,
+// every key still verifies.
+package signurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalid = errors.New("signurl: signature invalid")
+	ErrExpired = errors.New("signurl: url expired")
+)
+
+// query parameters of a signed url
+const (
+	paramKeyID       = "kid"
+	paramExpires     = "exp"
+	paramIPBound     = "ipb"
+	paramDisposition = "disp"
+	paramFilename    = "name"
+	paramSignature   = "sig"
+)
+
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Options are covered by the signature. The IP is not put into the URL, a
+// bound URL only verifies when it is requested from that IP.
+type Options struct {
+	ExpireAt    time.Time
+	IP          string
+	Disposition string
+	Filename    string
+}
+
+type Signer struct {
+	keys []Key
+}
+
+func NewSigner(keys ...Key) (*Signer, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("signurl: at least one key is required")
+	}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if key.ID == "" || len(key.Secret) == 0 {
+			return nil, errors.New("signurl: key id and secret are required")
+		}
+		if seen[key.ID] {
+			return nil, errors.New("signurl: duplicate key id " + key.ID)
+		}
+		seen[key.ID] = true
+	}
+	return &Signer{keys: keys}, nil
+}
+
+// Sign returns the query parameters that authorize a request for resource.
+// Resource names what is signed (e.g. "file:123") and is not part of the
+// parameters, the route has to derive it from the request path.
+func (s *Signer) Sign(resource string, opts Options) url.Values {
+	key := s.keys[0]
+	query := url.Values{}
+	query.Set(paramKeyID, key.ID)
+	query.Set(paramExpires, strconv.FormatInt(opts.ExpireAt.Unix(), 10))
+	if opts.IP != "" {
+		query.Set(paramIPBound, "1")
+	}
+	if opts.Disposition != "" {
+		query.Set(paramDisposition, opts.Disposition)
+	}
+	if opts.Filename != "" {
+		query.Set(paramFilename, opts.Filename)
+	}
+	query.Set(paramSignature, signature(key.Secret, resource, query, opts.IP))
+	return query
+}
+
+// Verify checks the parameters of a request for resource made from ip and
+// returns the options they were signed with.
+func (s *Signer) Verify(resource string, query url.Values, ip string, now time.Time) (*Options, error) {
+	var key *Key
+	for i := range s.keys {
+		if s.keys[i].ID == query.Get(paramKeyID) {
+			key = &s.keys[i]
+			break
+		}
+	}
+	if key == nil {
+		return nil, ErrInvalid
+	}
+
+	expires, err := strconv.ParseInt(query.Get(paramExpires), 10, 64)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	boundIP := ""
+	if query.Get(paramIPBound) == "1" {
+		boundIP = ip
+	}
+
+	given, err := base64.RawURLEncoding.DecodeString(query.Get(paramSignature))
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	expected, _ := base64.RawURLEncoding.DecodeString(signature(key.Secret, resource, query, boundIP))
+	if !hmac.Equal(given, expected) {
+		return nil, ErrInvalid
+	}
+
+	opts := &Options{
+		ExpireAt:    time.Unix(expires, 0),
+		IP:          boundIP,
+		Disposition: query.Get(paramDisposition),
+		Filename:    query.Get(paramFilename),
+	}
+	if now.After(opts.ExpireAt) {
+		return nil, ErrExpired
+	}
+	return opts, nil
+}
+
+func signature(secret []byte, resource string, query url.Values, ip string) string {
+	mac := hmac.New(sha256.New, secret)
+	// length prefixed, so no two field lists share an encoding
+	for _, field := range []string{
+		query.Get(paramKeyID),
+		resource,
+		query.Get(paramExpires),
+		query.Get(paramIPBound),
+		ip,
+		query.Get(paramDisposition),
+		query.Get(paramFilename),
+	} {
+		mac.Write([]byte(strconv.Itoa(len(field)) + ":" + field))
+	}
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
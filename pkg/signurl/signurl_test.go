@@ -0,0 +1,121 @@
+package signurl
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+var (
+	testNow  = time.Unix(1_700_000_000, 0)
+	keyNew   = Key{ID: "new", Secret: []byte("new-secret")}
+	keyOld   = Key{ID: "old", Secret: []byte("old-secret")}
+	keyOther = Key{ID: "new", Secret: []byte("other-secret")}
+)
+
+func newSigner(t *testing.T, keys ...Key) *Signer {
+	t.Helper()
+	s, err := NewSigner(keys...)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	return s
+}
+
+func TestNewSigner(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []Key
+		wantErr bool
+	}{
+		{"one key", []Key{keyNew}, false},
+		{"rotation", []Key{keyNew, keyOld}, false},
+		{"no keys", nil, true},
+		{"empty id", []Key{{Secret: []byte("x")}}, true},
+		{"empty secret", []Key{{ID: "x"}}, true},
+		{"duplicate id", []Key{keyNew, keyOther}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSigner(tt.keys...); (err != nil) != tt.wantErr {
+				t.Errorf("NewSigner() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	opts := Options{ExpireAt: testNow.Add(time.Minute), Disposition: "inline", Filename: "a.pdf"}
+	bound := opts
+	bound.IP = "203.0.113.7"
+
+	tests := []struct {
+		name     string
+		signer   []Key // keys of the signing instance
+		verifier []Key // keys of the verifying instance
+		opts     Options
+		resource string // verified resource, "file:1" is signed
+		edit     func(url.Values)
+		ip       string
+		now      time.Time
+		wantErr  error
+	}{
+		{name: "valid", opts: opts},
+		{name: "bound to the ip", opts: bound, ip: "203.0.113.7"},
+		{name: "other ip", opts: bound, ip: "198.51.100.1", wantErr: ErrInvalid},
+		{name: "unbound ignores the ip", opts: opts, ip: "198.51.100.1"},
+		{name: "other resource", opts: opts, resource: "file:2", wantErr: ErrInvalid},
+		{name: "expired", opts: opts, now: testNow.Add(2 * time.Minute), wantErr: ErrExpired},
+		{name: "extended expiry", opts: opts, edit: func(q url.Values) { q.Set(paramExpires, "9999999999") }, wantErr: ErrInvalid},
+		{name: "changed filename", opts: opts, edit: func(q url.Values) { q.Set(paramFilename, "a.html") }, wantErr: ErrInvalid},
+		{name: "dropped disposition", opts: opts, edit: func(q url.Values) { q.Del(paramDisposition) }, wantErr: ErrInvalid},
+		{name: "dropped ip binding", opts: bound, ip: "198.51.100.1", edit: func(q url.Values) { q.Del(paramIPBound) }, wantErr: ErrInvalid},
+		{name: "malformed signature", opts: opts, edit: func(q url.Values) { q.Set(paramSignature, "!") }, wantErr: ErrInvalid},
+		{name: "malformed expiry", opts: opts, edit: func(q url.Values) { q.Set(paramExpires, "soon") }, wantErr: ErrInvalid},
+		{name: "unknown key", opts: opts, edit: func(q url.Values) { q.Set(paramKeyID, "gone") }, wantErr: ErrInvalid},
+		{name: "rotated key still verifies", signer: []Key{keyOld}, verifier: []Key{keyNew, keyOld}, opts: opts},
+		{name: "retired key", signer: []Key{keyOld}, verifier: []Key{keyNew}, opts: opts, wantErr: ErrInvalid},
+		{name: "same id other secret", signer: []Key{keyOther}, opts: opts, wantErr: ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.signer == nil {
+				tt.signer = []Key{keyNew, keyOld}
+			}
+			if tt.verifier == nil {
+				tt.verifier = []Key{keyNew, keyOld}
+			}
+			if tt.resource == "" {
+				tt.resource = "file:1"
+			}
+			if tt.now.IsZero() {
+				tt.now = testNow
+			}
+
+			query := newSigner(t, tt.signer...).Sign("file:1", tt.opts)
+			if query.Get(paramKeyID) != tt.signer[0].ID {
+				t.Fatalf("signed with key %q, want the first key %q", query.Get(paramKeyID), tt.signer[0].ID)
+			}
+			for name, values := range query {
+				if tt.opts.IP != "" && values[0] == tt.opts.IP {
+					t.Fatalf("query parameter %s carries the ip", name)
+				}
+			}
+			if tt.edit != nil {
+				tt.edit(query)
+			}
+
+			got, err := newSigner(t, tt.verifier...).Verify(tt.resource, query, tt.ip, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !got.ExpireAt.Equal(tt.opts.ExpireAt) || got.IP != tt.opts.IP || got.Disposition != tt.opts.Disposition || got.Filename != tt.opts.Filename {
+				t.Errorf("Verify() = %+v, want %+v", got, tt.opts)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package sms
+
+import (
+	"context"
+	"super-web-server/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// Provider delivers a text message to a mobile number. Vendor integrations
+// implement it, the log provider is the default for dev and test setups.
+type Provider interface {
+	Send(ctx context.Context, mobile, message string) error
+}
+
+type logProvider struct {
+	logger *logger.Logger
+}
+
+func NewLogProvider(logger *logger.Logger) Provider {
+	return &logProvider{logger: logger}
+}
+
+func (p *logProvider) Send(ctx context.Context, mobile, message string) error {
+	p.logger.Info("SMS sent to log", zap.String("mobile", mobile), zap.String("message", message))
+	return nil
+}
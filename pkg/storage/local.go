@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// localTmpDir holds uploads until they are complete, it is not part of the
+// key space.
+const localTmpDir = ".tmp"
+
+// local keeps objects as files below root. The file system has no place for
+// a content type, it is derived from the extension of the key.
+type local struct {
+	root   string
+	signer *URLSigner
+}
+
+// NewLocal stores objects below root. Signed URLs are issued by signer and
+// have to be served by a route that calls VerifySignedURL.
+func NewLocal(root string, signer *URLSigner) (Storage, error) {
+	if err := os.MkdirAll(filepath.Join(root, localTmpDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage root: %w", err)
+	}
+	return &local{root: root, signer: signer}, nil
+}
+
+func (s *local) path(key string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if key == localTmpDir || strings.HasPrefix(key, localTmpDir+"/") {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+func (s *local) object(key string, info fs.FileInfo) *Object {
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &Object{
+		Key:         key,
+		Size:        info.Size(),
+		ContentType: contentType,
+		ETag:        fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size()),
+		ModTime:     info.ModTime(),
+	}
+}
+
+func (s *local) Put(ctx context.Context, key string, r io.Reader, size int64, opts PutOptions) (*Object, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Join(s.root, localTmpDir), "put-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, io.LimitReader(r, size+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if written != size {
+		return nil, fmt.Errorf("storage: expected %d bytes, got %d", size, written)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return nil, err
+	}
+	return s.Stat(ctx, key)
+}
+
+func (s *local) Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if length < 0 {
+		return file, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(file, length), file}, nil
+}
+
+func (s *local) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *local) Stat(ctx context.Context, key string) (*Object, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, ErrNotFound
+	}
+	return s.object(key, info), nil
+}
+
+func (s *local) List(ctx context.Context, prefix string) ([]Object, error) {
+	objects := []Object{}
+	err := filepath.WalkDir(s.root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.root, name)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if entry.IsDir() {
+			if key == localTmpDir {
+				return filepath.SkipDir
+			}
+			// skip directories that cannot contain a match
+			if key != "." && !strings.HasPrefix(key+"/", prefix) && !strings.HasPrefix(prefix, key+"/") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, *s.object(key, info))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (s *local) SignedURL(ctx context.Context, key string, expire time.Duration) (string, error) {
+	if _, err := s.path(key); err != nil {
+		return "", err
+	}
+	return s.signer.Sign(key, time.Now().Add(expire)), nil
+}
+
+func (s *local) VerifySignedURL(key string, query url.Values) error {
+	if _, err := s.path(key); err != nil {
+		return err
+	}
+	return s.signer.Verify(key, query, time.Now())
+}
@@ -0,0 +1,242 @@
+// Package s3test provides an in-process S3 compatible store for tests.
+package s3test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"super-web-server/pkg/storage"
+	"sync"
+	"time"
+)
+
+const (
+	AccessKeyID     = "s3test"
+	SecretAccessKey = "s3test-secret"
+	Region          = "us-east-1"
+)
+
+// Server keeps the objects of its buckets in memory. It implements the
+// object and ListObjectsV2 calls the storage package uses, with path style
+// addressing. Requests have to carry the credential of AccessKeyID, the
+// signature itself is not recomputed.
+type Server struct {
+	*httptest.Server
+
+	// PageSize limits the keys per list response, to exercise pagination.
+	PageSize int
+
+	mu      sync.Mutex
+	buckets map[string]map[string]*object
+}
+
+type object struct {
+	data        []byte
+	contentType string
+	etag        string
+	modTime     time.Time
+}
+
+func NewServer(buckets ...string) *Server {
+	s := &Server{PageSize: 1000, buckets: map[string]map[string]*object{}}
+	for _, bucket := range buckets {
+		s.buckets[bucket] = map[string]*object{}
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Config returns the storage configuration for bucket on this server.
+func (s *Server) Config(bucket string) storage.S3Config {
+	return storage.S3Config{
+		Endpoint:        s.URL,
+		Region:          Region,
+		Bucket:          bucket,
+		AccessKeyID:     AccessKeyID,
+		SecretAccessKey: SecretAccessKey,
+		PathStyle:       true,
+		HTTPClient:      s.Client(),
+	}
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeError(w, http.StatusForbidden, "AccessDenied", "Access Denied")
+		return
+	}
+
+	bucketName, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, ok := s.buckets[bucketName]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+
+	if key == "" {
+		if r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2" {
+			s.list(w, r, bucket)
+			return
+		}
+		writeError(w, http.StatusNotImplemented, "NotImplemented", "Not implemented")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+		sum := md5.Sum(data)
+		etag := hex.EncodeToString(sum[:])
+		contentType := r.Header.Get("Content-Type")
+		if contentType == "" {
+			contentType = "binary/octet-stream"
+		}
+		bucket[key] = &object{data: data, contentType: contentType, etag: etag, modTime: time.Now().UTC().Truncate(time.Second)}
+		w.Header().Set("ETag", `"`+etag+`"`)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		obj, ok := bucket[key]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist")
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("ETag", `"`+obj.etag+`"`)
+		w.Header().Set("Last-Modified", obj.modTime.Format(http.TimeFormat))
+		data, status := obj.data, http.StatusOK
+		if rangeHeader := r.Header.Get("Range"); rangeHeader != "" && r.Method == http.MethodGet {
+			start, end, ok := parseRange(rangeHeader, int64(len(data)))
+			if !ok {
+				writeError(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "The requested range is not satisfiable")
+				return
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			data, status = data[start:end+1], http.StatusPartialContent
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodDelete:
+		delete(bucket, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "Method not allowed")
+	}
+}
+
+// authorized accepts a header signed or a presigned, not expired request of
+// AccessKeyID.
+func (s *Server) authorized(r *http.Request) bool {
+	credentialPrefix := AccessKeyID + "/"
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		return strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential="+credentialPrefix) && strings.Contains(auth, "Signature=")
+	}
+
+	query := r.URL.Query()
+	if !strings.HasPrefix(query.Get("X-Amz-Credential"), credentialPrefix) || query.Get("X-Amz-Signature") == "" {
+		return false
+	}
+	date, err := time.Parse("20060102T150405Z", query.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil {
+		return false
+	}
+	return time.Now().Before(date.Add(time.Duration(expires) * time.Second))
+}
+
+type listBucketResult struct {
+	XMLName               xml.Name       `xml:"ListBucketResult"`
+	Contents              []listContents `xml:"Contents"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+}
+
+type listContents struct {
+	Key          string `xml:"Key"`
+	Size         int64  `xml:"Size"`
+	ETag         string `xml:"ETag"`
+	LastModified string `xml:"LastModified"`
+}
+
+// list pages through the keys, the continuation token is the last key
+// returned.
+func (s *Server) list(w http.ResponseWriter, r *http.Request, bucket map[string]*object) {
+	prefix := r.URL.Query().Get("prefix")
+	after := r.URL.Query().Get("continuation-token")
+
+	keys := []string{}
+	for key := range bucket {
+		if strings.HasPrefix(key, prefix) && key > after {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	result := listBucketResult{}
+	if len(keys) > s.PageSize {
+		keys = keys[:s.PageSize]
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[len(keys)-1]
+	}
+	for _, key := range keys {
+		obj := bucket[key]
+		result.Contents = append(result.Contents, listContents{
+			Key:          key,
+			Size:         int64(len(obj.data)),
+			ETag:         `"` + obj.etag + `"`,
+			LastModified: obj.modTime.Format(time.RFC3339),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(result)
+}
+
+func parseRange(header string, size int64) (int64, int64, bool) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		return 0, 0, false
+	}
+	startText, endText, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(startText, 10, 64)
+	if err != nil || start >= size {
+		return 0, 0, false
+	}
+	end := size - 1
+	if endText != "" {
+		end, err = strconv.ParseInt(endText, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false
+		}
+		end = min(end, size-1)
+	}
+	return start, end, true
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "%s<Error><Code>%s</Code><Message>%s</Message></Error>", xml.Header, code, message)
+}
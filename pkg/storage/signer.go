@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"net/url"
+	"strings"
+	"super-web-server/pkg/signurl"
+	"time"
+)
+
+// URLSigner signs URLs for backends without signed URLs of their own. The
+// signature covers the object key and the expiry.
+type URLSigner struct {
+	baseURL string
+	signer  *signurl.Signer
+}
+
+// NewURLSigner signs URLs below baseURL, the route that verifies and serves
+// them.
+func NewURLSigner(baseURL string, signer *signurl.Signer) *URLSigner {
+	return &URLSigner{baseURL: strings.TrimSuffix(baseURL, "/"), signer: signer}
+}
+
+func (s *URLSigner) Sign(key string, expireAt time.Time) string {
+	query := s.signer.Sign(s.resource(key), signurl.Options{ExpireAt: expireAt})
+	return s.baseURL + "/" + escapeKey(key) + "?" + query.Encode()
+}
+
+// Verify returns signurl.ErrInvalid or signurl.ErrExpired for a rejected
+// URL.
+func (s *URLSigner) Verify(key string, query url.Values, now time.Time) error {
+	_, err := s.signer.Verify(s.resource(key), query, "", now)
+	return err
+}
+
+func (s *URLSigner) resource(key string) string {
+	return "storage:" + key
+}
+
+// escapeKey escapes each segment of key, the slashes stay.
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
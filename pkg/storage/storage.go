@@ -0,0 +1,69 @@
+// Package storage stores files behind one interface, on the local disk or in
+// an S3 compatible object store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("storage: object not found")
+	ErrInvalidKey = errors.New("storage: invalid object key")
+)
+
+// Object describes a stored object.
+type Object struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"contentType"`
+	ETag        string    `json:"etag"`
+	ModTime     time.Time `json:"modTime"`
+}
+
+type PutOptions struct {
+	ContentType string
+}
+
+// Storage is implemented by every backend. Keys are slash separated relative
+// paths, see CleanKey.
+type Storage interface {
+	// Put stores size bytes read from r under key, replacing an existing
+	// object.
+	Put(ctx context.Context, key string, r io.Reader, size int64, opts PutOptions) (*Object, error)
+	// Get reads the object from offset on, length bytes or up to the end when
+	// length is negative.
+	Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Delete removes the object, deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (*Object, error)
+	// List returns the objects whose key starts with prefix, sorted by key.
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// SignedURL returns a URL anyone can GET the object with until expire
+	// has passed.
+	SignedURL(ctx context.Context, key string, expire time.Duration) (string, error)
+}
+
+// SignedURLVerifier is implemented by backends whose signed URLs are served
+// by this application rather than by the store itself.
+type SignedURLVerifier interface {
+	VerifySignedURL(key string, query url.Values) error
+}
+
+// CleanKey checks that key is a relative slash separated path without empty,
+// "." or ".." segments, so it can never leave the root of a backend.
+func CleanKey(key string) (string, error) {
+	if key == "" || len(key) > 1024 || strings.ContainsAny(key, "\\\x00") {
+		return "", ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	return key, nil
+}
@@ -0,0 +1,259 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"super-web-server/pkg/signurl"
+	"super-web-server/pkg/storage"
+	"super-web-server/pkg/storage/s3test"
+	"testing"
+	"time"
+)
+
+func newLocal(t *testing.T) storage.Storage {
+	t.Helper()
+	signer, err := signurl.NewSigner(signurl.Key{ID: "test", Secret: []byte("secret")})
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	s, err := storage.NewLocal(t.TempDir(), storage.NewURLSigner("http://localhost/storage", signer))
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	return s
+}
+
+func newS3(t *testing.T) (storage.Storage, *s3test.Server) {
+	t.Helper()
+	server := s3test.NewServer("files")
+	t.Cleanup(server.Close)
+	s, err := storage.NewS3(server.Config("files"))
+	if err != nil {
+		t.Fatalf("NewS3: %v", err)
+	}
+	return s, server
+}
+
+func put(t *testing.T, s storage.Storage, key, data string) *storage.Object {
+	t.Helper()
+	obj, err := s.Put(context.Background(), key, strings.NewReader(data), int64(len(data)), storage.PutOptions{ContentType: "text/plain"})
+	if err != nil {
+		t.Fatalf("Put(%q): %v", key, err)
+	}
+	return obj
+}
+
+func get(t *testing.T, s storage.Storage, key string, offset, length int64) string {
+	t.Helper()
+	body, err := s.Get(context.Background(), key, offset, length)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("read %q: %v", key, err)
+	}
+	return string(data)
+}
+
+// TestBackends runs the same checks against every backend, they have to be
+// interchangeable.
+func TestBackends(t *testing.T) {
+	backends := map[string]func(t *testing.T) storage.Storage{
+		"local": newLocal,
+		"s3": func(t *testing.T) storage.Storage {
+			s, _ := newS3(t)
+			return s
+		},
+	}
+	for name, newStorage := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newStorage(t)
+
+			obj := put(t, s, "a/b/hello.txt", "hello world")
+			if obj.Key != "a/b/hello.txt" || obj.Size != 11 || obj.ETag == "" {
+				t.Errorf("Put() = %+v", obj)
+			}
+			if got := get(t, s, "a/b/hello.txt", 0, -1); got != "hello world" {
+				t.Errorf("Get() = %q, want the whole object", got)
+			}
+			if got := get(t, s, "a/b/hello.txt", 6, -1); got != "world" {
+				t.Errorf("Get(offset) = %q, want %q", got, "world")
+			}
+			if got := get(t, s, "a/b/hello.txt", 2, 3); got != "llo" {
+				t.Errorf("Get(range) = %q, want %q", got, "llo")
+			}
+
+			put(t, s, "a/b/hello.txt", "replaced")
+			if got := get(t, s, "a/b/hello.txt", 0, -1); got != "replaced" {
+				t.Errorf("Get() after replace = %q", got)
+			}
+
+			put(t, s, "a/c.txt", "c")
+			put(t, s, "ab.txt", "ab")
+			objects, err := s.List(ctx, "a/")
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			var keys []string
+			for _, obj := range objects {
+				keys = append(keys, obj.Key)
+			}
+			if strings.Join(keys, ",") != "a/b/hello.txt,a/c.txt" {
+				t.Errorf("List(a/) = %v", keys)
+			}
+
+			if err := s.Delete(ctx, "a/c.txt"); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if _, err := s.Stat(ctx, "a/c.txt"); !errors.Is(err, storage.ErrNotFound) {
+				t.Errorf("Stat() after delete error = %v, want ErrNotFound", err)
+			}
+			if err := s.Delete(ctx, "a/c.txt"); err != nil {
+				t.Errorf("Delete() of a missing object = %v, want nil", err)
+			}
+			if _, err := s.Get(ctx, "missing.txt", 0, -1); !errors.Is(err, storage.ErrNotFound) {
+				t.Errorf("Get() of a missing object error = %v, want ErrNotFound", err)
+			}
+
+			for _, key := range []string{"", "../escape", "a//b", "a/./b", `a\b`} {
+				if _, err := s.Put(ctx, key, strings.NewReader("x"), 1, storage.PutOptions{}); !errors.Is(err, storage.ErrInvalidKey) {
+					t.Errorf("Put(%q) error = %v, want ErrInvalidKey", key, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLocalRejectsShortUpload(t *testing.T) {
+	s := newLocal(t)
+	if _, err := s.Put(context.Background(), "short.txt", strings.NewReader("abc"), 5, storage.PutOptions{}); err == nil {
+		t.Fatal("Put() with fewer bytes than announced succeeded")
+	}
+	if _, err := s.Stat(context.Background(), "short.txt"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Stat() error = %v, want the partial upload discarded", err)
+	}
+	if _, err := s.Put(context.Background(), ".tmp/x", strings.NewReader("x"), 1, storage.PutOptions{}); !errors.Is(err, storage.ErrInvalidKey) {
+		t.Errorf("Put(.tmp/x) error = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestLocalSignedURL(t *testing.T) {
+	s := newLocal(t)
+	put(t, s, "dir/a b.txt", "x")
+
+	signed, err := s.SignedURL(context.Background(), "dir/a b.txt", time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("parse %q: %v", signed, err)
+	}
+	if u.Path != "/storage/dir/a b.txt" {
+		t.Errorf("path = %q", u.Path)
+	}
+
+	verifier := s.(storage.SignedURLVerifier)
+	if err := verifier.VerifySignedURL("dir/a b.txt", u.Query()); err != nil {
+		t.Errorf("VerifySignedURL() = %v, want nil", err)
+	}
+	if err := verifier.VerifySignedURL("dir/other.txt", u.Query()); !errors.Is(err, signurl.ErrInvalid) {
+		t.Errorf("VerifySignedURL() for another key = %v, want ErrInvalid", err)
+	}
+
+	expired, err := s.SignedURL(context.Background(), "dir/a b.txt", -time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	u, _ = url.Parse(expired)
+	if err := verifier.VerifySignedURL("dir/a b.txt", u.Query()); !errors.Is(err, signurl.ErrExpired) {
+		t.Errorf("VerifySignedURL() of an expired url = %v, want ErrExpired", err)
+	}
+}
+
+func TestS3ListPaginates(t *testing.T) {
+	s, server := newS3(t)
+	server.PageSize = 2
+	for _, key := range []string{"p/1", "p/2", "p/3", "p/4", "p/5"} {
+		put(t, s, key, key)
+	}
+	objects, err := s.List(context.Background(), "p/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(objects) != 5 || objects[0].Key != "p/1" || objects[4].Key != "p/5" {
+		t.Errorf("List() = %+v, want all 5 objects in order", objects)
+	}
+}
+
+func TestS3SignedURL(t *testing.T) {
+	s, server := newS3(t)
+	put(t, s, "docs/report 1.txt", "report")
+
+	signed, err := s.SignedURL(context.Background(), "docs/report 1.txt", time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	resp, err := server.Client().Get(signed)
+	if err != nil {
+		t.Fatalf("GET %s: %v", signed, err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(data) != "report" {
+		t.Errorf("GET signed url = %d %q", resp.StatusCode, data)
+	}
+
+	if _, err := s.SignedURL(context.Background(), "docs/report 1.txt", 8*24*time.Hour); err == nil {
+		t.Error("SignedURL() beyond 7 days succeeded")
+	}
+}
+
+func TestS3Error(t *testing.T) {
+	server := s3test.NewServer("files")
+	defer server.Close()
+	config := server.Config("files")
+	config.AccessKeyID = "someone-else"
+	s, err := storage.NewS3(config)
+	if err != nil {
+		t.Fatalf("NewS3: %v", err)
+	}
+
+	_, err = s.Stat(context.Background(), "x")
+	var s3Err *storage.S3Error
+	if !errors.As(err, &s3Err) || s3Err.StatusCode != http.StatusForbidden {
+		t.Fatalf("Stat() error = %v, want a 403 S3Error", err)
+	}
+}
+
+func TestReader(t *testing.T) {
+	s := newLocal(t)
+	put(t, s, "r.txt", "0123456789")
+
+	r := storage.NewReader(context.Background(), s, "r.txt", 10)
+	defer r.Close()
+	if _, err := r.Seek(-4, io.SeekEnd); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil || string(data) != "6789" {
+		t.Errorf("ReadAll() after Seek = %q, %v", data, err)
+	}
+	if _, err := r.Seek(2, io.SeekStart); err != nil {
+		t.Fatalf("Seek: %v", err)
+	}
+	buf := make([]byte, 3)
+	if _, err := io.ReadFull(r, buf); err != nil || string(buf) != "234" {
+		t.Errorf("ReadFull() = %q, %v", buf, err)
+	}
+	if _, err := r.Seek(-20, io.SeekCurrent); err == nil {
+		t.Error("Seek() to a negative position succeeded")
+	}
+}
@@ -0,0 +1,96 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors, base32 encoded.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238(t *testing.T) {
+	// the last 6 digits of the 8 digit codes in RFC 6238 appendix B
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("Code(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code := func(t *testing.T, at time.Time) string {
+		t.Helper()
+		c, err := Code(rfcSecret, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		passcode string
+		skew     int
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current", rfcSecret, code(t, now), 0, Step(now), true},
+		{"lowercase secret with spaces", strings.ToLower(rfcSecret[:8]) + " " + rfcSecret[8:], code(t, now), 0, Step(now), true},
+		{"previous step within skew", rfcSecret, code(t, now.Add(-Period*time.Second)), 1, Step(now) - 1, true},
+		{"next step within skew", rfcSecret, code(t, now.Add(Period*time.Second)), 1, Step(now) + 1, true},
+		{"previous step without skew", rfcSecret, code(t, now.Add(-Period*time.Second)), 0, 0, false},
+		{"two steps off", rfcSecret, code(t, now.Add(-2*Period*time.Second)), 1, 0, false},
+		{"wrong length", rfcSecret, "12345", 1, 0, false},
+		{"invalid secret", "not base32!", "123456", 1, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(tt.secret, tt.passcode, now, tt.skew)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("Validate() = (%d, %v), want (%d, %v)", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Error("GenerateSecret() returned the same secret twice")
+	}
+	if _, err := Code(a, time.Now()); err != nil {
+		t.Errorf("Code() with a generated secret: %v", err)
+	}
+}
+
+func TestURL(t *testing.T) {
+	got := URL("super web", "user@example.com", rfcSecret)
+	want := "otpauth://totp/super%20web:user@example.com?algorithm=SHA1&digits=6&issuer=super+web&period=30&secret=" + rfcSecret
+	if got != want {
+		t.Errorf("URL() = %s, want %s", got, want)
+	}
+}
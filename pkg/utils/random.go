@@ -1,6 +1,9 @@
 package utils
 
 import (
+	crand "crypto/rand"
+	"encoding/base64"
+	"math/big"
 	"math/rand/v2"
 	"time"
 )
@@ -53,3 +56,28 @@ func GenerateRandomCodeOnlyNumberAndUppercase(length int) string {
 	}
 	return string(code)
 }
+
+// GenerateSecureRandomCodeOnlyNumber generates a numeric code using crypto/rand.
+// Use it for codes that guard authentication, e.g. verification codes.
+func GenerateSecureRandomCodeOnlyNumber(length int) (string, error) {
+	chars := "1234567890"
+	code := make([]byte, length)
+	max := big.NewInt(int64(len(chars)))
+	for i := range code {
+		n, err := crand.Int(crand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = chars[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// GenerateSecureToken returns a url safe token built from size random bytes.
+func GenerateSecureToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}